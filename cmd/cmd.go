@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/urfave/cli/v2"
@@ -19,21 +20,68 @@ func newEpubCommand() *cli.Command {
 		Name:        "epub",
 		Usage:       "将 TXT 小说转换为 EPUB",
 		Description: "按卷、章节规则解析 TXT 文件并输出 EPUB。",
-		Flags: []cli.Flag{
+		Flags: append(bookSourceFlags(),
+			&cli.StringFlag{
+				Name:    "cover",
+				Aliases: []string{"img"},
+				Usage:   "封面图片路径或 URL",
+			},
+			&cli.StringFlag{
+				Name:  "author",
+				Usage: "作者，留空则自动解析",
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "输出文件路径，或输出目录",
+			},
+			&cli.BoolFlag{
+				Name:  "vertical",
+				Usage: "竖排输出，翻页方向为从右向左，适合日文小说",
+			},
+			&cli.IntFlag{
+				Name:  "split-size",
+				Usage: "单个章节文件的大小上限（KB），超过时按段落拆分为多个文件，默认 256，设为 -1 不拆分",
+			},
+			&cli.BoolFlag{
+				Name:  "pack-chapters",
+				Usage: "把同一卷中相邻的短章节合并到同一个文件，合并后不超过 -split-size，适合章节数以千计的网络小说",
+			},
+			&cli.IntFlag{
+				Name:  "toc-group",
+				Usage: "没有分卷的长书在目录中每 N 章归为一组，例如“第1–100章”，默认不分组",
+			},
+		),
+		Action: func(c *cli.Context) error {
+			book, err := buildBookFromFlags(c)
+			if err != nil {
+				return err
+			}
+
+			start := time.Now()
+			if err := goepub.NewEPUBConverter().Convert(c.Context, book); err != nil {
+				return fmt.Errorf("转换文档失败: %w", err)
+			}
+
+			log.Printf("转换完成,耗时 -> %s", time.Since(start).Round(time.Millisecond))
+			return nil
+		},
+	}
+}
+
+// bookSourceFlags 返回读取和解析 TXT 所需的公共参数。
+// epub 与 inspect 命令共用这组参数，保证两者的解析结果一致。
+func bookSourceFlags() []cli.Flag {
+	return []cli.Flag{
 		&cli.StringFlag{
 			Name:     "file",
 			Aliases:  []string{"f"},
 			Required: true,
-			Usage:    "TXT 文件路径",
-		},
-		&cli.StringFlag{
-			Name:    "cover",
-			Aliases: []string{"img"},
-			Usage:   "封面图片路径或 URL",
+			Usage:    "TXT 或 Markdown 文件路径",
 		},
 		&cli.StringFlag{
-			Name:  "author",
-			Usage: "作者，留空则自动解析",
+			Name:  "input-format",
+			Usage: "输入格式：txt、markdown，留空时按扩展名判断，.md 和 .markdown 按 Markdown 解析",
 		},
 		&cli.StringFlag{
 			Name:    "book-title-regexp",
@@ -46,12 +94,12 @@ func newEpubCommand() *cli.Command {
 		},
 		&cli.StringFlag{
 			Name:  "lang",
-			Usage: "语言，默认 zh-CN",
+			Usage: "语言，en、ja 等语言会使用对应的规则预设；留空时按正文文字种类判断，默认 zh-CN，设置简繁转换时默认使用目标地区的语言",
 		},
 		&cli.StringFlag{
 			Name:    "encoding",
 			Aliases: []string{"charset"},
-			Usage:   "文本编码，默认 auto，可选 utf-8、gbk、gb18030",
+			Usage:   "文本编码，默认 auto，可选 utf-8、utf-16le、utf-16be、gbk、gb18030、big5、shift_jis",
 		},
 		&cli.StringFlag{
 			Name:    "rule-config",
@@ -75,32 +123,57 @@ func newEpubCommand() *cli.Command {
 		&cli.StringFlag{
 			Name:    "chapter-regexp",
 			Aliases: []string{"r", "regexr", "title-regexp", "chapter-pattern"},
-			Usage:   "提取章节标题的正则",
-		},
-		&cli.StringFlag{
-			Name:    "output",
-			Aliases: []string{"o"},
-			Usage:   "输出文件路径，或输出目录",
+			Usage:   "提取章节标题的正则；设为 auto 时，规则中的章节正则匹配不到章节会根据正文推断",
 		},
 		&cli.StringFlag{
 			Name:    "volume-regexp",
 			Aliases: []string{"vr", "volume-pattern"},
 			Usage:   "提取卷标题的正则",
 		},
+		&cli.StringFlag{
+			Name:    "part-regexp",
+			Aliases: []string{"part-pattern"},
+			Usage:   "提取部标题的正则，部位于卷之上，默认不启用",
 		},
-		Action: func(c *cli.Context) error {
-			book, err := buildBookFromFlags(c)
-			if err != nil {
-				return err
-			}
-
-			start := time.Now()
-			if err := goepub.NewEPUBConverter().Convert(c.Context, book); err != nil {
-				return fmt.Errorf("转换文档失败: %w", err)
-			}
-
-			log.Printf("转换完成,耗时 -> %s", time.Since(start).Round(time.Millisecond))
-			return nil
+		&cli.StringFlag{
+			Name:  "reflow",
+			Usage: "硬折行段落重排：auto、off、force，默认使用规则配置（auto）",
+		},
+		&cli.StringFlag{
+			Name:  "zh-convert",
+			Usage: "简繁转换：s2t、t2s、s2tw、s2hk，默认不转换",
+		},
+		&cli.StringFlag{
+			Name:  "paragraph-mode",
+			Usage: "段落划分方式：line 每行一段，blank 以空行分段并保留段内换行，默认使用规则配置（line）",
+		},
+		&cli.StringFlag{
+			Name:  "front-matter",
+			Usage: "首章之前正文的处理方式：keep 保留为前言章节，drop 丢弃，intro 并入简介，默认使用规则配置（keep）",
+		},
+		&cli.StringFlag{
+			Name:  "markup",
+			Usage: "正文标记格式：plain 纯文本，aozora 解析青空文库注记（注音、见出し、字下げ、换页），默认使用规则配置（plain）",
+		},
+		&cli.StringFlag{
+			Name:  "footnotes",
+			Usage: "注释识别：on 把 [1]、注①、（注：…）等注释转换为弹出式脚注，off 保留原文，默认使用规则配置（off）",
+		},
+		&cli.StringFlag{
+			Name:  "missing-chapters",
+			Usage: "章节缺号检查：off、warn，默认使用规则配置（warn）",
+		},
+		&cli.StringFlag{
+			Name:  "duplicate-chapters",
+			Usage: "重复章节检查：off、warn、fix；fix 会删除序号和正文都相同的重复章节",
+		},
+		&cli.StringFlag{
+			Name:  "chapter-order",
+			Usage: "章节乱序检查：off、warn、fix；fix 会在卷内按序号重新排序",
+		},
+		&cli.StringFlag{
+			Name:  "similar-chapters",
+			Usage: "正文相似章节处理：off、report、keep_longest、keep_latest，默认使用规则配置（report）",
 		},
 	}
 }
@@ -110,6 +183,7 @@ func newEpubCommand() *cli.Command {
 func buildBookFromFlags(c *cli.Context) (*goepub.Book, error) {
 	book := &goepub.Book{
 		Filename:       c.String("file"),
+		InputFormat:    c.String("input-format"),
 		Cover:          c.String("cover"),
 		Author:         c.String("author"),
 		Lang:           c.String("lang"),
@@ -119,6 +193,21 @@ func buildBookFromFlags(c *cli.Context) (*goepub.Book, error) {
 		RuleChannel:    c.String("rule-channel"),
 		RulePresetMode: c.String("rule-preset-mode"),
 		RuleConfigPath: c.String("rule-config"),
+
+		ReflowMode:             c.String("reflow"),
+		ParagraphMode:          c.String("paragraph-mode"),
+		FrontMatter:            c.String("front-matter"),
+		Markup:                 c.String("markup"),
+		FootnoteMode:           c.String("footnotes"),
+		VerticalWriting:        c.Bool("vertical"),
+		ChapterSplitSize:       c.Int("split-size") * 1024,
+		PackChapters:           c.Bool("pack-chapters"),
+		TOCGroupSize:           c.Int("toc-group"),
+		ChineseConversion:      c.String("zh-convert"),
+		MissingChapterPolicy:   c.String("missing-chapters"),
+		DuplicateChapterPolicy: c.String("duplicate-chapters"),
+		ChapterOrderPolicy:     c.String("chapter-order"),
+		SimilarChapterPolicy:   c.String("similar-chapters"),
 	}
 
 	titlePattern := c.String("book-title-regexp")
@@ -140,7 +229,9 @@ func buildBookFromFlags(c *cli.Context) (*goepub.Book, error) {
 	}
 
 	chapterPattern := c.String("chapter-regexp")
-	if chapterPattern != "" {
+	if strings.EqualFold(chapterPattern, "auto") {
+		book.InferChapterRegex = true
+	} else if chapterPattern != "" {
 		chapterRegex, err := regexp.Compile(chapterPattern)
 		if err != nil {
 			return nil, fmt.Errorf("章节正则无效: %w", err)
@@ -157,5 +248,14 @@ func buildBookFromFlags(c *cli.Context) (*goepub.Book, error) {
 		book.VolumeRegex = volumeRegex
 	}
 
+	partPattern := c.String("part-regexp")
+	if partPattern != "" {
+		partRegex, err := regexp.Compile(partPattern)
+		if err != nil {
+			return nil, fmt.Errorf("部正则无效: %w", err)
+		}
+		book.PartRegex = partRegex
+	}
+
 	return book, nil
 }
@@ -0,0 +1,349 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/text/width"
+
+	"github.com/lifei6671/gotexttoepub/goepub"
+)
+
+// Inspect 是只读的解析检查命令入口。
+var Inspect = newInspectCommand()
+
+func newInspectCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "inspect",
+		Usage:       "检查 TXT 的解析结果，不生成 EPUB",
+		Description: "按与 epub 命令相同的规则解析 TXT，输出编码、预设、卷章结构以及被忽略或丢弃的行。",
+		Flags: append(bookSourceFlags(),
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "以 JSON 格式输出报告",
+			},
+		),
+		Action: func(c *cli.Context) error {
+			book, err := buildBookFromFlags(c)
+			if err != nil {
+				return err
+			}
+
+			// 解析过程的逐行日志对检查报告没有帮助，这里临时关闭。
+			originalWriter := log.Writer()
+			log.SetOutput(io.Discard)
+			parsed, err := goepub.ParseBook(c.Context, book)
+			log.SetOutput(originalWriter)
+			if err != nil {
+				return fmt.Errorf("解析文档失败: %w", err)
+			}
+
+			writer := c.App.Writer
+			if writer == nil {
+				writer = os.Stdout
+			}
+			report := newInspectReport(book, parsed)
+			if c.Bool("json") {
+				encoder := json.NewEncoder(writer)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(report)
+			}
+			printInspectReport(writer, report)
+			return nil
+		},
+	}
+}
+
+// inspectReport 是 inspect 命令的输出结构，同时用于表格和 JSON 两种格式。
+type inspectReport struct {
+	File               string                      `json:"file"`
+	Name               string                      `json:"name"`
+	Author             string                      `json:"author"`
+	Encoding           string                      `json:"encoding"`
+	EncodingConfidence float64                     `json:"encoding_confidence"`
+	EncodingCandidates []goepub.EncodingCandidate  `json:"encoding_candidates"`
+	EncodingRepairs    []goepub.EncodingRepair     `json:"encoding_repairs"`
+	TextFixes          goepub.TextFixes            `json:"text_fixes"`
+	InferredChapter    *goepub.ChapterInference    `json:"inferred_chapter,omitempty"`
+	DetectedPresets    []goepub.DetectedRulePreset `json:"detected_presets"`
+	AppliedPresets     []string                    `json:"applied_presets"`
+	WrapWidth          int                         `json:"wrap_width"`
+	FrontMatter        string                      `json:"front_matter"`
+	FrontMatterLines   int                         `json:"front_matter_lines"`
+	VolumeCount        int                         `json:"volume_count"`
+	ChapterCount       int                         `json:"chapter_count"`
+	Volumes            []goepub.OutlineVolume      `json:"volumes"`
+	IgnoredLines       []goepub.ReportLine         `json:"ignored_lines"`
+	DiscardedLines     []goepub.ReportLine         `json:"discarded_lines"`
+	MissingImages      []goepub.ReportLine         `json:"missing_images"`
+	NumberingIssues    []goepub.NumberingIssue     `json:"numbering_issues"`
+	SimilarChapters    []goepub.SimilarChapter     `json:"similar_chapters"`
+}
+
+func newInspectReport(book *goepub.Book, parsed *goepub.ParsedBook) inspectReport {
+	name := parsed.Name
+	if name == "" {
+		name = book.Name
+	}
+	author := parsed.Author
+	if author == "" {
+		author = book.Author
+	}
+
+	report := inspectReport{
+		File:               book.Filename,
+		Name:               name,
+		Author:             author,
+		Encoding:           parsed.Report.Encoding,
+		EncodingConfidence: parsed.Report.EncodingConfidence,
+		EncodingCandidates: nonNilSlice(parsed.Report.EncodingCandidates),
+		EncodingRepairs:    nonNilSlice(parsed.Report.EncodingRepairs),
+		TextFixes:          parsed.Report.TextFixes,
+		InferredChapter:    parsed.Report.InferredChapter,
+		DetectedPresets:    nonNilSlice(parsed.Report.DetectedPresets),
+		AppliedPresets:     nonNilSlice(parsed.Report.AppliedPresets),
+		WrapWidth:          parsed.Report.WrapWidth,
+		FrontMatter:        parsed.Report.FrontMatter,
+		FrontMatterLines:   parsed.Report.FrontMatterLines,
+		Volumes:            parsed.Outline(),
+		IgnoredLines:       nonNilSlice(parsed.Report.IgnoredLines),
+		DiscardedLines:     nonNilSlice(parsed.Report.DiscardedLines),
+		MissingImages:      nonNilSlice(parsed.Report.MissingImages),
+		NumberingIssues:    nonNilSlice(parsed.Report.NumberingIssues),
+		SimilarChapters:    nonNilSlice(parsed.Report.SimilarChapters),
+	}
+	for _, volume := range report.Volumes {
+		if volume.Title != "" {
+			report.VolumeCount++
+		}
+		report.ChapterCount += len(volume.Chapters)
+	}
+	return report
+}
+
+func printInspectReport(writer io.Writer, report inspectReport) {
+	fmt.Fprintf(writer, "文件: %s\n", report.File)
+	fmt.Fprintf(writer, "书名: %s\n", valueOrNone(report.Name))
+	fmt.Fprintf(writer, "作者: %s\n", valueOrNone(report.Author))
+	fmt.Fprintf(writer, "编码: %s（置信度 %.2f）\n", report.Encoding, report.EncodingConfidence)
+	if len(report.EncodingCandidates) > 0 {
+		candidates := make([]string, 0, len(report.EncodingCandidates))
+		for _, candidate := range report.EncodingCandidates {
+			candidates = append(candidates, fmt.Sprintf("%s(%.2f)", candidate.Encoding, candidate.Confidence))
+		}
+		fmt.Fprintf(writer, "候选编码: %s\n", strings.Join(candidates, ", "))
+	}
+
+	fmt.Fprintf(writer, "文本规范化: %s\n", valueOrNone(formatTextFixes(report.TextFixes)))
+
+	if inference := report.InferredChapter; inference != nil {
+		fmt.Fprintf(writer, "推断的章节正则: %s（匹配 %d 行，得分 %.2f）\n", inference.Pattern, inference.Matches, inference.Score)
+	}
+
+	detected := make([]string, 0, len(report.DetectedPresets))
+	for _, preset := range report.DetectedPresets {
+		detected = append(detected, fmt.Sprintf("%s(score=%d)", preset.Name, preset.Score))
+	}
+	fmt.Fprintf(writer, "探测到的预设: %s\n", valueOrNone(strings.Join(detected, ", ")))
+	fmt.Fprintf(writer, "已应用的预设: %s\n", valueOrNone(strings.Join(report.AppliedPresets, ", ")))
+	if report.WrapWidth > 0 {
+		fmt.Fprintf(writer, "硬折行宽度: %d 列，已重排段落\n", report.WrapWidth)
+	} else {
+		fmt.Fprintln(writer, "硬折行宽度: 未检测到")
+	}
+	fmt.Fprintf(writer, "首章之前的正文: %d 行，%s\n", report.FrontMatterLines, frontMatterAction(report.FrontMatter))
+	fmt.Fprintln(writer)
+
+	fmt.Fprintf(writer, "卷章结构 (%d 卷, %d 章):\n", report.VolumeCount, report.ChapterCount)
+	rows := [][]string{{"类型", "行号", "字数", "标题"}}
+	partLine := 0
+	for _, volume := range report.Volumes {
+		if volume.Part != "" && volume.PartLine != partLine {
+			rows = append(rows, []string{"部", strconv.Itoa(volume.PartLine), "-", volume.Part})
+		}
+		partLine = volume.PartLine
+		if volume.Title != "" {
+			rows = append(rows, []string{"卷", strconv.Itoa(volume.Line), "-", volume.Title})
+		}
+		for _, chapter := range volume.Chapters {
+			rows = append(rows, []string{"章", strconv.Itoa(chapter.Line), strconv.Itoa(chapter.Chars), chapter.Title})
+		}
+	}
+	printTable(writer, rows)
+	fmt.Fprintln(writer)
+
+	printEncodingRepairs(writer, report.EncodingRepairs)
+	fmt.Fprintln(writer)
+	printReportLines(writer, "被忽略的行", report.IgnoredLines)
+	fmt.Fprintln(writer)
+	printReportLines(writer, "首章之前被丢弃的正文", report.DiscardedLines)
+	fmt.Fprintln(writer)
+	printReportLines(writer, "找不到的图片", report.MissingImages)
+	fmt.Fprintln(writer)
+	printNumberingIssues(writer, report.NumberingIssues)
+	fmt.Fprintln(writer)
+	printSimilarChapters(writer, report.SimilarChapters)
+}
+
+// frontMatterAction 描述首章之前正文的处理方式。
+func frontMatterAction(mode string) string {
+	switch mode {
+	case "drop":
+		return "已丢弃"
+	case "intro":
+		return "并入简介"
+	default:
+		return "保留为前言章节"
+	}
+}
+
+// formatTextFixes 只列出实际发生过的修正。
+func formatTextFixes(fixes goepub.TextFixes) string {
+	items := []struct {
+		label string
+		count int
+	}{
+		{`\r 换行`, fixes.CRLineBreaks},
+		{`\r\n 换行`, fixes.CRLFLineBreaks},
+		{"U+2028/U+2029 换行", fixes.UnicodeLineBreaks},
+		{"删除非法字符", fixes.InvalidXMLChars},
+		{"删除零宽字符", fixes.ZeroWidthChars},
+		{"替换无效字节", fixes.InvalidUTF8},
+	}
+	parts := make([]string, 0, len(items))
+	for _, item := range items {
+		if item.count > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", item.label, item.count))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func printEncodingRepairs(writer io.Writer, repairs []goepub.EncodingRepair) {
+	fmt.Fprintf(writer, "编码修复 (%d):\n", len(repairs))
+	if len(repairs) == 0 {
+		return
+	}
+	rows := [][]string{{"行号", "编码", "处理", "首行内容"}}
+	for _, repair := range repairs {
+		lines := strconv.Itoa(repair.StartLine)
+		if repair.EndLine > repair.StartLine {
+			lines += "-" + strconv.Itoa(repair.EndLine)
+		}
+		action := "按该编码单独解码"
+		if repair.Mojibake {
+			action = "还原误解码的 UTF-8 乱码"
+		}
+		rows = append(rows, []string{lines, repair.Encoding, action, repair.Sample})
+	}
+	printTable(writer, rows)
+}
+
+func printSimilarChapters(writer io.Writer, items []goepub.SimilarChapter) {
+	fmt.Fprintf(writer, "相似章节 (%d):\n", len(items))
+	if len(items) == 0 {
+		return
+	}
+	rows := [][]string{{"行号", "相似度", "章节", "相似于", "处理"}}
+	for _, item := range items {
+		action := "仅提示"
+		if item.DroppedTitle != "" {
+			action = "删除第 " + strconv.Itoa(item.DroppedLine) + " 行的章节"
+		}
+		rows = append(rows, []string{
+			strconv.Itoa(item.Line),
+			strconv.FormatFloat(item.Similarity, 'f', 2, 64),
+			item.Title,
+			item.OtherTitle,
+			action,
+		})
+	}
+	printTable(writer, rows)
+}
+
+func printNumberingIssues(writer io.Writer, issues []goepub.NumberingIssue) {
+	fmt.Fprintf(writer, "章节序号问题 (%d):\n", len(issues))
+	if len(issues) == 0 {
+		return
+	}
+	rows := [][]string{{"行号", "状态", "说明"}}
+	for _, issue := range issues {
+		status := "警告"
+		if issue.Fixed {
+			status = "已修正"
+		}
+		rows = append(rows, []string{strconv.Itoa(issue.Line), status, issue.String()})
+	}
+	printTable(writer, rows)
+}
+
+func printReportLines(writer io.Writer, title string, lines []goepub.ReportLine) {
+	fmt.Fprintf(writer, "%s (%d):\n", title, len(lines))
+	if len(lines) == 0 {
+		return
+	}
+	rows := [][]string{{"行号", "内容"}}
+	for _, line := range lines {
+		rows = append(rows, []string{strconv.Itoa(line.Line), line.Text})
+	}
+	printTable(writer, rows)
+}
+
+// printTable 按显示宽度对齐输出表格。
+// tabwriter 按字符数对齐，遇到中文这类全角字符时列会错开，因此这里单独计算宽度。
+func printTable(writer io.Writer, rows [][]string) {
+	widths := make([]int, 0, 4)
+	for _, row := range rows {
+		for i, cell := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], displayWidth(cell))
+		}
+	}
+	for _, row := range rows {
+		var line strings.Builder
+		for i, cell := range row {
+			line.WriteString(cell)
+			if i < len(row)-1 {
+				line.WriteString(strings.Repeat(" ", widths[i]-displayWidth(cell)+2))
+			}
+		}
+		fmt.Fprintln(writer, line.String())
+	}
+}
+
+// displayWidth 计算字符串在终端中的显示宽度，全角字符按两列计算。
+func displayWidth(value string) int {
+	total := 0
+	for _, r := range value {
+		switch width.LookupRune(r).Kind() {
+		case width.EastAsianWide, width.EastAsianFullwidth:
+			total += 2
+		default:
+			total++
+		}
+	}
+	return total
+}
+
+func valueOrNone(value string) string {
+	if strings.TrimSpace(value) == "" {
+		return "无"
+	}
+	return value
+}
+
+// nonNilSlice 保证 JSON 输出中的空列表是 [] 而不是 null。
+func nonNilSlice[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
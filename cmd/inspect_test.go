@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+)
+
+func writeInspectSample(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "story.txt")
+	content := strings.Join([]string{
+		"检查报告测试",
+		"作者：张三",
+		"这是一段没有章节归属的前言。",
+		"第一卷 起点",
+		"第一章 开始",
+		"第一段内容",
+		"今天只有一更",
+		"第二章 继续",
+		"第二段内容",
+	}, "\n")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write txt: %v", err)
+	}
+	return path
+}
+
+func TestInspectCommandPrintsStructuralReport(t *testing.T) {
+	path := writeInspectSample(t)
+
+	var buffer bytes.Buffer
+	app := &cli.App{
+		Commands: []*cli.Command{newInspectCommand()},
+		Writer:   &buffer,
+	}
+	if err := app.Run([]string{"gotexttoepub", "inspect", "--file", path, "--rule-preset-mode", "off", "--front-matter", "drop"}); err != nil {
+		t.Fatalf("run inspect: %v", err)
+	}
+
+	output := buffer.String()
+	for _, want := range []string{"编码: utf-8", "卷章结构 (1 卷, 2 章)", "第一章 开始", "今天只有一更", "这是一段没有章节归属的前言。"} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("expected output to contain %q, got: %s", want, output)
+		}
+	}
+	if _, err := os.Stat(strings.TrimSuffix(path, ".txt") + ".epub"); err == nil {
+		t.Fatal("inspect must not write an epub file")
+	}
+}
+
+func TestInspectCommandSupportsJSON(t *testing.T) {
+	path := writeInspectSample(t)
+
+	var buffer bytes.Buffer
+	app := &cli.App{
+		Commands: []*cli.Command{newInspectCommand()},
+		Writer:   &buffer,
+	}
+	if err := app.Run([]string{"gotexttoepub", "inspect", "--file", path, "--json", "--front-matter", "drop"}); err != nil {
+		t.Fatalf("run inspect: %v", err)
+	}
+
+	var report inspectReport
+	if err := json.Unmarshal(buffer.Bytes(), &report); err != nil {
+		t.Fatalf("decode json report: %v\n%s", err, buffer.String())
+	}
+	if report.ChapterCount != 2 || len(report.Volumes) != 1 {
+		t.Fatalf("unexpected outline: %+v", report.Volumes)
+	}
+	first := report.Volumes[0].Chapters[0]
+	if first.Line != 5 || first.Chars != len([]rune("第一段内容")) {
+		t.Fatalf("unexpected first chapter entry: %+v", first)
+	}
+	if len(report.IgnoredLines) != 1 || report.IgnoredLines[0].Line != 7 {
+		t.Fatalf("unexpected ignored lines: %+v", report.IgnoredLines)
+	}
+	if len(report.DiscardedLines) != 1 || report.DiscardedLines[0].Line != 3 {
+		t.Fatalf("unexpected discarded lines: %+v", report.DiscardedLines)
+	}
+	if report.FrontMatter != "drop" || report.FrontMatterLines != 1 {
+		t.Fatalf("unexpected front matter report: %s %d", report.FrontMatter, report.FrontMatterLines)
+	}
+}
+
+func TestInspectCommandKeepsFrontMatterByDefault(t *testing.T) {
+	path := writeInspectSample(t)
+
+	var buffer bytes.Buffer
+	app := &cli.App{
+		Commands: []*cli.Command{newInspectCommand()},
+		Writer:   &buffer,
+	}
+	if err := app.Run([]string{"gotexttoepub", "inspect", "--file", path, "--rule-preset-mode", "off"}); err != nil {
+		t.Fatalf("run inspect: %v", err)
+	}
+
+	output := buffer.String()
+	for _, want := range []string{"首章之前的正文: 1 行，保留为前言章节", "卷章结构 (1 卷, 3 章)", "首章之前被丢弃的正文 (0)"} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("expected output to contain %q, got: %s", want, output)
+		}
+	}
+}
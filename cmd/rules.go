@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/urfave/cli/v2"
@@ -140,6 +141,7 @@ func newRulesCommand() *cli.Command {
 				printRuleField(writer, "title_regex", summary.Config.TitleRegex)
 				printRuleField(writer, "title_author_regex", summary.Config.TitleAuthorRegex)
 				printRuleField(writer, "author_regex", summary.Config.AuthorRegex)
+				printRuleField(writer, "part_regex", summary.Config.PartRegex)
 				printRuleField(writer, "volume_regex", summary.Config.VolumeRegex)
 				printRuleField(writer, "chapter_regex", summary.Config.ChapterRegex)
 				printRuleField(writer, "extra_regex", summary.Config.ExtraRegex)
@@ -148,6 +150,24 @@ func newRulesCommand() *cli.Command {
 				printRuleList(writer, "special_chapter_titles", summary.Config.SpecialChapterTitles)
 				printRuleList(writer, "ignored_line_patterns", summary.Config.IgnoredLinePatterns)
 				printRuleList(writer, "ignored_line_contains", summary.Config.IgnoredLineContains)
+				printRuleList(writer, "scene_break_patterns", summary.Config.SceneBreakPatterns)
+				printReplacementRules(writer, summary.Config.Replacements)
+				printRuleField(writer, "reflow_mode", summary.Config.ReflowMode)
+				printRuleField(writer, "paragraph_mode", summary.Config.ParagraphMode)
+				printRuleField(writer, "front_matter", summary.Config.FrontMatter)
+				printRuleField(writer, "markup", summary.Config.Markup)
+				printRuleField(writer, "footnote_mode", summary.Config.FootnoteMode)
+				printRuleList(writer, "footnote_ref_patterns", summary.Config.FootnoteRefPatterns)
+				printRuleList(writer, "footnote_note_patterns", summary.Config.FootnoteNotePatterns)
+				printRuleList(writer, "footnote_inline_patterns", summary.Config.FootnoteInlinePatterns)
+				printRuleList(writer, "image_patterns", summary.Config.ImagePatterns)
+				printRuleField(writer, "chapter_number_regex", summary.Config.ChapterNumberRegex)
+				printRuleField(writer, "missing_chapter_policy", summary.Config.MissingChapterPolicy)
+				printRuleField(writer, "duplicate_chapter_policy", summary.Config.DuplicateChapterPolicy)
+				printRuleField(writer, "chapter_order_policy", summary.Config.ChapterOrderPolicy)
+				printRuleField(writer, "similar_chapter_policy", summary.Config.SimilarChapterPolicy)
+				printRuleField(writer, "similar_chapter_threshold", strconv.FormatFloat(summary.Config.SimilarChapterThreshold, 'f', -1, 64))
+				printRuleField(writer, "similar_chapter_window", strconv.Itoa(summary.Config.SimilarChapterWindow))
 				return nil
 			},
 		},
@@ -159,6 +179,17 @@ func printRuleField(writer io.Writer, name string, value string) {
 	fmt.Fprintf(writer, "%s: %s\n", name, value)
 }
 
+func printReplacementRules(writer io.Writer, rules []goepub.ReplacementRule) {
+	if len(rules) == 0 {
+		fmt.Fprintln(writer, "replacements: []")
+		return
+	}
+	fmt.Fprintln(writer, "replacements:")
+	for index, rule := range rules {
+		fmt.Fprintf(writer, "  %d. %s\n", index+1, rule)
+	}
+}
+
 func printRuleList(writer io.Writer, name string, values []string) {
 	if len(values) == 0 {
 		fmt.Fprintf(writer, "%s: []\n", name)
@@ -121,3 +121,38 @@ chapter_regex = "^第[0-9]+章.*$"
 		t.Fatalf("expected output to contain final ignored_line_contains, got: %s", output)
 	}
 }
+
+func TestRulesShowCommandPrintsReplacements(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := tmpDir + "/rules.toml"
+	configContent := `
+[[replacements]]
+pattern = "请记住本站域名[:：]?\\S*"
+
+[[replacements]]
+pattern = "（求月票）$"
+scope = "title"
+`
+
+	if err := os.WriteFile(configPath, []byte(configContent), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	var buffer bytes.Buffer
+	app := &cli.App{
+		Commands: []*cli.Command{newRulesCommand()},
+		Writer:   &buffer,
+	}
+
+	if err := app.Run([]string{"gotexttoepub", "rules", "show", "--rule-config", configPath}); err != nil {
+		t.Fatalf("run cli app: %v", err)
+	}
+
+	output := buffer.String()
+	if !strings.Contains(output, "  1. [body] 请记住本站域名[:：]?\\S* => \"\"") {
+		t.Fatalf("expected output to contain body replacement, got: %s", output)
+	}
+	if !strings.Contains(output, "  2. [title] （求月票）$") {
+		t.Fatalf("expected output to contain title replacement, got: %s", output)
+	}
+}
@@ -0,0 +1,313 @@
+package goepub
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// 正文标记格式。
+const (
+	// markupPlain 把正文当作纯文本，原样转义输出。
+	markupPlain = "plain"
+	// markupAozora 解析青空文库注记：注音、标题、字下げ和换页。日文网络小说的注音写法与之相同。
+	markupAozora = "aozora"
+)
+
+const (
+	// aozoraNotesTitle 是青空文库文件开头“记号说明”块的标题，这一块会整体跳过。
+	aozoraNotesTitle = "【テキスト中に現れる記号について】"
+	// aozoraAuthorMaxRunes 是书名下一行被当作作者名时的最大长度。
+	aozoraAuthorMaxRunes = 30
+	// aozoraPageBreak 是换页注记对应的输出。
+	aozoraPageBreak = "<div class=\"page-break\"></div>\n"
+)
+
+var (
+	aozoraNotePattern  = regexp.MustCompile(`［＃[^］]*］`)
+	aozoraGaijiPattern = regexp.MustCompile(`※［＃[^］]*?U\+([0-9A-Fa-f]{4,6})[^］]*］`)
+	// 注音有两种写法：“｜青空《あおぞら》”显式标出注音的起点，“青空《あおぞら》”作用于前面连续的汉字。
+	aozoraExplicitRubyPattern = regexp.MustCompile(`[｜|]([^｜|《》]+)《([^《》]+)》`)
+	aozoraRubyPattern         = regexp.MustCompile(`([\p{Han}々〆〇ヶ]+)《([^《》]+)》`)
+	// 傍点有“文字［＃「文字」に傍点］”和“［＃傍点］文字［＃傍点終わり］”两种写法。
+	aozoraEmphasisPattern      = regexp.MustCompile(`［＃「([^」]+)」に[^］]*傍点］`)
+	aozoraEmphasisBlockPattern = regexp.MustCompile(`［＃[^「］]*傍点］(.+?)［＃[^「］]*傍点終わり］`)
+	// 见出し同样有后置、行内和多行三种写法，级别为大、中、小。
+	aozoraHeadingNotePattern       = regexp.MustCompile(`［＃「(.+?)」は(?:同行|窓)?([大中小])見出し］`)
+	aozoraInlineHeadingPattern     = regexp.MustCompile(`［＃(?:同行|窓)?([大中小])見出し］(.+?)［＃(?:同行|窓)?[大中小]見出し終わり］`)
+	aozoraHeadingBlockStartPattern = regexp.MustCompile(`^［＃ここから(?:同行|窓)?([大中小])見出し］$`)
+	aozoraHeadingBlockEndPattern   = regexp.MustCompile(`^［＃ここで(?:同行|窓)?[大中小]見出し終わり］$`)
+	aozoraBlockStartPattern        = regexp.MustCompile(`^［＃ここから([^］]+)］$`)
+	aozoraBlockEndPattern          = regexp.MustCompile(`^［＃ここで[^］]*終わり］$`)
+	aozoraPageBreakPattern         = regexp.MustCompile(`^［＃改(?:ページ|丁|頁|段|見開き)］$`)
+	aozoraRulePattern              = regexp.MustCompile(`^-{20,}$`)
+	aozoraLeadingNotePattern       = regexp.MustCompile(`^［＃([^］]*)］`)
+	aozoraIndentPattern            = regexp.MustCompile(`^(` + japaneseNumeral + `)字下げ`)
+	aozoraRaisePattern             = regexp.MustCompile(`^地から(` + japaneseNumeral + `)字上げ`)
+)
+
+// aozoraState 保存青空文库注记跨行的状态。
+type aozoraState struct {
+	// lines 是已处理的非空行数，nameFromText 表示书名取自第一行，此时第二行按作者处理。
+	lines        int
+	nameFromText bool
+	// notes 表示正处于开头的记号说明块中。
+	notes bool
+	// headingLevel 和 headingLines 用于收集“ここから…見出し”的多行标题。
+	headingLevel string
+	headingLines []string
+	// block 是“ここから２字下げ”等范围注记，范围内的每一行都加上对应的行首注记。
+	block string
+}
+
+func normalizeMarkup(value string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", markupPlain:
+		return markupPlain, nil
+	case markupAozora:
+		return markupAozora, nil
+	}
+	return "", fmt.Errorf("不支持的正文标记格式: %s", value)
+}
+
+// handleAozoraLine 处理只在青空文库注记模式下才有意义的行：标题注记、范围注记、换页和文件头。
+// 已处理的行返回 true；其余的行返回补上范围注记后的文本，继续按普通行处理。
+func (s *textParseState) handleAozoraLine(line string) (string, bool) {
+	a := &s.aozora
+	a.lines++
+	if a.lines == 1 {
+		a.nameFromText = s.parsed.Name == ""
+	}
+
+	if a.headingLevel != "" {
+		if !aozoraHeadingBlockEndPattern.MatchString(line) {
+			a.headingLines = append(a.headingLines, line)
+			return "", true
+		}
+		level, title := a.headingLevel, strings.Join(a.headingLines, "　")
+		a.headingLevel, a.headingLines = "", nil
+		s.startAozoraHeading(level, title, "［＃"+level+"見出し］"+title+"［＃"+level+"見出し終わり］")
+		return "", true
+	}
+
+	if !s.structured {
+		switch {
+		case a.notes:
+			if aozoraRulePattern.MatchString(line) {
+				a.notes = false
+			}
+			s.parsed.Report.IgnoredLines = append(s.parsed.Report.IgnoredLines, reportLine(s.lineNo, line))
+			return "", true
+		case line == aozoraNotesTitle:
+			a.notes = true
+			s.parsed.Report.IgnoredLines = append(s.parsed.Report.IgnoredLines, reportLine(s.lineNo, line))
+			return "", true
+		case aozoraRulePattern.MatchString(line):
+			s.parsed.Report.IgnoredLines = append(s.parsed.Report.IgnoredLines, reportLine(s.lineNo, line))
+			return "", true
+		case a.lines == 2 && a.nameFromText && s.parsed.Author == "" && !strings.Contains(line, "［＃") &&
+			utf8.RuneCountInString(line) <= aozoraAuthorMaxRunes && !s.rules.IsStructuralLine(line):
+			// 青空文库的文件头是“书名、作者”各占一行，作者行没有任何前缀。
+			s.parsed.Author = aozoraPlainText(line)
+			log.Printf("小说作者: %s", s.parsed.Author)
+			return "", true
+		}
+	}
+
+	if m := aozoraHeadingBlockStartPattern.FindStringSubmatch(line); m != nil {
+		a.headingLevel = m[1]
+		return "", true
+	}
+	if m := aozoraHeadingNotePattern.FindStringSubmatch(line); m != nil {
+		s.startAozoraHeading(m[2], m[1], line)
+		return "", true
+	}
+	if m := aozoraInlineHeadingPattern.FindStringSubmatch(line); m != nil {
+		s.startAozoraHeading(m[1], m[2], line)
+		return "", true
+	}
+	if aozoraBlockEndPattern.MatchString(line) {
+		a.block = ""
+		return "", true
+	}
+	if m := aozoraBlockStartPattern.FindStringSubmatch(line); m != nil {
+		a.block = "［＃" + m[1] + "］"
+		return "", true
+	}
+	if aozoraPageBreakPattern.MatchString(line) {
+		s.flushParagraph()
+		s.emitParagraph(Paragraph{Text: line})
+		return "", true
+	}
+	if aozoraPlainText(line) == "" {
+		// 只有注记的行，例如“［＃本文終わり］”。
+		return "", true
+	}
+	if a.block != "" && !strings.HasPrefix(line, "［＃") {
+		line = a.block + line
+	}
+	return line, false
+}
+
+// startAozoraHeading 按见出し级别开启卷或章节：大见出し是卷，中见出し是章节，
+// 小见出し是章节内的小标题，保留原行交给格式化输出为 h3；还没有章节时小见出し也作为章节。
+func (s *textParseState) startAozoraHeading(level, title, line string) {
+	title = s.rules.replaceTitle(title)
+	switch {
+	case level == "大":
+		s.flushChapter()
+		s.flushVolume()
+		s.structured = true
+		s.currentVol = s.newVolume(title)
+		log.Printf("解析卷: %s", title)
+	case level == "中" || s.currentCh == nil:
+		s.startChapter(title)
+		log.Printf("解析章节: %s", title)
+	default:
+		s.flushParagraph()
+		s.emitParagraph(Paragraph{Text: line})
+	}
+}
+
+// aozoraPlainText 去掉注记和注音读法，只保留正文文字，用于书名、作者和卷章标题。
+func aozoraPlainText(text string) string {
+	text = replaceAozoraGaiji(text)
+	text = aozoraExplicitRubyPattern.ReplaceAllString(text, "$1")
+	text = aozoraRubyPattern.ReplaceAllString(text, "$1")
+	text = aozoraNotePattern.ReplaceAllString(text, "")
+	return strings.TrimSpace(text)
+}
+
+// replaceAozoraGaiji 把带 Unicode 码位的外字注记“※［＃「…」、U+5F45、…］”替换为对应的字符，
+// 没有码位的外字注记保留“※”，注记本身随后会被删除。
+// 替换发生在文本清理之后，XML 不允许的字符和零宽字符同样按没有码位处理。
+func replaceAozoraGaiji(text string) string {
+	if !strings.Contains(text, "※［＃") {
+		return text
+	}
+	return aozoraGaijiPattern.ReplaceAllStringFunc(text, func(note string) string {
+		code := aozoraGaijiPattern.FindStringSubmatch(note)[1]
+		value, err := strconv.ParseUint(code, 16, 32)
+		r := rune(value)
+		if err != nil || !utf8.ValidRune(r) || isInvalidXMLRune(r) || isZeroWidthRune(r) {
+			return note
+		}
+		return string(r)
+	})
+}
+
+// formatAozoraParagraph 把含青空文库注记的段落格式化为 XHTML。
+// 行首的字下げ转换为全角空格，地付き和字上げ右对齐；注音输出为 ruby，傍点输出为 em，
+// 不支持的注记直接删除。
+func formatAozoraParagraph(text string) string {
+	text = strings.TrimSpace(text)
+	if aozoraPageBreakPattern.MatchString(text) {
+		return aozoraPageBreak
+	}
+	if m := aozoraHeadingNotePattern.FindStringSubmatch(text); m != nil && m[2] == "小" {
+		return "<h3>" + formatAozoraInline(text) + "</h3>\n"
+	}
+	if m := aozoraInlineHeadingPattern.FindStringSubmatch(text); m != nil && m[1] == "小" {
+		return "<h3>" + formatAozoraInline(text) + "</h3>\n"
+	}
+
+	lines := strings.Split(text, "\n")
+	if len(lines) > 1 {
+		// 空行分段模式下的多行段落，每行保留自己的字下げ。
+		var b strings.Builder
+		b.WriteString(LineBlockStart)
+		for i, line := range lines {
+			if i > 0 {
+				b.WriteString("<br/>\n")
+			}
+			indent, _, _, content := splitAozoraLayout(strings.TrimSpace(line))
+			b.WriteString(strings.Repeat("　", indent))
+			b.WriteString(formatAozoraInline(content))
+		}
+		b.WriteString(ParagraphEnd)
+		return b.String()
+	}
+
+	indent, bottom, raise, content := splitAozoraLayout(text)
+	content = formatAozoraInline(content)
+	switch {
+	case bottom:
+		return "<p style=\"text-align: right; text-indent: 0;\">" + content + strings.Repeat("　", raise) + ParagraphEnd
+	case indent > 0:
+		return LineBlockStart + strings.Repeat("　", indent) + content + ParagraphEnd
+	}
+	return ParagraphStart + content + ParagraphEnd
+}
+
+// splitAozoraLayout 解析行首的排版注记，返回字下げ的字数、是否地付き、地から字上げ的字数和余下的正文。
+// 遇到第一个不是排版注记的注记（例如“［＃傍点］”）就停止，交给行内格式化处理。
+func splitAozoraLayout(line string) (int, bool, int, string) {
+	indent, bottom, raise := 0, false, 0
+	for {
+		m := aozoraLeadingNotePattern.FindStringSubmatch(line)
+		if m == nil {
+			return indent, bottom, raise, line
+		}
+		if n := aozoraIndentPattern.FindStringSubmatch(m[1]); n != nil {
+			indent, _ = parseNumeral(n[1])
+		} else if n := aozoraRaisePattern.FindStringSubmatch(m[1]); n != nil {
+			bottom = true
+			raise, _ = parseNumeral(n[1])
+		} else if strings.HasPrefix(m[1], "地付き") {
+			bottom = true
+		} else {
+			return indent, bottom, raise, line
+		}
+		line = line[len(m[0]):]
+	}
+}
+
+// formatAozoraInline 转义一行正文，并把注音、傍点转换为 XHTML，删除其余注记。
+func formatAozoraInline(text string) string {
+	text = html.EscapeString(replaceAozoraGaiji(text))
+	text = applyAozoraEmphasis(text)
+	text = aozoraEmphasisBlockPattern.ReplaceAllString(text, `<em class="sesame">$1</em>`)
+	text = aozoraExplicitRubyPattern.ReplaceAllString(text, "<ruby>$1<rt>$2</rt></ruby>")
+	text = aozoraRubyPattern.ReplaceAllString(text, "<ruby>$1<rt>$2</rt></ruby>")
+	return aozoraNotePattern.ReplaceAllString(text, "")
+}
+
+// applyAozoraEmphasis 处理后置的傍点注记，只有注记前面紧挨着被引用的文字时才加上傍点。
+func applyAozoraEmphasis(text string) string {
+	matches := aozoraEmphasisPattern.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return text
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		before, target := text[last:m[0]], text[m[2]:m[3]]
+		if strings.HasSuffix(before, target) {
+			b.WriteString(before[:len(before)-len(target)])
+			b.WriteString(`<em class="sesame">` + target + `</em>`)
+		} else {
+			b.WriteString(before)
+		}
+		last = m[1]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// trimAozoraPageBreaks 去掉章节首尾的换页注记，每一章本来就从新的一页开始。
+func trimAozoraPageBreaks(paragraphs []Paragraph) []Paragraph {
+	isPageBreak := func(p Paragraph) bool {
+		return aozoraPageBreakPattern.MatchString(strings.TrimSpace(p.Text))
+	}
+	for len(paragraphs) > 0 && isPageBreak(paragraphs[0]) {
+		paragraphs = paragraphs[1:]
+	}
+	for len(paragraphs) > 0 && isPageBreak(paragraphs[len(paragraphs)-1]) {
+		paragraphs = paragraphs[:len(paragraphs)-1]
+	}
+	return paragraphs
+}
@@ -0,0 +1,180 @@
+package goepub
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFormatAozoraParagraph(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"implicit ruby", "暮方《くれがた》の事", ParagraphStart + "<ruby>暮方<rt>くれがた</rt></ruby>の事" + ParagraphEnd},
+		{"explicit ruby", "一人の｜下人《げにん》が", ParagraphStart + "一人の<ruby>下人<rt>げにん</rt></ruby>が" + ParagraphEnd},
+		{"no ruby base", "書名は《羅生門》だ", ParagraphStart + "書名は《羅生門》だ" + ParagraphEnd},
+		{"emphasis", "誰もいない［＃「誰もいない」に傍点］。", ParagraphStart + `<em class="sesame">誰もいない</em>。` + ParagraphEnd},
+		{"emphasis block", "［＃傍点］本当［＃傍点終わり］に", ParagraphStart + `<em class="sesame">本当</em>に` + ParagraphEnd},
+		{"indent", "［＃３字下げ］下人の行方", LineBlockStart + "　　　下人の行方" + ParagraphEnd},
+		{"bottom", "［＃地付き］（大正四年九月）", `<p style="text-align: right; text-indent: 0;">（大正四年九月）` + ParagraphEnd},
+		{"raise", "［＃地から２字上げ］了", `<p style="text-align: right; text-indent: 0;">了　　` + ParagraphEnd},
+		{"gaiji", "※［＃「彳＋低のつくり」、U+5F7D、12-5］徊", ParagraphStart + "彽徊" + ParagraphEnd},
+		{"invalid gaiji", "次。※［＃「不明」、U+FFFE、1-1］※［＃「制御」、U+0001、1-2］※［＃「零幅」、U+200B、1-3］", ParagraphStart + "次。※※※" + ParagraphEnd},
+		{"escape and unknown note", "<a> & b［＃「b」は縦中横］", ParagraphStart + "&lt;a&gt; &amp; b" + ParagraphEnd},
+		{"page break", "［＃改ページ］", aozoraPageBreak},
+		{"small heading", "［＃２字下げ］老婆《ろうば》［＃「老婆」は小見出し］", "<h3><ruby>老婆<rt>ろうば</rt></ruby></h3>\n"},
+		{"lines", "［＃２字下げ］一行目\n二行目", LineBlockStart + "　　一行目<br/>\n二行目" + ParagraphEnd},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatAozoraParagraph(tt.text); got != tt.want {
+				t.Fatalf("formatAozoraParagraph(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBookAozoraText(t *testing.T) {
+	isolateAutoRuleConfigDiscovery(t)
+
+	body := "　ある日の暮方《くれがた》の事である。一人の｜下人《げにん》が、羅生門の下で雨やみを待っていた。"
+	content := strings.Join([]string{
+		"羅生門《らしょうもん》",
+		"芥川龍之介",
+		"",
+		"-------------------------------------------------------",
+		"【テキスト中に現れる記号について】",
+		"",
+		"《》：ルビ",
+		"（例）下人《げにん》",
+		"-------------------------------------------------------",
+		"",
+		"［＃３字下げ］上［＃「上」は大見出し］",
+		"［＃５字下げ］一［＃「一」は中見出し］",
+		body,
+		"　広い門の下には、この男のほかに誰もいない。",
+		"［＃改ページ］",
+		"［＃ここから中見出し］",
+		"二",
+		"［＃ここで中見出し終わり］",
+		body,
+		"［＃ここから２字下げ］",
+		"下人の行方は、誰も知らない。",
+		"［＃ここで字下げ終わり］",
+		"［＃地付き］（大正四年九月）",
+		"［＃改ページ］",
+		"［＃大見出し］下［＃大見出し終わり］",
+		"第三話　雨の夜",
+		body,
+	}, "\n")
+	txtPath := filepath.Join(t.TempDir(), "rashomon.txt")
+	if err := os.WriteFile(txtPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write txt: %v", err)
+	}
+
+	book := &Book{Filename: txtPath}
+	parsed, err := ParseBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("parse book: %v", err)
+	}
+	if book.Lang != "ja" || !slices.Contains(parsed.Report.AppliedPresets, japaneseRulePreset) {
+		t.Fatalf("expected japanese rules from detected script: lang=%s presets=%v", book.Lang, parsed.Report.AppliedPresets)
+	}
+	if parsed.Name != "羅生門" || parsed.Author != "芥川龍之介" {
+		t.Fatalf("unexpected metadata: %q %q", parsed.Name, parsed.Author)
+	}
+	if len(parsed.Volumes) != 2 || parsed.Volumes[0].Title != "上" || parsed.Volumes[1].Title != "下" {
+		t.Fatalf("unexpected volumes: %+v", parsed.Volumes)
+	}
+	var titles []string
+	for _, volume := range parsed.Volumes {
+		for _, chapter := range volume.Chapters {
+			titles = append(titles, chapter.Title)
+		}
+	}
+	if want := []string{"一", "二", "第三話　雨の夜"}; !slices.Equal(titles, want) {
+		t.Fatalf("unexpected chapters: %v", titles)
+	}
+	if number := parsed.Volumes[1].Chapters[0].Number; number != 3 {
+		t.Fatalf("expected chapter number from 第三話, got %d", number)
+	}
+
+	first := parsed.Volumes[0].Chapters[0].Content.String()
+	if !strings.Contains(first, "<ruby>暮方<rt>くれがた</rt></ruby>") || !strings.Contains(first, "<ruby>下人<rt>げにん</rt></ruby>") {
+		t.Fatalf("expected ruby markup, got %q", first)
+	}
+	if strings.Contains(first, "page-break") {
+		t.Fatalf("page break at the end of a chapter should be dropped: %q", first)
+	}
+	second := parsed.Volumes[0].Chapters[1].Content.String()
+	if !strings.Contains(second, LineBlockStart+"　　下人の行方は、誰も知らない。") ||
+		!strings.Contains(second, `<p style="text-align: right; text-indent: 0;">（大正四年九月）`) {
+		t.Fatalf("expected indent and bottom alignment, got %q", second)
+	}
+	if len(parsed.Report.DiscardedLines) != 0 {
+		t.Fatalf("unexpected discarded lines: %+v", parsed.Report.DiscardedLines)
+	}
+	if len(parsed.Report.IgnoredLines) != 5 {
+		t.Fatalf("expected the notes block to be skipped, got %+v", parsed.Report.IgnoredLines)
+	}
+
+	// 显式指定中文时不会解析注记，标题只能靠中文规则识别。
+	book = &Book{Filename: txtPath, Lang: "zh-CN"}
+	if _, err := ParseBook(context.Background(), book); err == nil {
+		t.Fatal("expected chinese rules to find no chapters")
+	}
+
+	// 只指定 aozora 标记时语言默认为日文。
+	book = &Book{Filename: txtPath, Markup: "aozora", RulePresetMode: presetModeOff}
+	if _, err := ParseBook(context.Background(), book); err != nil {
+		t.Fatalf("parse book with aozora markup: %v", err)
+	}
+	if book.Lang != "ja" {
+		t.Fatalf("expected aozora markup to default to ja, got %s", book.Lang)
+	}
+}
+
+func TestAozoraFrontMatterTitle(t *testing.T) {
+	isolateAutoRuleConfigDiscovery(t)
+
+	content := strings.Join([]string{
+		"羅生門",
+		"芥川龍之介",
+		"この作品は青空文庫から転載したものです。",
+		"［＃大見出し］一［＃大見出し終わり］",
+		"　ある日の暮方の事である。",
+	}, "\n")
+	txtPath := filepath.Join(t.TempDir(), "rashomon.txt")
+	if err := os.WriteFile(txtPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write txt: %v", err)
+	}
+
+	book := &Book{Filename: txtPath, Markup: markupAozora, RulePresetMode: presetModeOff}
+	parsed, err := ParseBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("parse book: %v", err)
+	}
+	if book.Lang != "ja" || len(parsed.Volumes) == 0 || parsed.Volumes[0].Chapters[0].Title != "まえがき" {
+		t.Fatalf("aozora front matter should use the japanese title: lang=%s %+v", book.Lang, parsed.Volumes)
+	}
+
+	// 直接使用解析器时没有书籍语言，青空文库注记同样按日文处理。
+	config := defaultRuleConfig()
+	config.Markup = markupAozora
+	rules, err := compileRuleConfig(config)
+	if err != nil {
+		t.Fatalf("compile rules: %v", err)
+	}
+	parsed, err = NewTextParser().Parse(context.Background(), strings.NewReader(content), rules)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(parsed.Volumes) == 0 || parsed.Volumes[0].Chapters[0].Title != "まえがき" {
+		t.Fatalf("aozora front matter should use the japanese title: %+v", parsed.Volumes)
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+
+	"github.com/lifei6671/gotexttoepub/internal/zhconv"
 )
 
 const (
@@ -14,6 +16,8 @@ const (
 	defaultEncoding     = "auto"
 	defaultPresetMode   = presetModeSuggest
 	maxScannerTokenSize = 4 * 1024 * 1024
+	// presetDetectionPrefixSize 是自动探测规则预设时最多读取的文本前缀字节数。
+	presetDetectionPrefixSize = 256 * 1024
 )
 
 // 文件解析规则的正则表达式
@@ -26,13 +30,27 @@ const (
 	ExtraPattern   = `^番外.{0,30}$`
 	ParagraphStart = "<p style=\"text-indent: 2em;\">"
 	ParagraphEnd   = "</p>\n"
+	// LineBlockStart 用于保留行内换行和缩进的段落，如诗歌、书信和引文。
+	LineBlockStart = "<p class=\"lines\">"
+	// SceneBreak 是场景分隔行（如 ***、☆☆☆）对应的输出。
+	SceneBreak = "<hr class=\"scene-break\"/>\n"
 )
 
+// PartPattern 是“部 > 卷 > 章”三级结构中“部”的正则。
+// 默认规则里“第X部”仍按卷处理，只有启用 parts 预设或设置 part_regex 后才会使用它。
+const PartPattern = `^(第[一二三四五六七八九十百零0-9]+部)([\s　:：\-—].{0,30})?$`
+
 // Volume 表示一本书中的“卷”。
 // 有些小说没有卷的概念，此时 Title 可以为空，只包含章节列表。
 type Volume struct {
 	Title    string
 	Chapters []Chapter
+	// Line 是卷标题在源文件中的行号，从 1 开始；0 表示未知或匿名卷。
+	Line int
+	// Part 是卷所属“部”的标题，只有启用三级结构时才会设置，PartLine 是部标题的行号。
+	// 同一部下的卷相邻排列，写出 EPUB 时归到同一个部的目录节点下。
+	Part     string
+	PartLine int
 }
 
 // Chapter 表示单个章节。
@@ -40,6 +58,10 @@ type Volume struct {
 type Chapter struct {
 	Title   string
 	Content strings.Builder
+	// Line 是章节标题在源文件中的行号，从 1 开始；0 表示未知。
+	Line int
+	// Number 是从标题中解析出的章节序号；0 表示标题中没有可识别的序号。
+	Number int
 }
 
 // Book 描述一次转换任务所需的全部输入和解析配置。
@@ -51,19 +73,25 @@ type Book struct {
 	Author string
 	// Volumes 是解析后的卷章结构。
 	Volumes []Volume
+	// Images 是正文引用的本地图片，写出时会一并打包进 EPUB。
+	Images []Image
 	// Cover 支持本地路径或网络 URL。
 	Cover string
-	// Lang 是 EPUB 语言标识，默认使用 zh-CN。
+	// Lang 是 EPUB 语言标识，默认使用 zh-CN；设置了简繁转换时默认使用目标地区的标识。
 	Lang string
 	// Encoding 是输入 TXT 的编码格式，默认 auto。
-	// 当前支持 auto、utf-8、gbk、gb18030。
+	// 支持 auto、utf-8、utf-16le、utf-16be、gbk、gb18030、big5、shift_jis，完整列表见 SupportedEncodings；
+	// 也接受 utf8、gb2312、cp936、cp950、sjis、cp932 等常见别名。
 	Encoding string
 	// Intro 是图书简介；留空时会尝试从“简介/序/楔子”等章节推导。
 	Intro       string
 	Publisher   string
 	PublishDate string
-	// Filename 是输入 TXT 文件路径。
+	// Filename 是输入 TXT 或 Markdown 文件路径。
 	Filename string
+	// InputFormat 是输入文件的格式，支持 txt、markdown；留空时按扩展名判断，.md 和 .markdown 为 markdown。
+	// Markdown 的卷章来自 # 和 ## 标题，书名、作者等元信息来自 YAML 头信息。
+	InputFormat string
 	// Output 既可以是输出文件路径，也可以是输出目录。
 	Output string
 	// RulePresets 是可选的命名规则预设列表。
@@ -78,7 +106,58 @@ type Book struct {
 	// RuleConfigPath 是可选的规则配置文件路径。
 	// 留空时完全使用内置规则，填写后会在内置规则基础上做覆盖。
 	RuleConfigPath string
+	// Parser 是可选的自定义解析器，留空时使用内置的正则解析器。
+	Parser Parser
+	// LineFilters 在内置解析器识别每一行之前按顺序执行，可用于自定义清洗或屏蔽。
+	// 过滤器只对 TXT 生效，Markdown 输入设置了过滤器时解析失败。
+	LineFilters []LineFilter
+	// ChapterFilters 在每章段落格式化为 XHTML 之前按顺序执行。
+	ChapterFilters []ChapterFilter
+	// MissingChapterPolicy 控制章节缺号检查，支持 off、warn；留空时使用规则配置。
+	MissingChapterPolicy string
+	// DuplicateChapterPolicy 控制重复章节检查，支持 off、warn、fix；
+	// fix 会删除序号和正文都相同的重复章节。
+	DuplicateChapterPolicy string
+	// ChapterOrderPolicy 控制章节乱序检查，支持 off、warn、fix；
+	// fix 会在卷内按序号重新排序。
+	ChapterOrderPolicy string
+	// SimilarChapterPolicy 控制正文相似章节的处理，支持 off、report、keep_longest、keep_latest；
+	// 留空时使用规则配置。
+	SimilarChapterPolicy string
+	// ReflowMode 控制硬折行文本的段落重排，支持 auto、off、force；留空时使用规则配置。
+	ReflowMode string
+	// ParagraphMode 控制段落划分方式，支持 line、blank；留空时使用规则配置。
+	// blank 模式以空行分段，段内换行和缩进会保留到 XHTML 中。
+	ParagraphMode string
+	// FrontMatter 控制首个卷章之前正文的处理方式，支持 keep、drop、intro；留空时使用规则配置。
+	// keep 保留为第一卷之前的“前言”章节，drop 丢弃，intro 并入简介。
+	FrontMatter string
+	// Markup 是正文的标记格式，支持 plain、aozora；留空时使用规则配置。
+	// aozora 解析青空文库注记，注音输出为 ruby，见出し注记决定卷章结构；未指定语言时语言设为 ja。
+	Markup string
+	// FootnoteMode 控制注释识别，支持 on、off；留空时使用规则配置。
+	// on 把 [1]、注①、（注：…）等注释转换为 EPUB3 弹出式脚注，注释内容放在章节末尾；默认 off，正文保持原样。
+	FootnoteMode string
+	// DisableImageLookup 为 true 时不在 TXT 或 Markdown 所在目录中查找正文引用的图片，图片引用都按找不到处理。
+	// 服务端转换单独上传的 TXT 时应当打开，避免插图引用把同目录的其他文件打包进 EPUB。
+	DisableImageLookup bool
+	// VerticalWriting 为 true 时输出竖排版式，翻页方向为从右向左。
+	VerticalWriting bool
+	// ChapterSplitSize 是单个章节 XHTML 正文的字节数上限，超过时在段落边界拆分为多个续接文件，
+	// 目录仍指向章节开头；0 使用默认的 256 KB，负数不拆分。
+	ChapterSplitSize int
+	// PackChapters 为 true 时把同一卷中相邻的章节合并写入同一个 XHTML 文件，合并后不超过 ChapterSplitSize，
+	// 目录项指向各章标题的锚点。章节数以千计的网络小说可以借此减少书脊和清单中的文件数。
+	PackChapters bool
+	// TOCGroupSize 大于 0 时，没有卷标题的章节超过这个数量会在目录中每 TOCGroupSize 章归为一组，
+	// 分组标题形如“第1–100章”，只出现在目录中，没有标题页，正文不变。
+	TOCGroupSize int
+	// ChineseConversion 是简繁转换方式，支持 s2t、t2s、s2tw、s2hk；留空不转换。
+	// 转换作用于解析出的书名、作者、简介、卷章标题和正文，调用方显式提供的元信息保持原样。
+	ChineseConversion string
 
+	// PartRegex 用于识别卷之上的“部”标题，为空时不启用三级结构。
+	PartRegex *regexp.Regexp
 	// VolumeRegex 用于识别卷标题。
 	VolumeRegex *regexp.Regexp
 	// TitleRegex 用于识别书名。
@@ -87,6 +166,9 @@ type Book struct {
 	AuthorRegex *regexp.Regexp
 	// ChapterRegex 用于识别章节标题。
 	ChapterRegex *regexp.Regexp
+	// InferChapterRegex 为 true 时，如果章节正则一个章节都没有匹配到，
+	// 会根据正文推断章节正则并重新解析，对应命令行的 -chapter-regexp=auto。
+	InferChapterRegex bool
 	// ExtraRegex 用于识别“番外”等特殊章节。
 	ExtraRegex *regexp.Regexp
 	// IntroRegex 用于识别简介类章节。
@@ -94,13 +176,37 @@ type Book struct {
 
 	parseRules          *ParseRules
 	detectedRulePresets []string
+	// languageRulePreset 是按 Lang 或文字种类选择的语言规则预设。
+	languageRulePreset string
+	// detectLanguage 表示调用方没有指定语言，需要根据正文的文字种类选择语言规则。
+	detectLanguage bool
+	// inferLang 表示语言不是调用方指定的，输入文件自带的语言信息可以覆盖它。
+	inferLang bool
 }
 
 // FullDefault 填充默认值并规范化路径。
 func (book *Book) FullDefault() error {
+	conversion, err := zhconv.Normalize(book.ChineseConversion)
+	if err != nil {
+		return err
+	}
+	book.ChineseConversion = conversion
+	book.detectLanguage = false
+	book.inferLang = false
 	if strings.TrimSpace(book.Lang) == "" {
 		book.Lang = defaultLanguage
+		if lang, ok := chineseConversionLanguages[conversion]; ok {
+			book.Lang = lang
+		}
+		// 简繁转换只用于中文，此时不需要再按文字种类判断语言。
+		book.inferLang = conversion == ""
+		book.detectLanguage = book.inferLang
+		if strings.EqualFold(strings.TrimSpace(book.Markup), markupAozora) {
+			book.Lang = japaneseRulePreset
+			book.detectLanguage = false
+		}
 	}
+	book.languageRulePreset = languageRulePreset(book.Lang)
 	if strings.TrimSpace(book.Encoding) == "" {
 		book.Encoding = defaultEncoding
 	}
@@ -113,11 +219,18 @@ func (book *Book) FullDefault() error {
 		return fmt.Errorf("解析输入文件路径失败: %w", err)
 	}
 	if strings.TrimSpace(filename) == "" {
-		return fmt.Errorf("TXT 文件路径不能为空")
+		// 已经带有卷章结构的 Book 不再需要读取 TXT，输入路径可以为空。
+		if len(book.Volumes) == 0 {
+			return fmt.Errorf("TXT 文件路径不能为空")
+		}
+	} else {
+		book.Filename, err = filepath.Abs(filename)
+		if err != nil {
+			return fmt.Errorf("解析输入文件绝对路径失败: %w", err)
+		}
 	}
-	book.Filename, err = filepath.Abs(filename)
-	if err != nil {
-		return fmt.Errorf("解析输入文件绝对路径失败: %w", err)
+	if book.InputFormat, err = normalizeInputFormat(book.InputFormat, book.Filename); err != nil {
+		return err
 	}
 
 	if strings.TrimSpace(book.Output) != "" {
@@ -145,7 +258,11 @@ func (book *Book) FullDefault() error {
 
 	book.Name = strings.TrimSpace(book.Name)
 	book.Author = strings.TrimSpace(book.Author)
-	book.Encoding = normalizeEncodingName(book.Encoding)
+	encoding := normalizeEncodingName(book.Encoding)
+	if !isSupportedEncoding(encoding) {
+		return unsupportedEncodingError(book.Encoding)
+	}
+	book.Encoding = encoding
 	book.Intro = strings.TrimSpace(book.Intro)
 	book.RulePresetMode = normalizePresetMode(book.RulePresetMode)
 
@@ -153,14 +270,20 @@ func (book *Book) FullDefault() error {
 	if err != nil {
 		return err
 	}
-	book.parseRules = parseRules
-	book.VolumeRegex = parseRules.VolumeRegex
-	book.ChapterRegex = parseRules.ChapterRegex
-	book.ExtraRegex = parseRules.ExtraRegex
-	book.IntroRegex = parseRules.IntroRegex
+	book.setParseRules(parseRules)
 	return nil
 }
 
+// setParseRules 保存组合好的解析规则，并把卷章正则回写到 Book 上供调用方查看。
+func (book *Book) setParseRules(rules *ParseRules) {
+	book.parseRules = rules
+	book.PartRegex = rules.PartRegex
+	book.VolumeRegex = rules.VolumeRegex
+	book.ChapterRegex = rules.ChapterRegex
+	book.ExtraRegex = rules.ExtraRegex
+	book.IntroRegex = rules.IntroRegex
+}
+
 // OutputPath 根据书名、输入文件名和输出参数，推导最终的 EPUB 文件路径。
 // 这样命令行可以同时支持“输出目录”和“输出文件”两种写法。
 func (book *Book) OutputPath() (string, error) {
@@ -200,7 +323,7 @@ func FlagParse() *Book {
 	flag.StringVar(&book.Name, "name", "", "书名，不填写程序会自动解析")
 	flag.StringVar(&book.Author, "author", "", "作者，不填写程序会自动解析")
 	flag.StringVar(&book.Lang, "lang", "", "语言，默认中文，可设置其他语言如：en,de,fr,it,es,zh,ja,pt,ru,nl")
-	flag.StringVar(&book.Encoding, "encoding", "", "文本编码，默认 auto，可设置 utf-8、gbk、gb18030")
+	flag.StringVar(&book.Encoding, "encoding", "", "文本编码，默认 auto，可设置 utf-8、utf-16le、utf-16be、gbk、gb18030、big5、shift_jis")
 	flag.StringVar(&book.Cover, "cover", "", "封面图片的路径，可以是本地文件路径也可以是网络图片url")
 	flag.StringVar(&book.Intro, "intro", "", "简介，不填写程序会自动解析")
 	flag.StringVar(&book.Publisher, "publisher", "", "出版社")
@@ -216,6 +339,8 @@ func FlagParse() *Book {
 	flag.StringVar(&titlePattern, "title-regexp", "", "书名的解析规则，不填写程序会自动解析")
 	var authorPattern string
 	flag.StringVar(&authorPattern, "author-regexp", "", "作者的解析规则，不填写程序会自动解析")
+	var partPattern string
+	flag.StringVar(&partPattern, "part-pattern", "", "部的解析规则，用于“部 > 卷 > 章”三级结构，默认不启用")
 	var volumePattern string
 	flag.StringVar(&volumePattern, "volume-pattern", "", "卷的解析规则,不填写程序会自动解析")
 	var chapterPattern string
@@ -226,6 +351,9 @@ func FlagParse() *Book {
 	flag.StringVar(&introPattern, "intro-pattern", "", "简介的解析规则，不填写程序会自动解析")
 	flag.Parse()
 
+	if partPattern != "" {
+		book.PartRegex = regexp.MustCompile(partPattern)
+	}
 	if volumePattern != "" {
 		book.VolumeRegex = regexp.MustCompile(volumePattern)
 	}
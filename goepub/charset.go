@@ -0,0 +1,307 @@
+package goepub
+
+import (
+	"bytes"
+	"cmp"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// encodingSampleSize 是 auto 模式下用于给候选编码评分的文件开头字节数。
+const encodingSampleSize = 64 * 1024
+
+var (
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+)
+
+// supportedEncodings 是 Book.Encoding 可以使用的编码名称。
+var supportedEncodings = []string{
+	encodingAuto,
+	encodingUTF8,
+	encodingUTF16LE,
+	encodingUTF16BE,
+	encodingGBK,
+	encodingGB18030,
+	encodingBig5,
+	encodingShiftJIS,
+}
+
+// autoEncodingCandidates 是 auto 模式下参与评分的编码；得分相同时排在前面的优先。
+// 只有整份文件不是有效 UTF-8 时才需要评分，所以 UTF-8 排在单字节兼容 ASCII 的编码之后，
+// 纯 ASCII 样本仍按历史行为选择 GB18030。GB18030 兼容 GBK，因此不再单独给 GBK 评分。
+var autoEncodingCandidates = []string{
+	encodingGB18030,
+	encodingBig5,
+	encodingShiftJIS,
+	encodingUTF8,
+	encodingUTF16LE,
+	encodingUTF16BE,
+}
+
+// legacyEncodingCandidates 是按行修复混合编码时尝试的多字节编码。
+var legacyEncodingCandidates = []string{encodingGB18030, encodingBig5, encodingShiftJIS}
+
+// SupportedEncodings 返回 Book.Encoding 支持的编码名称。
+func SupportedEncodings() []string {
+	return slices.Clone(supportedEncodings)
+}
+
+func isSupportedEncoding(name string) bool {
+	return slices.Contains(supportedEncodings, name)
+}
+
+// EncodingCandidate 是自动探测时某个候选编码的评分。
+type EncodingCandidate struct {
+	Encoding string `json:"encoding"`
+	// Confidence 是 0 到 1 之间的置信度。
+	Confidence float64 `json:"confidence"`
+	// InvalidSequences 是用该编码解码样本时遇到的无效字节序列数。
+	InvalidSequences int `json:"invalid_sequences"`
+}
+
+// encodingDetection 是一次编码判定的结果。
+// 显式指定编码、BOM 和整份文件都是有效 UTF-8 这几种情况不需要评分，candidates 为空。
+type encodingDetection struct {
+	encoding   string
+	confidence float64
+	candidates []EncodingCandidate
+	// segments 是 auto 模式下的按行解码器，读完文件后从中取出修复记录。
+	segments *segmentDecoder
+}
+
+// repairs 返回按行修复的文本段，必须在读完解码后的文本之后调用。
+func (d encodingDetection) repairs() []EncodingRepair {
+	if d.segments == nil {
+		return nil
+	}
+	return d.segments.repairs
+}
+
+// textEncoding 返回需要转码的编码实现；UTF-8 不需要转码，返回 nil。
+// UTF-16 遇到 BOM 时以 BOM 为准并去掉它。
+func textEncoding(name string) encoding.Encoding {
+	switch name {
+	case encodingGBK:
+		return simplifiedchinese.GBK
+	case encodingGB18030:
+		return simplifiedchinese.GB18030
+	case encodingBig5:
+		return traditionalchinese.Big5
+	case encodingShiftJIS:
+		return japanese.ShiftJIS
+	case encodingUTF16LE:
+		return unicode.UTF16(unicode.LittleEndian, unicode.UseBOM)
+	case encodingUTF16BE:
+		return unicode.UTF16(unicode.BigEndian, unicode.UseBOM)
+	default:
+		return nil
+	}
+}
+
+// detectBOM 根据文件开头的 BOM 判断编码，返回编码名称和 BOM 长度。
+func detectBOM(head []byte) (string, int) {
+	switch {
+	case bytes.HasPrefix(head, utf8BOM):
+		return encodingUTF8, len(utf8BOM)
+	case bytes.HasPrefix(head, utf16LEBOM):
+		return encodingUTF16LE, len(utf16LEBOM)
+	case bytes.HasPrefix(head, utf16BEBOM):
+		return encodingUTF16BE, len(utf16BEBOM)
+	}
+	return "", 0
+}
+
+// detectSampleEncoding 用样本给候选编码评分并选出得分最高的一个。
+// truncated 表示样本是从更长的文件中截取的，末尾可能有半个字符。
+func detectSampleEncoding(sample []byte, truncated bool) encodingDetection {
+	if truncated {
+		// 在双字节编码中换行符不会出现在字符中间，截到最后一个换行可以避免末尾的半个字符被算作错误。
+		if cut := bytes.LastIndexByte(sample, '\n'); cut > 0 {
+			sample = sample[:cut+1]
+		}
+	}
+
+	candidates := make([]EncodingCandidate, 0, len(autoEncodingCandidates))
+	for _, name := range autoEncodingCandidates {
+		candidates = append(candidates, scoreEncoding(sample, name))
+	}
+	// 稳定排序保证得分相同时按候选顺序取舍，例如纯 ASCII 样本仍按历史行为选择 GB18030。
+	slices.SortStableFunc(candidates, func(a, b EncodingCandidate) int {
+		return cmp.Compare(b.Confidence, a.Confidence)
+	})
+	return encodingDetection{
+		encoding:   candidates[0].Encoding,
+		confidence: candidates[0].Confidence,
+		candidates: candidates,
+	}
+}
+
+// scoreEncoding 用指定编码解码样本并打分。
+func scoreEncoding(sample []byte, name string) EncodingCandidate {
+	candidate := EncodingCandidate{Encoding: name}
+	if name == encodingUTF8 {
+		return scoreUTF8Lines(sample)
+	}
+	text := string(sample)
+	if enc := textEncoding(name); enc != nil {
+		if name == encodingUTF16LE || name == encodingUTF16BE {
+			text = string(sample[:len(sample)&^1])
+		}
+		decoded, _, err := transform.String(enc.NewDecoder(), text)
+		if err != nil {
+			return candidate
+		}
+		text = decoded
+	}
+	candidate.Confidence, candidate.InvalidSequences = scoreText(text)
+	return candidate
+}
+
+// scoreUTF8Lines 给 UTF-8 打分。混合编码的文件里 UTF-8 行本身是完好的，
+// 因此只对有效的行打分，再乘以这些行占非 ASCII 字节的比例，
+// 这样以 UTF-8 为主、夹杂少量其他编码的文件仍会选择 UTF-8 作为主编码。
+func scoreUTF8Lines(sample []byte) EncodingCandidate {
+	candidate := EncodingCandidate{Encoding: encodingUTF8}
+	var valid strings.Builder
+	validBytes, totalBytes := 0, 0
+	for _, line := range bytes.SplitAfter(sample, []byte("\n")) {
+		if !utf8.Valid(line) {
+			totalBytes += len(line)
+			candidate.InvalidSequences += countInvalidUTF8(line)
+			continue
+		}
+		valid.Write(line)
+		if !isASCII(line) {
+			validBytes += len(line)
+			totalBytes += len(line)
+		}
+	}
+	candidate.Confidence, _ = scoreText(valid.String())
+	if totalBytes > 0 {
+		candidate.Confidence *= float64(validBytes) / float64(totalBytes)
+	}
+	return candidate
+}
+
+// countInvalidUTF8 统计无效的 UTF-8 字节个数。
+func countInvalidUTF8(data []byte) int {
+	count := 0
+	for len(data) > 0 {
+		r, size := utf8.DecodeRune(data)
+		if r == utf8.RuneError && size == 1 {
+			count++
+		}
+		data = data[size:]
+	}
+	return count
+}
+
+// scoreText 按字符分布给解码结果打分，返回 0 到 1 之间的置信度和替换字符个数。
+// 正确的解码会得到大量常用汉字、假名和中文标点；错误的解码则会出现替换字符、
+// 控制字符、半角片假名、私用区字符或罕用字。
+func scoreText(text string) (float64, int) {
+	commonHan := commonHanSet()
+	var weight float64
+	counted, invalid := 0, 0
+	for _, r := range text {
+		switch {
+		case r == utf8.RuneError:
+			invalid++
+			weight -= 3
+		case r < utf8.RuneSelf:
+			if (r < 0x20 && r != '\t' && r != '\n' && r != '\r' && r != '\f') || r == 0x7F {
+				weight -= 3
+				counted++
+			}
+			// 可打印 ASCII 在各编码下都一样，不参与评分。
+			continue
+		case hasRune(commonHan, r):
+			weight++
+		case r >= 0x3000 && r <= 0x303F, r >= 0xFF01 && r <= 0xFF5E, r >= 0x2010 && r <= 0x2027:
+			// 中文标点、全角字符和引号、省略号等通用标点。
+			weight++
+		case r >= 0x3040 && r <= 0x30FF:
+			// 平假名和片假名。
+			weight += 0.5
+		case r >= 0x4E00 && r <= 0x9FFF:
+			weight += 0.2
+		default:
+			weight--
+		}
+		counted++
+	}
+	if counted == 0 {
+		return 1, invalid
+	}
+	// 单个字符最多加 1 分，因此平均分不会超过 1。
+	return max(weight/float64(counted), 0), invalid
+}
+
+func hasRune(set map[rune]struct{}, r rune) bool {
+	_, ok := set[r]
+	return ok
+}
+
+var commonHanSet = sync.OnceValue(func() map[rune]struct{} {
+	set := make(map[rune]struct{}, utf8.RuneCountInString(commonHanSimplified)+utf8.RuneCountInString(commonHanTraditional))
+	for _, r := range commonHanSimplified + commonHanTraditional {
+		set[r] = struct{}{}
+	}
+	return set
+})
+
+// commonHanSimplified 是现代中文里最常用的约 1200 个简体汉字。
+const commonHanSimplified = "的一是不了人我在有他这个们中来上大为和国地到以说时要就出会可也你对生能而子那得于" +
+	"着下自之年过发后作里用道行所然家种事成方多经么去法学如都同现当没动面起看定天分还" +
+	"进好小部其些主样理心她本前开但因只从想实日军者意无力它与长把机十民第公此已工使情" +
+	"明性知全三又关点正业外将两高间由问很最重并物手应战向头文体政美相见被利什二等产或" +
+	"新己制身果加西斯月话合回特代内信表化老给世位次度门任常先海通教儿原东声提立及比员" +
+	"解水名真论处走义各入几口认条平系气题活尔更别打女变四神总何电数安少报才结反受目太" +
+	"量再感建务做接必场件计管期市直德资命山金指克许统区保至队形社便空决治展马科司五基" +
+	"眼书非则听白却界达光放强即像难且权思王象完设式色路记南品住告类求据程北边死张该交" +
+	"规万取拉格望觉术领共确传师观清今切院让识候带导争运笑飞风步改收根干造言联持组每济" +
+	"车亲极林服快办议往元英士证近失转夫令准布始怎呢存未远叫台单影具罗字爱击流备兵连调" +
+	"深商算质团集百需价花党华城石级整府离况亚请技际约示复病息究线似官火断精满支视消越" +
+	"器容照须九增研写称企八功吗包片史委乎查轻易早曾除农找装广显吧阿李标谈吃图念六引历" +
+	"首医局突专费号尽另周较注语仅考落青随选列武红响虽推势参希古众构房半节土投某案黑维" +
+	"革划敌致陈律足态护七兴派孩验责营星够章音跟志底站严巴例防族供效续施留讲型料终答紧" +
+	"黄绝奇察母京段依批群项故按河米围江织害斗双境客纪采举杀攻父苏密低朝友诉止细愿千值" +
+	"仍男钱破网热助倒育属坐帝限船脸职速刻乐否刚威毛状率甚独球般普怕弹校苦创假久错承印" +
+	"晚兰试股拿脑预谁益阳若哪微尼继送急血惊伤素药适波夜省初喜卫源食险待述陆习置居劳财" +
+	"环排福纳欢雷警获模充负云停木游龙树疑层冷洲冲射略范竟句室异激汉村哈策演简卡罪判担" +
+	"州静退既衣您宗积余痛检差富灵协角占配征修皮挥胜降阶审沉坚善妈刘读啊超免压银买皇养" +
+	"伊怀执副乱抗犯追帮宣佛岁航优怪香著田铁控税左右份穿艺背阵草脚概恶块顿敢守酒岛托央" +
+	"户烈洋哥索胡款靠评版宝座释景顾弟登货互付伯慢欧换闻危忙核暗姐介坏讨丽良序升监临亮" +
+	"露永呼味野架域沙掉括舰鱼杂误湾吉减编楚肯测败屋跑梦散温困剑渐封救贵枪缺楼县尚毫移" +
+	"娘朋画班智亦耳恩短掌恐遗固席松秘谢鲁遇康虑幸均销钟诗藏赶剧票损忽巨炮旧端探湖录叶" +
+	"春乡附吸予礼港雨呀板庭妇归睛饭额含顺输摇招婚脱补谓督毒油疗旅泽材灭逐莫笔亡鲜词圣" +
+	"择寻厂睡博勒烟授诺伦岸奥唐卖俄炸载洛健堂旁宫喝借君禁阴园谋宋避抓荣姑孙逃牙束跳顶" +
+	"玉镇雪午练迫爷篇肉嘴馆遍凡础洞卷坦牛宁纸诸训私庄祖丝翻暴森塔默握戏隐熟骨访弱蒙歌" +
+	"店鬼软典欲萨伙遭盘爸扩盖弄雄稳忘亿刺拥徒姆杨齐赛趣曲刀床迎冰虚玩析窗醒妻透购替塞" +
+	"努休虎扬途侵刑绿兄迅套贸毕唯谷轮库迹尤竞街促延震弃甲伟麻川申缓潜闪售灯针哲络抵朱" +
+	"埃抱鼓植纯夏忍页杰筑折郑贝尊吴秀混臣雅振染盛怒舞圆搞狂措姓残秋培迷诚宽宇猛摆梅毁" +
+	"伸摩盟末乃悲拍丁赵尖偷肃叹嘿呵哦嗯咱俺啥吼喊哭泪眉脖肩腰腿膝拳胸腹腕颈唇舌齿鼻喉"
+
+// commonHanTraditional 是上述汉字在繁体、台湾和香港用字中的不同写法。
+const commonHanTraditional = "這個們來爲為國說説時會對於過發後裏裡種經麼學現當沒動還進樣開從實軍無與長機關點業" +
+	"將兩間問並應戰頭體見產話內給門兒東聲員論處義幾認條氣題爾別變總電數報結務場計資許" +
+	"統區隊決馬書則聽卻達強難權設記類據邊張該規萬覺術領確傳師觀讓識帶導爭運飛風幹聯組" +
+	"濟車親極辦議證轉準遠臺單羅愛擊備連調質團價黨華級離況亞請際約復線斷滿視須寫稱嗎輕" +
+	"農裝廣顯標談喫圖歷醫專費號盡較語僅隨選紅響雖勢參衆眾構節維劃敵陳態護興驗責營夠嚴" +
+	"續講終緊黃絕羣項圍織鬥雙紀採舉殺蘇訴細願錢網熱屬臉職樂剛狀獨彈創錯蘭試腦預誰陽繼" +
+	"驚傷藥適衛衞險陸習勞財環納歡獲負雲遊龍樹層衝範異漢簡擔靜積餘檢靈協佔徵揮勝階審堅" +
+	"媽劉讀壓銀買養懷執亂幫歲優鐵稅藝陣腳惡塊頓島託戶評寶釋顧貨歐換聞壞討麗監臨艦魚雜" +
+	"誤灣減編測敗夢溫劍漸貴槍樓縣畫遺祕謝魯慮銷鍾詩趕劇損舊錄葉鄉禮婦歸飯額順輸搖脫補" +
+	"謂療澤滅筆鮮詞聖擇尋廠煙諾倫奧賣載宮陰園謀榮孫頂鎮練爺館礎寧紙諸訓莊絲戲隱訪軟薩" +
+	"夥盤擴蓋穩億擁楊齊賽牀虛購揚綠貿畢輪庫跡競棄偉緩潛閃燈針絡純頁傑築鄭貝吳圓殘誠寬" +
+	"擺毀趙肅嘆淚頸脣齒"
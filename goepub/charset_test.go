@@ -0,0 +1,114 @@
+package goepub
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+func writeEncodedText(t *testing.T, enc encoding.Encoding, source string) string {
+	t.Helper()
+	encoded, _, err := transform.String(enc.NewEncoder(), source)
+	if err != nil {
+		t.Fatalf("encode text: %v", err)
+	}
+	txtPath := filepath.Join(t.TempDir(), "encoded.txt")
+	if err := os.WriteFile(txtPath, []byte(encoded), 0o644); err != nil {
+		t.Fatalf("write txt: %v", err)
+	}
+	return txtPath
+}
+
+func readDetectedText(t *testing.T, txtPath string, encoding string) (string, encodingDetection) {
+	t.Helper()
+	reader, detection, err := openTextReader(txtPath, encoding)
+	if err != nil {
+		t.Fatalf("open text reader: %v", err)
+	}
+	defer reader.Close()
+	decoded, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("read decoded text: %v", err)
+	}
+	return string(decoded), detection
+}
+
+func TestOpenTextReaderDetectsEncodings(t *testing.T) {
+	chinese := "第一章 開始\n　　他說：「我們明天再見吧。」然後轉身離開了這個地方。\n"
+	simplified := "第一章 开始\n　　他说：“我们明天再见吧。”然后转身离开了这个地方。\n"
+	japaneseText := "第一章 始まり\n　　彼は「また明日会いましょう」と言って、静かに部屋を出ていった。\n"
+	tests := []struct {
+		name     string
+		enc      encoding.Encoding
+		source   string
+		want     string
+		scored   bool
+		wantText string
+	}{
+		{name: "big5", enc: traditionalchinese.Big5, source: chinese, want: encodingBig5, scored: true},
+		{name: "shift_jis", enc: japanese.ShiftJIS, source: japaneseText, want: encodingShiftJIS, scored: true},
+		{name: "utf-16le without bom", enc: unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), source: simplified, want: encodingUTF16LE, scored: true},
+		{name: "utf-16be without bom", enc: unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), source: simplified, want: encodingUTF16BE, scored: true},
+		{name: "utf-16le with bom", enc: unicode.UTF16(unicode.LittleEndian, unicode.UseBOM), source: simplified, want: encodingUTF16LE},
+		{name: "utf-16be with bom", enc: unicode.UTF16(unicode.BigEndian, unicode.UseBOM), source: simplified, want: encodingUTF16BE},
+		{name: "ascii utf-16le", enc: unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), source: "Chapter 1\nHello world.\n", want: encodingUTF16LE, scored: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txtPath := writeEncodedText(t, tt.enc, strings.Repeat(tt.source, 3))
+			decoded, detection := readDetectedText(t, txtPath, "")
+			if detection.encoding != tt.want {
+				t.Fatalf("expected encoding %s, got %+v", tt.want, detection)
+			}
+			if decoded != strings.Repeat(tt.source, 3) {
+				t.Fatalf("unexpected decoded content: %q", decoded)
+			}
+			if !tt.scored {
+				if detection.confidence != 1 || len(detection.candidates) != 0 {
+					t.Fatalf("bom detection should not be scored: %+v", detection)
+				}
+				return
+			}
+			if len(detection.candidates) < 2 || detection.candidates[0].Encoding != tt.want {
+				t.Fatalf("unexpected candidates: %+v", detection.candidates)
+			}
+			if detection.confidence < 0.5 || detection.candidates[1].Confidence >= detection.confidence {
+				t.Fatalf("expected a clear winner: %+v", detection.candidates)
+			}
+		})
+	}
+}
+
+func TestOpenTextReaderUsesExplicitEncodingAliases(t *testing.T) {
+	source := "第一章 開始\n這是一段正文。\n"
+	txtPath := writeEncodedText(t, traditionalchinese.Big5, source)
+
+	decoded, detection := readDetectedText(t, txtPath, "CP950")
+	if detection.encoding != encodingBig5 || detection.confidence != 1 || decoded != source {
+		t.Fatalf("unexpected explicit decoding: %+v %q", detection, decoded)
+	}
+}
+
+func TestUnsupportedEncodingListsSupportedValues(t *testing.T) {
+	supported := SupportedEncodings()
+	for _, name := range []string{"auto", "utf-8", "utf-16le", "utf-16be", "gbk", "gb18030", "big5", "shift_jis"} {
+		if !slices.Contains(supported, name) {
+			t.Fatalf("expected %s in supported encodings: %v", name, supported)
+		}
+	}
+
+	book := &Book{Filename: "book.txt", Encoding: "latin1"}
+	err := book.FullDefault()
+	if err == nil || !strings.Contains(err.Error(), "latin1") || !strings.Contains(err.Error(), "shift_jis") {
+		t.Fatalf("expected unsupported encoding error listing supported values, got %v", err)
+	}
+}
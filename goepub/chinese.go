@@ -0,0 +1,50 @@
+package goepub
+
+import (
+	"strings"
+
+	"github.com/lifei6671/gotexttoepub/internal/zhconv"
+)
+
+// chineseConversionLanguages 是各简繁转换方式对应的 EPUB 语言标识。
+var chineseConversionLanguages = map[string]string{
+	zhconv.S2T:  "zh-Hant",
+	zhconv.T2S:  "zh-CN",
+	zhconv.S2TW: "zh-TW",
+	zhconv.S2HK: "zh-HK",
+}
+
+// ChineseConversions 返回支持的简繁转换方式。
+func ChineseConversions() []string {
+	return zhconv.Variants()
+}
+
+// convertChineseScript 对解析结果中的书名、作者、简介、卷章标题和正文做简繁转换。
+// 它在章节检查之后执行，序号识别等规则始终面对原始文本。
+// 正文已经是 XHTML，但标签和实体只含 ASCII 字符，可以整体转换。
+func convertChineseScript(parsed *ParsedBook, variant string) error {
+	if variant == "" {
+		return nil
+	}
+	converter, err := zhconv.New(variant)
+	if err != nil {
+		return err
+	}
+
+	parsed.Name = converter.Convert(parsed.Name)
+	parsed.Author = converter.Convert(parsed.Author)
+	parsed.Intro = converter.Convert(parsed.Intro)
+	for vi := range parsed.Volumes {
+		volume := &parsed.Volumes[vi]
+		volume.Title = converter.Convert(volume.Title)
+		volume.Part = converter.Convert(volume.Part)
+		for ci := range volume.Chapters {
+			chapter := &volume.Chapters[ci]
+			chapter.Title = converter.Convert(chapter.Title)
+			content := converter.Convert(chapter.Content.String())
+			chapter.Content = strings.Builder{}
+			chapter.Content.WriteString(content)
+		}
+	}
+	return nil
+}
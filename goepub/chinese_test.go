@@ -0,0 +1,63 @@
+package goepub
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestChineseConversionConvertsParsedBook(t *testing.T) {
+	isolateAutoRuleConfigDiscovery(t)
+
+	tmpDir := t.TempDir()
+	txtPath := filepath.Join(tmpDir, "convert.txt")
+	content := strings.Join([]string{
+		"头发的故事",
+		"作者：张三",
+		"第一卷 发展",
+		"第二百章 后台",
+		"他说里面着手开发。",
+	}, "\n")
+	if err := os.WriteFile(txtPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write txt: %v", err)
+	}
+
+	book := &Book{Filename: txtPath, ChineseConversion: "S2TW"}
+	parsed, err := ParseBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("parse book: %v", err)
+	}
+	if book.Lang != "zh-TW" {
+		t.Fatalf("expected language to follow conversion, got %q", book.Lang)
+	}
+	if parsed.Name != "頭髮的故事" || parsed.Author != "張三" {
+		t.Fatalf("unexpected metadata: name=%q author=%q", parsed.Name, parsed.Author)
+	}
+	volume := parsed.Volumes[0]
+	chapter := volume.Chapters[0]
+	if volume.Title != "第一卷 發展" || chapter.Title != "第二百章 後臺" {
+		t.Fatalf("unexpected titles: %q %q", volume.Title, chapter.Title)
+	}
+	// 序号检查在转换前执行，识别出的序号不受转换影响。
+	if chapter.Number != 200 {
+		t.Fatalf("expected chapter number 200, got %d", chapter.Number)
+	}
+	if got, want := chapter.Content.String(), formatParagraph("他說裡面著手開發。"); got != want {
+		t.Fatalf("unexpected content:\n got: %s\nwant: %s", got, want)
+	}
+
+	book = &Book{Filename: txtPath, ChineseConversion: "s2hk", Lang: "zh-Hant"}
+	if err := book.FullDefault(); err != nil {
+		t.Fatalf("full default: %v", err)
+	}
+	if book.Lang != "zh-Hant" {
+		t.Fatalf("explicit language should be kept, got %q", book.Lang)
+	}
+
+	book = &Book{Filename: txtPath, ChineseConversion: "s2jp"}
+	if err := book.FullDefault(); err == nil {
+		t.Fatal("expected unsupported conversion to fail")
+	}
+}
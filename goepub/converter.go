@@ -8,3 +8,11 @@ type Converter interface {
 	// Convert 按照 Book 中的配置将输入文本转换为目标格式。
 	Convert(ctx context.Context, book *Book) error
 }
+
+// ParsedConverter 是可以直接消费解析结果的转换器。
+// 配合 Parser 使用时，调用方可以接入自定义解析器，同时复用现有的输出流程。
+type ParsedConverter interface {
+	Converter
+	// ConvertParsed 使用已经解析好的卷章结构生成目标格式，不再读取原始文本。
+	ConvertParsed(ctx context.Context, book *Book, parsed *ParsedBook) error
+}
@@ -0,0 +1,138 @@
+package goepub
+
+import (
+	"strings"
+	"unicode"
+)
+
+// englishRulePreset 是英文小说规则预设的名称。
+const englishRulePreset = "en"
+
+// latinScriptMinLetters 和 latinScriptMinShare 是判定文本为拉丁字母书写所需的最少字母数和最低占比。
+const (
+	latinScriptMinLetters = 200
+	latinScriptMinShare   = 0.9
+)
+
+// englishNumberWords 是标题中用英文单词书写的序号，例如 Twelve、Twenty-One、One Hundred and Five。
+var englishNumberWords = map[string]int{
+	"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9,
+	"ten": 10, "eleven": 11, "twelve": 12, "thirteen": 13, "fourteen": 14, "fifteen": 15, "sixteen": 16,
+	"seventeen": 17, "eighteen": 18, "nineteen": 19, "twenty": 20, "thirty": 30, "forty": 40, "fifty": 50,
+	"sixty": 60, "seventy": 70, "eighty": 80, "ninety": 90, "hundred": 100, "thousand": 1000,
+}
+
+// englishNumberWordPattern 把长的单词排在前面，避免 Seventeen 只匹配到 Seven。
+const englishNumberWordPattern = `seventeen|thirteen|fourteen|eighteen|nineteen|thousand|fifteen|sixteen|hundred|seventy|twenty|eleven|twelve|thirty|eighty|ninety|three|seven|eight|forty|fifty|sixty|zero|four|five|nine|one|two|six|ten`
+
+// englishNumeral 匹配阿拉伯数字、罗马数字和英文单词序号。
+// 单词序号的首词要求首字母大写或全大写，避免“Part one of the plan”这类正文被当作标题。
+var englishNumeral = `(?:[0-9]+|[IVXLCDM]+|(?:` + titleCaseAlternation(englishNumberWordPattern) + `)` +
+	`(?:[\s-]+(?:(?i:and)[\s-]+)?(?i:` + englishNumberWordPattern + `))*)`
+
+// englishTitleSuffix 是序号之后可选的标题部分，例如“Chapter 12: The Storm”。
+const englishTitleSuffix = `(?:[\s:.\-—–]+.{0,60})?$`
+
+var (
+	englishVolumePattern  = `^(?:Part|PART|Book|BOOK|Volume|VOLUME|Vol\.)\s*` + englishNumeral + englishTitleSuffix
+	englishChapterPattern = `^(?:(?:Chapter|CHAPTER|Ch\.)\s*` + englishNumeral + `|(?i:prologue|epilogue|interlude|afterword|foreword|preface))` + englishTitleSuffix
+	// englishChapterNumberPattern 只提取 Chapter 之后的序号，序言、尾声等没有序号。
+	englishChapterNumberPattern = `^(?:Chapter|CHAPTER|Ch\.)\s*(` + englishNumeral + `)`
+)
+
+// 英文的作者行和“书名 by 作者”写法要求作者名每个单词首字母大写，
+// 合并写法至少两个单词，避免“Stand by Me”这类书名被拆开。
+const (
+	englishAuthorName          = `\p{Lu}[\p{L}.'\-]*`
+	englishAuthorPattern       = `^(?:[Bb]y|BY|[Aa]uthor:|AUTHOR:)\s+(` + englishAuthorName + `(?:\s+` + englishAuthorName + `){0,4})$`
+	englishTitleAuthorPattern  = `^(.+?)\s+(?:by|BY)\s+(` + englishAuthorName + `(?:\s+` + englishAuthorName + `){1,4})$`
+	englishIntroPattern        = `^(?i:synopsis|summary|blurb|description)$`
+	englishAuthorNotePattern   = `^(?i:a/n|author'?s note)\s*[:：\-].*$`
+	englishSceneBreakPattern   = `^(?:#\s*){1,3}$`
+	englishChapterExtraPattern = `^(?i:bonus chapter|side story|extra)(?:[\s:.\-—–]+.{0,60})?$`
+)
+
+// titleCaseAlternation 把小写单词的备选列表展开为首字母大写和全大写两种写法。
+func titleCaseAlternation(words string) string {
+	parts := strings.Split(words, "|")
+	variants := make([]string, 0, len(parts)*2)
+	for _, word := range parts {
+		variants = append(variants, strings.ToUpper(word[:1])+word[1:], strings.ToUpper(word))
+	}
+	return strings.Join(variants, "|")
+}
+
+// detectLatinScript 判断文本是否主要由拉丁字母书写。
+// 只统计字母，数字、标点和空白不参与计算；样本中的字母太少时不做判断。
+func detectLatinScript(text string) bool {
+	latin, letters := 0, 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.Is(unicode.Latin, r) {
+			latin++
+		}
+	}
+	return latin >= latinScriptMinLetters && float64(latin) >= float64(letters)*latinScriptMinShare
+}
+
+// parseLatinNumeral 解析罗马数字和英文单词序号。
+func parseLatinNumeral(value string) (int, bool) {
+	if number, ok := parseRomanNumeral(value); ok {
+		return number, true
+	}
+	return parseEnglishNumber(value)
+}
+
+var romanDigits = map[rune]int{'I': 1, 'V': 5, 'X': 10, 'L': 50, 'C': 100, 'D': 500, 'M': 1000}
+
+func parseRomanNumeral(value string) (int, bool) {
+	if value == "" {
+		return 0, false
+	}
+	total, previous := 0, 0
+	runes := []rune(value)
+	for i := len(runes) - 1; i >= 0; i-- {
+		digit, ok := romanDigits[runes[i]]
+		if !ok {
+			return 0, false
+		}
+		if digit < previous {
+			total -= digit
+		} else {
+			total += digit
+			previous = digit
+		}
+	}
+	return total, true
+}
+
+// parseEnglishNumber 解析“Twenty-One”“One Hundred and Five”这类英文序号。
+func parseEnglishNumber(value string) (int, bool) {
+	words := strings.FieldsFunc(strings.ToLower(value), func(r rune) bool {
+		return r == '-' || unicode.IsSpace(r)
+	})
+	total, current, found := 0, 0, false
+	for _, word := range words {
+		if word == "and" {
+			continue
+		}
+		number, ok := englishNumberWords[word]
+		if !ok {
+			return 0, false
+		}
+		found = true
+		switch number {
+		case 100:
+			current = max(current, 1) * 100
+		case 1000:
+			total += max(current, 1) * 1000
+			current = 0
+		default:
+			current += number
+		}
+	}
+	return total + current, found
+}
@@ -0,0 +1,141 @@
+package goepub
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseLatinNumeral(t *testing.T) {
+	tests := map[string]int{
+		"XII":                  12,
+		"IV":                   4,
+		"MCMXC":                1990,
+		"Twelve":               12,
+		"Twenty-One":           21,
+		"ONE HUNDRED AND FIVE": 105,
+		"two thousand twenty":  2020,
+	}
+	for value, want := range tests {
+		if got, ok := parseLatinNumeral(value); !ok || got != want {
+			t.Fatalf("parseLatinNumeral(%q) = %d, %v, want %d", value, got, ok, want)
+		}
+	}
+	if _, ok := parseLatinNumeral("Storm"); ok {
+		t.Fatal("expected plain word to be rejected")
+	}
+}
+
+func TestEnglishPresetPatterns(t *testing.T) {
+	rules, err := compileRuleConfig(extendRuleConfig(defaultRuleConfig(), builtinRulePresets[englishRulePreset].Config))
+	if err != nil {
+		t.Fatalf("compile english rules: %v", err)
+	}
+	for _, line := range []string{"Chapter 12", "Chapter Twelve", "CHAPTER XII: The Storm", "Chapter Twenty-One - Home", "Prologue", "EPILOGUE"} {
+		if !rules.ChapterRegex.MatchString(line) {
+			t.Fatalf("expected chapter match: %q", line)
+		}
+	}
+	for _, line := range []string{"Part One", "Book II", "VOLUME 3: Winter"} {
+		if !rules.VolumeRegex.MatchString(line) {
+			t.Fatalf("expected volume match: %q", line)
+		}
+	}
+	for _, line := range []string{"Part one of the plan was simple.", "Chapters are hard to write, she said, and longer ones are harder still to finish.", "Booking the room took all day"} {
+		if rules.ChapterRegex.MatchString(line) || rules.VolumeRegex.MatchString(line) {
+			t.Fatalf("body line should not be a heading: %q", line)
+		}
+	}
+	if author, ok := rules.ParseAuthor("by Jane Doe"); !ok || author != "Jane Doe" {
+		t.Fatalf("unexpected author: %q %v", author, ok)
+	}
+	if _, _, ok := rules.ParseInlineTitleAndAuthor("Stand by Me"); ok {
+		t.Fatal("single word after by should not be split into title and author")
+	}
+	for title, want := range map[string]int{"Chapter Twenty-One - Home": 21, "Chapter Seventeen": 17, "Chapter XIV": 14} {
+		if number, ok := rules.ChapterNumber(title); !ok || number != want {
+			t.Fatalf("unexpected chapter number for %q: %d %v", title, number, ok)
+		}
+	}
+}
+
+func TestParseBookSelectsEnglishRules(t *testing.T) {
+	isolateAutoRuleConfigDiscovery(t)
+
+	body := "It was a bright cold day in April, and the clocks were striking thirteen. " +
+		"Winston Smith slipped quickly through the glass doors of Victory Mansions."
+	content := strings.Join([]string{
+		"The Long Road",
+		"by Jane Doe",
+		"",
+		"Part One",
+		"Prologue",
+		body,
+		"Chapter One",
+		body,
+		"Part one of the plan was simple.",
+		"CHAPTER II: The Storm",
+		body,
+		"Book II",
+		"Chapter 3 - Home",
+		body,
+		"Epilogue",
+		body,
+	}, "\n")
+	txtPath := filepath.Join(t.TempDir(), "road.txt")
+	if err := os.WriteFile(txtPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write txt: %v", err)
+	}
+
+	book := &Book{Filename: txtPath}
+	parsed, err := ParseBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("parse book: %v", err)
+	}
+	if book.Lang != "en" || !slices.Contains(parsed.Report.AppliedPresets, englishRulePreset) {
+		t.Fatalf("expected english rules from detected script: lang=%s presets=%v", book.Lang, parsed.Report.AppliedPresets)
+	}
+	if parsed.Name != "The Long Road" || parsed.Author != "Jane Doe" {
+		t.Fatalf("unexpected metadata: %q %q", parsed.Name, parsed.Author)
+	}
+	if len(parsed.Volumes) != 2 || parsed.Volumes[0].Title != "Part One" || parsed.Volumes[1].Title != "Book II" {
+		t.Fatalf("unexpected volumes: %+v", parsed.Volumes)
+	}
+	var titles []string
+	var numbers []int
+	for _, volume := range parsed.Volumes {
+		for _, chapter := range volume.Chapters {
+			titles = append(titles, chapter.Title)
+			numbers = append(numbers, chapter.Number)
+		}
+	}
+	if want := []string{"Prologue", "Chapter One", "CHAPTER II: The Storm", "Chapter 3 - Home", "Epilogue"}; !slices.Equal(titles, want) {
+		t.Fatalf("unexpected chapters: %v", titles)
+	}
+	if want := []int{0, 1, 2, 3, 0}; !slices.Equal(numbers, want) {
+		t.Fatalf("unexpected chapter numbers: %v", numbers)
+	}
+
+	// 显式指定中文时不再按文字种类切换规则。
+	book = &Book{Filename: txtPath, Lang: "zh-CN"}
+	if _, err := ParseBook(context.Background(), book); err == nil {
+		t.Fatal("expected chinese rules to find no chapters")
+	}
+	if book.ChapterRegex.String() != ChapterPattern {
+		t.Fatalf("explicit language should keep chinese rules: %s", book.ChapterRegex)
+	}
+
+	// 显式指定 en-US 时直接使用英文规则，调用方设置的章节正则仍然优先。
+	book = &Book{Filename: txtPath, Lang: "en-US", ChapterRegex: regexp.MustCompile(`^Chapter One$`)}
+	parsed, err = ParseBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("parse book: %v", err)
+	}
+	if book.VolumeRegex.String() != englishVolumePattern || countParsedChapters(parsed) != 1 {
+		t.Fatalf("unexpected rules for en-US: volume=%s chapters=%d", book.VolumeRegex, countParsedChapters(parsed))
+	}
+}
@@ -1,13 +1,11 @@
 package goepub
 
 import (
-	"bufio"
 	"context"
 	"errors"
 	"fmt"
 	"html"
 	"io"
-	"log"
 	"net/http"
 	"net/url"
 	"os"
@@ -34,14 +32,39 @@ func (c *epubConverter) Convert(ctx context.Context, book *Book) error {
 	if err := book.FullDefault(); err != nil {
 		return err
 	}
-	rules := book.parseRules
 	if len(book.Volumes) == 0 {
 		// 如果调用方没有预先提供卷章结构，就从 TXT 原文中实时解析。
-		if err := c.parse(ctx, book, rules); err != nil {
+		parsed, err := parseBookSource(ctx, book)
+		if err != nil {
 			return err
 		}
+		parsed.applyTo(book)
 	}
+	return c.write(ctx, book)
+}
 
+// ConvertParsed 使用已经解析好的卷章结构生成 EPUB，不再读取或解析 TXT。
+// Book 仍用于提供书名、封面、输出路径等元信息，parsed 只补齐其中的空缺。
+func (c *epubConverter) ConvertParsed(ctx context.Context, book *Book, parsed *ParsedBook) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if book == nil {
+		return errors.New("book 不能为空")
+	}
+	if parsed == nil || len(parsed.Volumes) == 0 {
+		return errors.New("解析结果中没有任何章节")
+	}
+	book.Volumes = parsed.Volumes
+	if err := book.FullDefault(); err != nil {
+		return err
+	}
+	parsed.applyTo(book)
+	return c.write(ctx, book)
+}
+
+// write 将已经完成解析的 Book 写成 EPUB 文件。
+func (c *epubConverter) write(ctx context.Context, book *Book) error {
 	e, err := epublib.NewEpub(book.Name)
 	if err != nil {
 		return fmt.Errorf("创建 EPUB 失败: %w", err)
@@ -58,14 +81,18 @@ func (c *epubConverter) Convert(ctx context.Context, book *Book) error {
 		defer fontCleanup()
 	}
 
-	style, styleCleanup, err := addEmbeddedStyles(e)
+	style, styleCleanup, err := addEmbeddedStyles(e, book.VerticalWriting)
 	if err != nil {
 		return err
 	}
 	if styleCleanup != nil {
 		defer styleCleanup()
 	}
-	if err := c.writeChapters(ctx, book, e, style); err != nil {
+	toc, err := c.writeChapters(ctx, book, e, style, bookChapterLayout(book))
+	if err != nil {
+		return err
+	}
+	if err := c.addImages(book, e); err != nil {
 		return err
 	}
 	coverCleanup, err := c.setCover(ctx, book, e)
@@ -83,219 +110,23 @@ func (c *epubConverter) Convert(ctx context.Context, book *Book) error {
 	if err := os.MkdirAll(filepath.Dir(output), 0o755); err != nil {
 		return fmt.Errorf("创建输出目录失败: %w", err)
 	}
-	return e.Write(output)
-}
-
-// WriteTo 将已经解析完成的卷章结构写入现有的 EPUB 对象。
-// 这个方法主要为后续扩展或测试场景保留。
-func (c *epubConverter) WriteTo(book *Book, e *epublib.Epub) error {
-	return c.writeChapters(context.Background(), book, e, "")
-}
-
-// parse 负责把 TXT 文本解析成 Book.Volumes 结构。
-// 它只关心“识别标题、作者、卷、章和正文”，不直接处理 EPUB 输出。
-func (c *epubConverter) parse(ctx context.Context, book *Book, rules *ParseRules) error {
-	raw, err := os.ReadFile(book.Filename)
-	if err != nil {
-		return fmt.Errorf("读取文件失败: %s - %w", book.Filename, err)
-	}
-
-	text, detectedEncoding, err := decodeTextContent(raw, book.Encoding)
-	if err != nil {
+	if err := e.Write(output); err != nil {
 		return err
 	}
-	log.Printf("检测到文本编码: %s", detectedEncoding)
-
-	if len(book.RulePresets) == 0 && book.RulePresetMode != presetModeOff {
-		detections := DetectRulePresets(text)
-		if len(detections) > 0 {
-			names := make([]string, 0, len(detections))
-			reasonParts := make([]string, 0, len(detections))
-			for _, detected := range detections {
-				names = append(names, detected.Name)
-				reasonParts = append(reasonParts, fmt.Sprintf("%s(score=%d, hit=%s)", detected.Name, detected.Score, strings.Join(detected.Reasons, " | ")))
-			}
-
-			switch book.RulePresetMode {
-			case presetModeApply:
-				book.detectedRulePresets = names
-				rules, err = buildParseRules(book)
-				if err != nil {
-					return err
-				}
-				book.parseRules = rules
-				book.VolumeRegex = rules.VolumeRegex
-				book.ChapterRegex = rules.ChapterRegex
-				book.ExtraRegex = rules.ExtraRegex
-				book.IntroRegex = rules.IntroRegex
-				log.Printf("自动应用规则预设: %s", strings.Join(reasonParts, "; "))
-			default:
-				log.Printf("检测到推荐规则预设: %s", strings.Join(reasonParts, "; "))
-				log.Printf("如需自动应用，可使用 -rule-preset-mode=apply；如需手动指定，可使用 -rule-preset=%s", strings.Join(names, ","))
-			}
-		}
-	}
-
-	scanner := bufio.NewScanner(strings.NewReader(text))
-	// 默认 Scanner 单行长度限制较小，小说正文里常见的超长段落会直接触发错误，
-	// 这里主动放大缓冲区以提升兼容性。
-	scanner.Buffer(make([]byte, 64*1024), maxScannerTokenSize)
-
-	var (
-		currentVol      *Volume
-		currentCh       *Chapter
-		introLines      []string
-		collectingIntro bool
-	)
-
-	flushChapter := func() {
-		if currentVol == nil || currentCh == nil {
-			return
-		}
-		currentVol.Chapters = append(currentVol.Chapters, *currentCh)
-		currentCh = nil
-	}
-	flushVolume := func() {
-		if currentVol == nil {
-			return
-		}
-		if len(currentVol.Chapters) == 0 && strings.TrimSpace(currentVol.Title) == "" {
-			return
-		}
-		book.Volumes = append(book.Volumes, *currentVol)
-		currentVol = nil
-	}
-
-	for scanner.Scan() {
-		if err := ctx.Err(); err != nil {
-			return err
-		}
-
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
-			if collectingIntro {
-				continue
-			}
-			continue
-		}
-
-		if rules.ShouldIgnoreLine(line) {
-			continue
-		}
-
-		if book.Name == "" || book.Author == "" {
-			title, author, ok := rules.ParseInlineTitleAndAuthor(line)
-			if ok {
-				if book.Name == "" {
-					book.Name = title
-					log.Printf("小说标题: %s", book.Name)
-				}
-				if book.Author == "" {
-					book.Author = author
-					log.Printf("小说作者: %s", book.Author)
-				}
-				continue
-			}
-		}
-
-		if book.Name == "" {
-			if title, ok := rules.ParseTitle(line); ok {
-				book.Name = title
-				log.Printf("小说标题: %s", book.Name)
-				continue
-			}
-		}
-
-		if book.Author == "" {
-			if author, ok := rules.ParseAuthor(line); ok {
-				book.Author = author
-				log.Printf("小说作者: %s", book.Author)
-				continue
-			}
-		}
-
-		if book.Intro == "" {
-			if introText, ok := rules.ParsePrefixedIntro(line); ok {
-				collectingIntro = true
-				if introText != "" {
-					introLines = append(introLines, introText)
-				}
-				continue
-			}
-			if collectingIntro {
-				if rules.IsStructuralLine(line) {
-					book.Intro = strings.TrimSpace(strings.Join(introLines, "\n"))
-					collectingIntro = false
-				} else {
-					introLines = append(introLines, line)
-					continue
-				}
-			}
-		}
-
-		switch {
-		case rules.VolumeRegex != nil && rules.VolumeRegex.MatchString(line):
-			// 遇到新卷时，先收束当前章节和当前卷，再开启下一卷。
-			flushChapter()
-			flushVolume()
-			currentVol = &Volume{Title: line}
-			log.Printf("解析卷: %s", line)
-			continue
-		case rules.ChapterRegex != nil && rules.ChapterRegex.MatchString(line):
-			if currentVol == nil {
-				// 无卷小说也允许直接挂章节，因此这里自动创建匿名卷。
-				currentVol = &Volume{}
-			}
-			flushChapter()
-			currentCh = &Chapter{Title: line}
-			log.Printf("解析章节: %s", line)
-			continue
-		case rules.ExtraRegex != nil && rules.ExtraRegex.MatchString(line):
-			if currentVol == nil {
-				currentVol = &Volume{}
-			}
-			flushChapter()
-			currentCh = &Chapter{Title: line}
-			log.Printf("解析番外: %s", line)
-			continue
-		case rules.IsSpecialChapterTitle(line):
-			if currentVol == nil {
-				currentVol = &Volume{}
-			}
-			flushChapter()
-			currentCh = &Chapter{Title: line}
-			log.Printf("解析特殊章节: %s", line)
-			continue
-		}
-
-		if currentCh != nil {
-			// 普通正文仅归属到当前章节，且在写入前做 HTML 转义。
-			currentCh.Content.WriteString(formatParagraph(line))
-		}
-	}
-
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("扫描 TXT 文件失败: %w", err)
-	}
-
-	flushChapter()
-	flushVolume()
-
-	if book.Intro == "" && len(introLines) > 0 {
-		book.Intro = strings.TrimSpace(strings.Join(introLines, "\n"))
-	}
-	if book.Name == "" {
-		book.Name = strings.TrimSuffix(filepath.Base(book.Filename), filepath.Ext(book.Filename))
-	}
-	if book.Intro == "" {
-		book.Intro = deriveIntro(book)
-	}
-	if len(book.Volumes) == 0 {
-		return errors.New("未解析到任何章节，请检查章节正则是否正确")
+	if toc.rewrite {
+		// go-epub 会把续接文件也写进目录，目录项也不能指向锚点，这里按实际的卷章结构重写目录。
+		return rewriteTOC(output, toc)
 	}
 	return nil
 }
 
+// WriteTo 将已经解析完成的卷章结构写入现有的 EPUB 对象。
+// 这个方法主要为后续扩展或测试场景保留；目录由调用方写出，因此这里不拆分也不合并章节。
+func (c *epubConverter) WriteTo(book *Book, e *epublib.Epub) error {
+	_, err := c.writeChapters(context.Background(), book, e, "", chapterLayout{})
+	return err
+}
+
 // applyMetadata 将 Book 中的元信息同步到 EPUB 对象。
 func (c *epubConverter) applyMetadata(book *Book, e *epublib.Epub) error {
 	e.SetTitle(book.Name)
@@ -306,47 +137,151 @@ func (c *epubConverter) applyMetadata(book *Book, e *epublib.Epub) error {
 	if book.Intro != "" {
 		e.SetDescription(book.Intro)
 	}
+	if book.VerticalWriting {
+		// 竖排从右向左翻页。
+		e.SetPpd("rtl")
+	}
 	return nil
 }
 
-// writeChapters 将卷章树写入 EPUB 文档结构。
+// writeChapters 将卷章树写入 EPUB 文档结构，并返回写入的目录结构。
 // 卷会生成父级 section，章节会作为 subsection 挂载在卷下。
-func (c *epubConverter) writeChapters(ctx context.Context, book *Book, e *epublib.Epub, style string) error {
+// 卷属于某一部时，部会生成带标题页的顶层 section，卷再挂到部下，形成三级目录。
+// 正文超过上限的章节会拆出续接文件挂在章节下，它们不出现在返回的目录中；
+// 合并模式下同一卷相邻的章节写入同一个文件，目录项指向各章标题的锚点；
+// 无标题卷的章节可以在目录中按数量分组。
+func (c *epubConverter) writeChapters(ctx context.Context, book *Book, e *epublib.Epub, style string, layout chapterLayout) (*epubTOC, error) {
 	if len(book.Volumes) == 0 {
-		return errors.New("卷不能为空")
+		return nil, errors.New("卷不能为空")
 	}
 
+	toc := &epubTOC{}
+	partFilename := ""
+	var partEntry *tocEntry
 	for i, vol := range book.Volumes {
 		if err := ctx.Err(); err != nil {
-			return err
+			return nil, err
+		}
+
+		// 相邻且来自同一“部”标题行的卷共用一个部 section。
+		if vol.Part == "" {
+			partFilename, partEntry = "", nil
+		} else if i == 0 || vol.Part != book.Volumes[i-1].Part || vol.PartLine != book.Volumes[i-1].PartLine {
+			internalFilename := fmt.Sprintf("part%d.xhtml", i)
+			body := fmt.Sprintf(`<h1 class="part">%s</h1>`, html.EscapeString(vol.Part))
+			var err error
+			partFilename, err = e.AddSection(body, vol.Part, internalFilename, style)
+			if err != nil {
+				return nil, fmt.Errorf("添加部失败 %s: %w", vol.Part, err)
+			}
+			partEntry = toc.add(nil, vol.Part, partFilename)
 		}
 
-		parentFilename := ""
+		parentFilename, parentEntry := partFilename, partEntry
 		if vol.Title != "" {
 			internalFilename := fmt.Sprintf("volume%d.xhtml", i)
+			body := fmt.Sprintf("<h1>%s</h1>", html.EscapeString(vol.Title))
 			var err error
-			parentFilename, err = e.AddSection(fmt.Sprintf("<h1>%s</h1>", html.EscapeString(vol.Title)), vol.Title, internalFilename, style)
+			if partFilename == "" {
+				parentFilename, err = e.AddSection(body, vol.Title, internalFilename, style)
+			} else {
+				parentFilename, err = e.AddSubSection(partFilename, body, vol.Title, internalFilename, style)
+			}
 			if err != nil {
-				return fmt.Errorf("添加卷失败 %s: %w", vol.Title, err)
+				return nil, fmt.Errorf("添加卷失败 %s: %w", vol.Title, err)
+			}
+			parentEntry = toc.add(partEntry, vol.Title, parentFilename)
+		}
+
+		// 没有卷标题的长书在目录中按章节数分组，分组只是目录节点，不生成标题页。
+		var groups []*tocEntry
+		if vol.Title == "" && layout.groupSize > 0 && len(vol.Chapters) > layout.groupSize {
+			for start := 0; start < len(vol.Chapters); start += layout.groupSize {
+				end := min(start+layout.groupSize, len(vol.Chapters))
+				groups = append(groups, toc.addGroup(parentEntry, tocGroupTitle(layout.lang, vol.Chapters[start:end], start)))
 			}
 		}
 
-		for j, ch := range vol.Chapters {
+		for j := 0; j < len(vol.Chapters); {
 			if err := ctx.Err(); err != nil {
-				return err
+				return nil, err
 			}
 
+			count := 1
+			if layout.packSize > 0 {
+				count = packedChapterCount(vol.Chapters[j:], layout.packSize)
+			}
+			group := vol.Chapters[j : j+count]
 			chapterFilename := fmt.Sprintf("volume%d_chapter%d.xhtml", i, j)
-			body := fmt.Sprintf("<h2>%s</h2>%s", html.EscapeString(ch.Title), ch.Content.String())
+			var body strings.Builder
+			for k := range group {
+				ch := &group[k]
+				switch {
+				case layout.packSize == 0:
+					body.WriteString("<h2>")
+				case k == 0:
+					fmt.Fprintf(&body, `<h2 id="%s">`, chapterAnchor(i, j+k))
+				default:
+					// 合并在同一文件中的章节仍从新的一页开始。
+					fmt.Fprintf(&body, `<h2 id="%s" style="page-break-before: always;">`, chapterAnchor(i, j+k))
+				}
+				body.WriteString(html.EscapeString(ch.Title))
+				body.WriteString("</h2>")
+				body.WriteString(ch.Content.String())
+			}
+			title := group[0].Title
+			parts := []string{body.String()}
+			if count == 1 {
+				// 合并的章节总长不超过上限，只有单独成文件的章节才需要拆分，锚点因此总在第一个文件中。
+				parts = splitChapterBody(parts[0], layout.splitSize)
+			}
+			if len(parts) > 1 {
+				filenames := []string{chapterFilename}
+				for k := 1; k < len(parts); k++ {
+					filenames = append(filenames, continuationFilename(i, j, k))
+				}
+				parts = linkSplitParts(parts, filenames)
+			}
+			var err error
 			if parentFilename == "" {
-				if _, err := e.AddSection(body, ch.Title, chapterFilename, style); err != nil {
-					return fmt.Errorf("添加章节失败 卷:%s 章:%s: %w", vol.Title, ch.Title, err)
+				chapterFilename, err = e.AddSection(parts[0], title, chapterFilename, style)
+			} else {
+				chapterFilename, err = e.AddSubSection(parentFilename, parts[0], title, chapterFilename, style)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("添加章节失败 卷:%s 章:%s: %w", vol.Title, title, err)
+			}
+			for k := range group {
+				entryParent := parentEntry
+				if groups != nil {
+					entryParent = groups[(j+k)/layout.groupSize]
+				}
+				entry := toc.add(entryParent, group[k].Title, chapterFilename)
+				if layout.packSize > 0 {
+					// go-epub 的目录只能指向文件，指向章节锚点需要重写目录。
+					entry.href += "#" + chapterAnchor(i, j+k)
+					toc.rewrite = true
 				}
-				continue
 			}
-			if _, err := e.AddSubSection(parentFilename, body, ch.Title, chapterFilename, style); err != nil {
-				return fmt.Errorf("添加章节失败 卷:%s 章:%s: %w", vol.Title, ch.Title, err)
+
+			// 续接文件挂在章节下，go-epub 按深度优先排列书脊，它们会紧跟在章节之后。
+			for k, part := range parts[1:] {
+				if _, err := e.AddSubSection(chapterFilename, part, title, continuationFilename(i, j, k+1), style); err != nil {
+					return nil, fmt.Errorf("添加章节续接文件失败 卷:%s 章:%s: %w", vol.Title, title, err)
+				}
+				toc.rewrite = true
 			}
+			j += count
+		}
+	}
+	return toc, nil
+}
+
+// addImages 把正文引用的本地图片写入 EPUB，章节正文已经按 Image.Filename 引用了它们。
+func (c *epubConverter) addImages(book *Book, e *epublib.Epub) error {
+	for _, image := range book.Images {
+		if _, err := e.AddImage(image.Source, image.Filename); err != nil {
+			return fmt.Errorf("添加图片失败 %s: %w", image.Source, err)
 		}
 	}
 	return nil
@@ -381,7 +316,7 @@ func (c *epubConverter) setCover(ctx context.Context, book *Book, e *epublib.Epu
 }
 
 // NewEPUBConverter 创建一个新的 EPUB 转换器实现。
-func NewEPUBConverter() Converter {
+func NewEPUBConverter() ParsedConverter {
 	return &epubConverter{}
 }
 
@@ -4,9 +4,12 @@ import (
 	"archive/zip"
 	"bytes"
 	"context"
+	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
 )
@@ -137,3 +140,323 @@ func TestEPUBConverterConvertDoesNotLogMissingParentSection(t *testing.T) {
 		t.Fatalf("unexpected toc warning log: %s", got)
 	}
 }
+
+// TestEPUBConverterConvertNestsPartsInTOC 验证启用部正则后目录按“部 > 卷 > 章”三级嵌套。
+func TestEPUBConverterConvertNestsPartsInTOC(t *testing.T) {
+	isolateAutoRuleConfigDiscovery(t)
+
+	tmpDir := t.TempDir()
+	txtPath := filepath.Join(tmpDir, "parts.txt")
+	outputPath := filepath.Join(tmpDir, "parts.epub")
+
+	content := strings.Join([]string{
+		"三级目录测试",
+		"作者：孙八",
+		"第一部 地球往事",
+		"第一卷 疯狂年代",
+		"第一章 开始",
+		"第一段内容",
+	}, "\n")
+	if err := os.WriteFile(txtPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write txt: %v", err)
+	}
+
+	book := &Book{
+		Filename:    txtPath,
+		Output:      outputPath,
+		RulePresets: []string{"parts"},
+	}
+	if err := NewEPUBConverter().Convert(context.Background(), book); err != nil {
+		t.Fatalf("convert: %v", err)
+	}
+
+	reader, err := zip.OpenReader(outputPath)
+	if err != nil {
+		t.Fatalf("open epub: %v", err)
+	}
+	defer reader.Close()
+
+	var nav, part string
+	for _, file := range reader.File {
+		if !strings.HasSuffix(file.Name, "nav.xhtml") && !strings.HasSuffix(file.Name, "part0.xhtml") {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", file.Name, err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("read %s: %v", file.Name, err)
+		}
+		if strings.HasSuffix(file.Name, "nav.xhtml") {
+			nav = string(data)
+		} else {
+			part = string(data)
+		}
+	}
+
+	if !strings.Contains(part, `<h1 class="part">第一部 地球往事</h1>`) {
+		t.Fatalf("missing part title page: %s", part)
+	}
+	partIndex := strings.Index(nav, "第一部 地球往事")
+	volumeIndex := strings.Index(nav, "第一卷 疯狂年代")
+	chapterIndex := strings.Index(nav, "第一章 开始")
+	if partIndex < 0 || volumeIndex < partIndex || chapterIndex < volumeIndex {
+		t.Fatalf("unexpected toc order: %s", nav)
+	}
+	// 三级目录中部、卷各自打开一层嵌套列表。
+	if depth := strings.Count(nav[partIndex:chapterIndex], "<ol"); depth != 2 {
+		t.Fatalf("expected chapter nested two levels below part, got %d: %s", depth, nav)
+	}
+}
+
+func TestEPUBConverterConvertWritesVerticalLayout(t *testing.T) {
+	isolateAutoRuleConfigDiscovery(t)
+
+	tmpDir := t.TempDir()
+	txtPath := filepath.Join(tmpDir, "vertical.txt")
+	outputPath := filepath.Join(tmpDir, "vertical.epub")
+
+	content := strings.Join([]string{
+		"羅生門",
+		"第一話　暮方",
+		"一人の｜下人《げにん》が、羅生門の下で雨やみを待っていた。",
+	}, "\n")
+	if err := os.WriteFile(txtPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write txt: %v", err)
+	}
+
+	book := &Book{
+		Filename:        txtPath,
+		Output:          outputPath,
+		Lang:            "ja",
+		VerticalWriting: true,
+	}
+	if err := NewEPUBConverter().Convert(context.Background(), book); err != nil {
+		t.Fatalf("convert: %v", err)
+	}
+
+	reader, err := zip.OpenReader(outputPath)
+	if err != nil {
+		t.Fatalf("open epub: %v", err)
+	}
+	defer reader.Close()
+
+	var opf, chapter string
+	hasVerticalStyle := false
+	for _, file := range reader.File {
+		switch {
+		case strings.HasSuffix(file.Name, "vertical.css"):
+			hasVerticalStyle = true
+			continue
+		case !strings.HasSuffix(file.Name, ".opf") && !strings.HasSuffix(file.Name, "volume0_chapter0.xhtml"):
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", file.Name, err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("read %s: %v", file.Name, err)
+		}
+		if strings.HasSuffix(file.Name, ".opf") {
+			opf = string(data)
+		} else {
+			chapter = string(data)
+		}
+	}
+
+	if !hasVerticalStyle || !strings.Contains(opf, `page-progression-direction="rtl"`) {
+		t.Fatalf("expected vertical style and rtl spine, style=%v opf=%s", hasVerticalStyle, opf)
+	}
+	if !strings.Contains(chapter, "<ruby>下人<rt>げにん</rt></ruby>") {
+		t.Fatalf("expected ruby markup from the ja preset: %s", chapter)
+	}
+}
+
+func TestEPUBConverterConvertSplitsLargeChapters(t *testing.T) {
+	isolateAutoRuleConfigDiscovery(t)
+
+	tmpDir := t.TempDir()
+	txtPath := filepath.Join(tmpDir, "large.txt")
+	outputPath := filepath.Join(tmpDir, "large.epub")
+
+	lines := []string{"拆分测试", "作者：周九", "第一卷 长章", "第一章 很长"}
+	for i := 0; i < 60; i++ {
+		lines = append(lines, strings.Repeat("长", 90+i%20)+"。")
+	}
+	lines = append(lines, "第二章 很短", "短正文")
+	if err := os.WriteFile(txtPath, []byte(strings.Join(lines, "\n")), 0o644); err != nil {
+		t.Fatalf("write txt: %v", err)
+	}
+
+	book := &Book{Filename: txtPath, Output: outputPath, ChapterSplitSize: 8 * 1024}
+	if err := NewEPUBConverter().Convert(context.Background(), book); err != nil {
+		t.Fatalf("convert: %v", err)
+	}
+
+	files := readEPUBFiles(t, outputPath)
+	first, ok := files["EPUB/xhtml/volume0_chapter0.xhtml"]
+	if !ok || !strings.Contains(first, "<h2>第一章 很长</h2>") {
+		t.Fatalf("missing chapter start: %v", ok)
+	}
+	continuations := 0
+	for name, data := range files {
+		if !strings.HasPrefix(name, "EPUB/xhtml/volume0_chapter0_") {
+			continue
+		}
+		continuations++
+		if strings.Contains(data, "<h2>") || len(data) > 9*1024 {
+			t.Fatalf("unexpected continuation %s (%d bytes)", name, len(data))
+		}
+	}
+	if continuations < 2 {
+		t.Fatalf("expected the chapter to be split, got %d continuation files", continuations)
+	}
+
+	nav, ncx := files["EPUB/nav.xhtml"], files["EPUB/toc.ncx"]
+	for _, toc := range []string{nav, ncx} {
+		if strings.Contains(toc, "volume0_chapter0_") || !strings.Contains(toc, "volume0_chapter1.xhtml") {
+			t.Fatalf("toc should list chapter starts only: %s", toc)
+		}
+	}
+	if strings.Count(nav, "第一章 很长") != 1 || !strings.Contains(nav, `<a href="xhtml/volume0.xhtml">第一卷 长章</a>`) {
+		t.Fatalf("unexpected nav: %s", nav)
+	}
+	// 书脊中续接文件紧跟在章节之后。
+	opf := files["EPUB/package.opf"]
+	start := strings.Index(opf, `idref="volume0_chapter0.xhtml"`)
+	next := strings.Index(opf, `idref="volume0_chapter1.xhtml"`)
+	continuation := strings.Index(opf, `idref="volume0_chapter0_1.xhtml"`)
+	if start < 0 || continuation < start || next < continuation {
+		t.Fatalf("unexpected spine order: %s", opf)
+	}
+}
+
+// readEPUBFiles 读取 EPUB 中的全部文件，按压缩包内的路径返回内容。
+func readEPUBFiles(t *testing.T, path string) map[string]string {
+	t.Helper()
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("open epub: %v", err)
+	}
+	defer reader.Close()
+
+	files := make(map[string]string, len(reader.File))
+	for _, file := range reader.File {
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", file.Name, err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("read %s: %v", file.Name, err)
+		}
+		files[file.Name] = string(data)
+	}
+	if len(reader.File) == 0 || reader.File[0].Name != "mimetype" || reader.File[0].Method != zip.Store {
+		t.Fatalf("mimetype must be the first stored file")
+	}
+	return files
+}
+
+func TestEPUBConverterConvertPacksSmallChapters(t *testing.T) {
+	isolateAutoRuleConfigDiscovery(t)
+
+	tmpDir := t.TempDir()
+	txtPath := filepath.Join(tmpDir, "serial.txt")
+	outputPath := filepath.Join(tmpDir, "serial.epub")
+
+	lines := []string{"合并测试", "作者：吴十"}
+	for volume := 1; volume <= 2; volume++ {
+		lines = append(lines, fmt.Sprintf("第%d卷", volume))
+		for chapter := 1; chapter <= 30; chapter++ {
+			lines = append(lines, fmt.Sprintf("第%d章", (volume-1)*30+chapter), strings.Repeat("短", 80)+"。")
+		}
+	}
+	if err := os.WriteFile(txtPath, []byte(strings.Join(lines, "\n")), 0o644); err != nil {
+		t.Fatalf("write txt: %v", err)
+	}
+
+	book := &Book{Filename: txtPath, Output: outputPath, PackChapters: true, ChapterSplitSize: 4 * 1024}
+	if err := NewEPUBConverter().Convert(context.Background(), book); err != nil {
+		t.Fatalf("convert: %v", err)
+	}
+
+	files := readEPUBFiles(t, outputPath)
+	documents := 0
+	for name, data := range files {
+		if !strings.HasPrefix(name, "EPUB/xhtml/volume") || !strings.Contains(name, "_chapter") {
+			continue
+		}
+		documents++
+		if len(data) > 5*1024 {
+			t.Fatalf("packed document %s exceeds the limit: %d bytes", name, len(data))
+		}
+	}
+	if documents < 4 || documents > 30 {
+		t.Fatalf("expected chapters to be packed into a few documents, got %d", documents)
+	}
+
+	nav, ncx := files["EPUB/nav.xhtml"], files["EPUB/toc.ncx"]
+	if strings.Count(nav, "<a href=") != 62 || strings.Count(ncx, "<navPoint ") != 62 {
+		t.Fatalf("expected every volume and chapter in the toc: %s", nav)
+	}
+	// 第二卷的首章不会和第一卷的章节合并。
+	if !strings.Contains(nav, `<a href="xhtml/volume1_chapter0.xhtml#volume1_chapter0">第31章</a>`) {
+		t.Fatalf("expected the first chapter of volume two to start a document: %s", nav)
+	}
+	for _, href := range regexp.MustCompile(`href="xhtml/([^"#]+)#([^"]+)"`).FindAllStringSubmatch(nav, -1) {
+		if !strings.Contains(files["EPUB/xhtml/"+href[1]], `id="`+href[2]+`"`) {
+			t.Fatalf("anchor %s not found in %s", href[2], href[1])
+		}
+	}
+}
+
+func TestEPUBConverterConvertGroupsVolumelessTOC(t *testing.T) {
+	isolateAutoRuleConfigDiscovery(t)
+
+	tmpDir := t.TempDir()
+	txtPath := filepath.Join(tmpDir, "long.txt")
+	outputPath := filepath.Join(tmpDir, "long.epub")
+
+	lines := []string{"分组测试", "作者：郑十一"}
+	for chapter := 1; chapter <= 250; chapter++ {
+		lines = append(lines, fmt.Sprintf("第%d章", chapter), "正文。")
+	}
+	if err := os.WriteFile(txtPath, []byte(strings.Join(lines, "\n")), 0o644); err != nil {
+		t.Fatalf("write txt: %v", err)
+	}
+
+	book := &Book{Filename: txtPath, Output: outputPath, TOCGroupSize: 100}
+	if err := NewEPUBConverter().Convert(context.Background(), book); err != nil {
+		t.Fatalf("convert: %v", err)
+	}
+
+	files := readEPUBFiles(t, outputPath)
+	nav, ncx := files["EPUB/nav.xhtml"], files["EPUB/toc.ncx"]
+	for _, title := range []string{"第1–100章", "第101–200章", "第201–250章"} {
+		if !strings.Contains(nav, "<span>"+title+"</span>") || !strings.Contains(ncx, "<text>"+title+"</text>") {
+			t.Fatalf("missing group %s: %s", title, nav)
+		}
+	}
+	// 分组没有标题页，NCX 中指向组内第一章。
+	group := ncx[strings.Index(ncx, "<text>第101–200章</text>"):]
+	if !strings.HasPrefix(group[strings.Index(group, "<content"):], `<content src="xhtml/volume0_chapter100.xhtml">`) {
+		t.Fatalf("expected the group to point at its first chapter: %s", group[:300])
+	}
+	chapters := 0
+	for name := range files {
+		if strings.HasPrefix(name, "EPUB/xhtml/") {
+			chapters++
+		}
+	}
+	if chapters != 250 {
+		t.Fatalf("groups should not add content documents, got %d", chapters)
+	}
+}
@@ -0,0 +1,153 @@
+package goepub
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+)
+
+// LineInfo 描述交给 LineFilter 的一行文本及其位置。
+type LineInfo struct {
+	// Number 是从 1 开始的行号。
+	Number int
+	// Text 是原始行文本，保留行首缩进。
+	Text string
+	// Volume 和 Chapter 是该行之前最近的卷标题和章节标题，尚未进入卷章时为空。
+	Volume  string
+	Chapter string
+}
+
+// LineFilter 在内置解析器对每一行做分类之前执行。
+// 返回的文本会替代原始行继续参与书名、卷章和正文的识别；keep 为 false 时丢弃该行。
+type LineFilter interface {
+	FilterLine(ctx context.Context, line LineInfo) (text string, keep bool, err error)
+}
+
+// LineFilterFunc 让普通函数可以作为 LineFilter 使用。
+type LineFilterFunc func(ctx context.Context, line LineInfo) (string, bool, error)
+
+// FilterLine 调用 f 本身。
+func (f LineFilterFunc) FilterLine(ctx context.Context, line LineInfo) (string, bool, error) {
+	return f(ctx, line)
+}
+
+// Paragraph 是尚未格式化为 XHTML 的正文段落。
+type Paragraph struct {
+	// Text 是段落纯文本，不需要转义；空行分段模式下段内各行以 \n 分隔并保留行首缩进。
+	// 青空文库注记模式下 Text 保留注音等注记，格式化时才转换为 XHTML。
+	Text string
+	// SceneBreak 表示场景分隔，此时 Text 为空。
+	SceneBreak bool
+	// Image 不为空时该段是一张插图，值为章节 XHTML 中引用图片的相对路径，此时 Text 为空。
+	Image string
+}
+
+// ChapterText 是交给 ChapterFilter 的整章内容。
+type ChapterText struct {
+	// Volume 是所在卷的标题，无卷小说为空。
+	Volume string
+	// Title 是章节标题，过滤器可以直接修改。
+	Title string
+	// Line 是章节标题所在行号。
+	Line int
+	// Paragraphs 是按顺序排列的段落，过滤器可以修改、删除或插入段落。
+	Paragraphs []Paragraph
+}
+
+// ChapterFilter 在一章解析完成、段落格式化为 XHTML 之前执行。
+type ChapterFilter interface {
+	FilterChapter(ctx context.Context, chapter *ChapterText) error
+}
+
+// ChapterFilterFunc 让普通函数可以作为 ChapterFilter 使用。
+type ChapterFilterFunc func(ctx context.Context, chapter *ChapterText) error
+
+// FilterChapter 调用 f 本身。
+func (f ChapterFilterFunc) FilterChapter(ctx context.Context, chapter *ChapterText) error {
+	return f(ctx, chapter)
+}
+
+// filterLine 依次执行行过滤器，任一过滤器丢弃该行后不再继续。
+func (s *textParseState) filterLine(raw string) (string, bool, error) {
+	if len(s.rules.LineFilters) == 0 {
+		return raw, true, nil
+	}
+	info := LineInfo{Number: s.lineNo, Text: raw}
+	if s.currentVol != nil {
+		info.Volume = s.currentVol.Title
+	}
+	if s.currentCh != nil {
+		info.Chapter = s.currentCh.Title
+	}
+	for _, filter := range s.rules.LineFilters {
+		text, keep, err := filter.FilterLine(s.ctx, info)
+		if err != nil {
+			return "", false, fmt.Errorf("行过滤器处理第 %d 行失败: %w", s.lineNo, err)
+		}
+		if !keep {
+			return "", false, nil
+		}
+		info.Text = text
+	}
+	return info.Text, true, nil
+}
+
+// emitParagraph 把一个段落追加到当前章节的待格式化列表。
+func (s *textParseState) emitParagraph(paragraph Paragraph) {
+	if s.currentCh == nil {
+		return
+	}
+	s.paragraphs = append(s.paragraphs, paragraph)
+}
+
+// renderChapter 执行章节过滤器，再把段落格式化写入章节正文。
+func (s *textParseState) renderChapter(chapter *Chapter) error {
+	paragraphs := s.paragraphs
+	s.paragraphs = nil
+	if len(s.rules.ChapterFilters) > 0 {
+		text := &ChapterText{Title: chapter.Title, Line: chapter.Line, Paragraphs: paragraphs}
+		if s.currentVol != nil {
+			text.Volume = s.currentVol.Title
+		}
+		for _, filter := range s.rules.ChapterFilters {
+			if err := filter.FilterChapter(s.ctx, text); err != nil {
+				return fmt.Errorf("章节过滤器处理 %s 失败: %w", chapter.Title, err)
+			}
+		}
+		chapter.Title = text.Title
+		paragraphs = text.Paragraphs
+	}
+	if s.rules.Markup == markupAozora {
+		paragraphs = trimAozoraPageBreaks(paragraphs)
+	}
+	paragraphs, notes := s.extractFootnotes(paragraphs)
+
+	var body strings.Builder
+	for _, paragraph := range paragraphs {
+		switch {
+		case paragraph.SceneBreak:
+			body.WriteString(SceneBreak)
+		case paragraph.Image != "":
+			body.WriteString(formatIllustration(paragraph.Image))
+		case strings.TrimSpace(paragraph.Text) == "":
+			continue
+		case s.rules.Markup == markupAozora:
+			body.WriteString(formatAozoraParagraph(paragraph.Text))
+		case s.rules.ParagraphMode == paragraphModeBlank:
+			body.WriteString(formatLineBlock(strings.Split(paragraph.Text, "\n")))
+		default:
+			body.WriteString(formatParagraph(strings.TrimSpace(paragraph.Text)))
+		}
+	}
+	if notes == nil {
+		chapter.Content.WriteString(body.String())
+		return nil
+	}
+	format := html.EscapeString
+	if s.rules.Markup == markupAozora {
+		format = formatAozoraInline
+	}
+	chapter.Content.WriteString(s.linkFootnotes(body.String(), notes, format))
+	return nil
+}
@@ -0,0 +1,124 @@
+package goepub
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFilterTestBook(t *testing.T) string {
+	t.Helper()
+	isolateAutoRuleConfigDiscovery(t)
+
+	txtPath := filepath.Join(t.TempDir(), "filter.txt")
+	content := strings.Join([]string{
+		"过滤测试",
+		"第一卷 起点",
+		"第一章 开始",
+		"　　内部屏蔽词出现在这里。",
+		"　　正常的一段。",
+		"***",
+		"　　TODO 删除这一行",
+		"第二章 继续",
+		"　　后续正文。",
+	}, "\n")
+	if err := os.WriteFile(txtPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write txt: %v", err)
+	}
+	return txtPath
+}
+
+func TestLineAndChapterFiltersRunBeforeFormatting(t *testing.T) {
+	txtPath := writeFilterTestBook(t)
+
+	var seen []LineInfo
+	lineFilter := LineFilterFunc(func(_ context.Context, line LineInfo) (string, bool, error) {
+		seen = append(seen, line)
+		if strings.Contains(line.Text, "TODO") {
+			return "", false, nil
+		}
+		// 过滤器看到的是未分类的原始行，改写后的行照常参与章节识别。
+		if line.Text == "第二章 继续" {
+			return "第二章 继续<修>", true, nil
+		}
+		return strings.ReplaceAll(line.Text, "内部屏蔽词", "**"), true, nil
+	})
+
+	var chapters []ChapterText
+	chapterFilter := ChapterFilterFunc(func(_ context.Context, chapter *ChapterText) error {
+		chapters = append(chapters, ChapterText{Volume: chapter.Volume, Title: chapter.Title, Paragraphs: append([]Paragraph(nil), chapter.Paragraphs...)})
+		chapter.Title = strings.TrimSuffix(chapter.Title, "<修>")
+		chapter.Paragraphs = append(chapter.Paragraphs, Paragraph{Text: "<附注>"})
+		return nil
+	})
+
+	book := &Book{
+		Filename:       txtPath,
+		LineFilters:    []LineFilter{lineFilter},
+		ChapterFilters: []ChapterFilter{chapterFilter},
+	}
+	parsed, err := ParseBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("parse book: %v", err)
+	}
+
+	if len(seen) != 9 || seen[3].Number != 4 || seen[3].Volume != "第一卷 起点" || seen[3].Chapter != "第一章 开始" || seen[3].Text != "　　内部屏蔽词出现在这里。" {
+		t.Fatalf("unexpected line metadata: %+v", seen)
+	}
+
+	if len(chapters) != 2 || chapters[0].Volume != "第一卷 起点" || chapters[1].Title != "第二章 继续<修>" {
+		t.Fatalf("unexpected chapter filter input: %+v", chapters)
+	}
+	wantParagraphs := []Paragraph{{Text: "**出现在这里。"}, {Text: "正常的一段。"}, {SceneBreak: true}}
+	if len(chapters[0].Paragraphs) != len(wantParagraphs) {
+		t.Fatalf("unexpected paragraphs: %+v", chapters[0].Paragraphs)
+	}
+	for i, paragraph := range wantParagraphs {
+		if chapters[0].Paragraphs[i] != paragraph {
+			t.Fatalf("unexpected paragraph %d: %+v", i, chapters[0].Paragraphs[i])
+		}
+	}
+
+	got := parsed.Volumes[0].Chapters
+	if got[1].Title != "第二章 继续" {
+		t.Fatalf("chapter filter should be able to rename chapters: %q", got[1].Title)
+	}
+	want := formatParagraph("**出现在这里。") + formatParagraph("正常的一段。") + SceneBreak + formatParagraph("<附注>")
+	if content := got[0].Content.String(); content != want {
+		t.Fatalf("unexpected filtered content:\n got: %s\nwant: %s", content, want)
+	}
+}
+
+func TestFilterErrorsStopParsing(t *testing.T) {
+	txtPath := writeFilterTestBook(t)
+	errBlocked := errors.New("blocked")
+
+	book := &Book{
+		Filename: txtPath,
+		LineFilters: []LineFilter{LineFilterFunc(func(_ context.Context, line LineInfo) (string, bool, error) {
+			if line.Number == 5 {
+				return "", false, errBlocked
+			}
+			return line.Text, true, nil
+		})},
+	}
+	if _, err := ParseBook(context.Background(), book); !errors.Is(err, errBlocked) {
+		t.Fatalf("expected line filter error, got %v", err)
+	}
+
+	book = &Book{
+		Filename: txtPath,
+		ChapterFilters: []ChapterFilter{ChapterFilterFunc(func(_ context.Context, chapter *ChapterText) error {
+			if chapter.Title == "第二章 继续" {
+				return errBlocked
+			}
+			return nil
+		})},
+	}
+	if _, err := ParseBook(context.Background(), book); !errors.Is(err, errBlocked) {
+		t.Fatalf("expected chapter filter error, got %v", err)
+	}
+}
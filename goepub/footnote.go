@@ -0,0 +1,235 @@
+package goepub
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 注释识别开关。
+const (
+	// footnoteModeOn 把注释标记转换成 EPUB3 弹出式脚注。
+	footnoteModeOn = "on"
+	// footnoteModeOff 保留注释标记和注释段落的原文，是默认值。
+	footnoteModeOff = "off"
+)
+
+var (
+	// defaultFootnoteRefPatterns 匹配正文中的注释引用，第一个捕获组为注释编号。
+	// 带圈数字在正文中常用作列表序号，只有带“注”前缀时才当作注释引用。
+	defaultFootnoteRefPatterns = []string{
+		`\[(\d{1,3})\]`,
+		`注([①-⑳])`,
+	}
+	// defaultFootnoteNotePatterns 匹配章末单独成段的注释，第一个捕获组为注释编号，第二个为注释内容。
+	defaultFootnoteNotePatterns = []string{
+		`^\[(\d{1,3})\][:：\s]*(.+)$`,
+		`^注?([①-⑳])[:：\s]*(.+)$`,
+	}
+	// defaultFootnoteInlinePatterns 匹配夹在正文中的行内注释，第一个捕获组为注释内容。
+	defaultFootnoteInlinePatterns = []string{
+		`（注[:：]([^（）]+)）`,
+		`\(注[:：]([^()]+)\)`,
+	}
+)
+
+// footnoteInlineLabel 是行内注释在正文中显示的引用标记。
+const footnoteInlineLabel = "注"
+
+// 注释引用在格式化之前先换成占位符，避免被转义或被青空文库注记处理改写。
+// 占位符使用 XML 不允许的控制字符，sanitizeLine 已经把它们从原文中删除，正文不会与占位符混淆；
+// 章节过滤器改写过的段落在识别注释前会再清理一次。
+const (
+	footnotePlaceholderStart = "\x01"
+	footnotePlaceholderEnd   = "\x02"
+)
+
+var (
+	footnotePlaceholderPattern = regexp.MustCompile(`\x01(\d+)\x02`)
+	footnotePlaceholderCleaner = strings.NewReplacer(footnotePlaceholderStart, "", footnotePlaceholderEnd, "")
+)
+
+func normalizeFootnoteMode(value string) (string, error) {
+	switch value = strings.ToLower(strings.TrimSpace(value)); value {
+	case "", footnoteModeOff:
+		return footnoteModeOff, nil
+	case footnoteModeOn:
+		return value, nil
+	}
+	return "", fmt.Errorf("不支持的注释处理方式: %s", value)
+}
+
+// footnote 是章节中的一条注释。
+type footnote struct {
+	// id 是全书唯一的注释编号，按引用在正文中出现的顺序分配，合并写入同一文件的章节之间不会重复。
+	id   int
+	text string
+	// refs 是正文中指向它的引用数，第一个引用是注释返回链接的目标。
+	refs int
+	// label 是第一个引用在正文中的原文，也用作注释开头的返回链接。
+	label string
+}
+
+// footnoteRef 是正文中的一个注释引用，label 是它在正文中显示的原文。
+type footnoteRef struct {
+	note  *footnote
+	label string
+}
+
+// chapterFootnotes 记录一个章节中识别出的注释和引用。
+type chapterFootnotes struct {
+	notes []*footnote
+	refs  []footnoteRef
+}
+
+// extractFootnotes 识别章节中的注释：行内注释，以及被其他段落引用的注释段落。
+// 注释段落只在章末连续的一组段落中查找，夹在正文中的编号列表不会被当作注释。
+// 引用位置换成占位符，被引用的注释段落从正文中去掉；没有被引用的注释段落按普通段落保留。
+func (s *textParseState) extractFootnotes(paragraphs []Paragraph) ([]Paragraph, *chapterFootnotes) {
+	rules := s.rules
+	if rules.FootnoteMode != footnoteModeOn {
+		return paragraphs, nil
+	}
+
+	for i := range paragraphs {
+		paragraphs[i].Text = footnotePlaceholderCleaner.Replace(paragraphs[i].Text)
+	}
+
+	// start 是章末注释段落的起点，其间的空段落不打断这组注释。
+	start := len(paragraphs)
+	for start > 0 {
+		paragraph := paragraphs[start-1]
+		empty := !paragraph.SceneBreak && paragraph.Image == "" && strings.TrimSpace(paragraph.Text) == ""
+		if _, _, ok := footnoteNoteMatch(rules.FootnoteNoteRegexps, paragraph.Text); !empty && !ok {
+			break
+		}
+		start--
+	}
+	// 同一编号出现多次时使用第一段注释。
+	definitions := make(map[string]int)
+	for i := start; i < len(paragraphs); i++ {
+		if label, _, ok := footnoteNoteMatch(rules.FootnoteNoteRegexps, paragraphs[i].Text); ok {
+			if _, ok := definitions[label]; !ok {
+				definitions[label] = i
+			}
+		}
+	}
+
+	notes := &chapterFootnotes{}
+	byLabel := make(map[string]*footnote)
+	addRef := func(note *footnote, label string) string {
+		notes.refs = append(notes.refs, footnoteRef{note: note, label: label})
+		return footnotePlaceholderStart + strconv.Itoa(len(notes.refs)-1) + footnotePlaceholderEnd
+	}
+	newNote := func(text string) *footnote {
+		note := &footnote{text: strings.TrimSpace(text)}
+		notes.notes = append(notes.notes, note)
+		return note
+	}
+
+	used := make(map[int]bool)
+	for i := range paragraphs[:start] {
+		if paragraphs[i].SceneBreak {
+			continue
+		}
+		text := paragraphs[i].Text
+		for _, re := range rules.FootnoteInlineRegexps {
+			text = re.ReplaceAllStringFunc(text, func(match string) string {
+				m := re.FindStringSubmatch(match)
+				if strings.TrimSpace(m[1]) == "" {
+					return match
+				}
+				return addRef(newNote(m[1]), footnoteInlineLabel)
+			})
+		}
+		for _, re := range rules.FootnoteRefRegexps {
+			text = re.ReplaceAllStringFunc(text, func(match string) string {
+				label := re.FindStringSubmatch(match)[1]
+				index, ok := definitions[label]
+				if !ok {
+					return match
+				}
+				note := byLabel[label]
+				if note == nil {
+					_, text, _ := footnoteNoteMatch(rules.FootnoteNoteRegexps, paragraphs[index].Text)
+					note = newNote(text)
+					byLabel[label] = note
+					used[index] = true
+				}
+				return addRef(note, match)
+			})
+		}
+		paragraphs[i].Text = text
+	}
+	if len(notes.refs) == 0 {
+		return paragraphs, nil
+	}
+
+	kept := paragraphs[:0]
+	for i, paragraph := range paragraphs {
+		if !used[i] {
+			kept = append(kept, paragraph)
+		}
+	}
+	return kept, notes
+}
+
+// footnoteNoteMatch 用段落的第一行匹配注释段落正则，返回注释编号和内容；内容为空时视为不匹配。
+// 空行分段模式下一段注释可能跨多行，第一行之后的各行接在注释内容后面。
+func footnoteNoteMatch(patterns []*regexp.Regexp, text string) (string, string, bool) {
+	first, rest, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	first = strings.TrimSpace(first)
+	for _, re := range patterns {
+		m := re.FindStringSubmatch(first)
+		if m == nil {
+			continue
+		}
+		note := strings.TrimSpace(m[2] + "\n" + rest)
+		if note == "" {
+			continue
+		}
+		return m[1], note, true
+	}
+	return "", "", false
+}
+
+// linkFootnotes 把正文中的占位符换成指向注释的 noteref 链接，并在正文末尾追加注释。
+// 注释写成 EPUB3 的 aside 脚注，支持的阅读器以弹窗显示；其他阅读器把它们当作章末的普通段落，
+// 通过链接在引用和注释之间来回跳转。format 决定注释内容的转义方式。
+func (s *textParseState) linkFootnotes(body string, notes *chapterFootnotes, format func(string) string) string {
+	var linked []*footnote
+	body = footnotePlaceholderPattern.ReplaceAllStringFunc(body, func(match string) string {
+		index, err := strconv.Atoi(footnotePlaceholderPattern.FindStringSubmatch(match)[1])
+		if err != nil || index >= len(notes.refs) {
+			return ""
+		}
+		ref := notes.refs[index]
+		note := ref.note
+		note.refs++
+		if note.id == 0 {
+			s.footnotes++
+			note.id, note.label = s.footnotes, ref.label
+			linked = append(linked, note)
+		}
+		id := fmt.Sprintf("noteref%d", note.id)
+		if note.refs > 1 {
+			id = fmt.Sprintf("noteref%d-%d", note.id, note.refs)
+		}
+		return fmt.Sprintf(`<a epub:type="noteref" href="#note%d" id="%s" class="noteref">%s</a>`, note.id, id, html.EscapeString(ref.label))
+	})
+
+	var b strings.Builder
+	b.WriteString(body)
+	for _, note := range linked {
+		// 多行注释保留换行。
+		lines := strings.Split(note.text, "\n")
+		for i, line := range lines {
+			lines[i] = format(strings.TrimSpace(line))
+		}
+		fmt.Fprintf(&b, `<aside epub:type="footnote" id="note%d" class="footnote"><p><a href="#noteref%d">%s</a> %s</p></aside>`+"\n",
+			note.id, note.id, html.EscapeString(note.label), strings.Join(lines, "<br/>"))
+	}
+	return b.String()
+}
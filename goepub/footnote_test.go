@@ -0,0 +1,145 @@
+package goepub
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestFootnotes(t *testing.T) {
+	source := strings.Join([]string{
+		"注释测试",
+		"第一章 渡河",
+		"他读过《楚辞》[1]，也读过《离骚》[1]。",
+		"岸边有一座庙注①（注：今已不存）。",
+		"他在第[2]页停下。",
+		"私用区字符\uE0000\uE001不是注释。",
+		"[1] 屈原所作。",
+		"①：旧称河伯庙。",
+		"第二章 归途",
+		"又见注①。",
+		"① 另一章的注释。",
+		"第三章 说明",
+		"先看①再看②。",
+		"[1] 打开电源",
+		"之后参见[1]。",
+		"①打开电源",
+		"②按下按钮",
+	}, "\n")
+
+	parse := func(mode string) *ParsedBook {
+		t.Helper()
+		config := defaultRuleConfig()
+		config.FootnoteMode = mode
+		rules, err := compileRuleConfig(config)
+		if err != nil {
+			t.Fatalf("compile rules: %v", err)
+		}
+		parsed, err := NewTextParser().Parse(context.Background(), strings.NewReader(source), rules)
+		if err != nil {
+			t.Fatalf("parse: %v", err)
+		}
+		return parsed
+	}
+
+	chapters := parse(footnoteModeOn).Volumes[0].Chapters
+	first := chapters[0].Content.String()
+	for _, want := range []string{
+		`《楚辞》<a epub:type="noteref" href="#note1" id="noteref1" class="noteref">[1]</a>`,
+		`《离骚》<a epub:type="noteref" href="#note1" id="noteref1-2" class="noteref">[1]</a>`,
+		`庙<a epub:type="noteref" href="#note2" id="noteref2" class="noteref">注①</a><a epub:type="noteref" href="#note3" id="noteref3" class="noteref">注</a>。`,
+		"第[2]页",
+		`<aside epub:type="footnote" id="note1" class="footnote"><p><a href="#noteref1">[1]</a> 屈原所作。</p></aside>` + "\n" +
+			`<aside epub:type="footnote" id="note2" class="footnote"><p><a href="#noteref2">注①</a> 旧称河伯庙。</p></aside>` + "\n" +
+			`<aside epub:type="footnote" id="note3" class="footnote"><p><a href="#noteref3">注</a> 今已不存</p></aside>`,
+	} {
+		if !strings.Contains(first, want) {
+			t.Fatalf("expected %q in chapter: %s", want, first)
+		}
+	}
+	if strings.Contains(first, "<p>　　[1]") || strings.Contains(first, "<p>　　①") {
+		t.Fatalf("referenced notes should be moved out of the body: %s", first)
+	}
+	// 注释编号全书连续，每章的注释只在本章中查找。
+	second := chapters[1].Content.String()
+	if !strings.Contains(second, `href="#note4" id="noteref4" class="noteref">注①</a>`) || !strings.Contains(second, `id="note4"`) {
+		t.Fatalf("expected chapter-local note with a book-wide id: %s", second)
+	}
+
+	// 原文中的私用区字符不会被当作注释占位符。
+	if !strings.Contains(first, "私用区字符\uE0000\uE001不是注释。") || strings.Count(first, "<aside") != 3 {
+		t.Fatalf("private use characters should be kept as text: %s", first)
+	}
+
+	// 章节过滤器写入的控制字符同样不会变成注释链接。
+	config := defaultRuleConfig()
+	config.FootnoteMode = footnoteModeOn
+	rules, err := compileRuleConfig(config)
+	if err != nil {
+		t.Fatalf("compile rules: %v", err)
+	}
+	rules.ChapterFilters = []ChapterFilter{ChapterFilterFunc(func(ctx context.Context, chapter *ChapterText) error {
+		chapter.Paragraphs = append([]Paragraph{{Text: "过滤器\x010\x02插入"}}, chapter.Paragraphs...)
+		return nil
+	})}
+	parsed, err := NewTextParser().Parse(context.Background(), strings.NewReader(source), rules)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if filtered := parsed.Volumes[0].Chapters[0].Content.String(); !strings.Contains(filtered, "过滤器0插入") || strings.Count(filtered, `class="noteref"`) != 4 {
+		t.Fatalf("control characters from filters should be removed: %s", filtered)
+	}
+
+	// 编号列表不是注释：带圈数字没有“注”前缀，夹在正文中间的 [1] 段落也不在章末。
+	third := chapters[2].Content.String()
+	if strings.Contains(third, "noteref") || strings.Contains(third, "<aside") ||
+		!strings.Contains(third, "先看①再看②。") || !strings.Contains(third, "[1] 打开电源") ||
+		!strings.Contains(third, "之后参见[1]。") || !strings.Contains(third, "①打开电源") || !strings.Contains(third, "②按下按钮") {
+		t.Fatalf("numbered lists should be kept as body text: %s", third)
+	}
+
+	// 注释识别需要显式打开，默认输出与 off 相同。
+	if config := defaultRuleConfig(); config.FootnoteMode != footnoteModeOff {
+		t.Fatalf("footnotes should be off by default, got %q", config.FootnoteMode)
+	}
+	defaultContent := parse("").Volumes[0].Chapters[0].Content.String()
+	if strings.Contains(defaultContent, "<aside") || !strings.Contains(defaultContent, "（注：今已不存）") {
+		t.Fatalf("default mode should keep notes as text: %s", defaultContent)
+	}
+
+	off := parse(footnoteModeOff).Volumes[0].Chapters[0].Content.String()
+	if strings.Contains(off, "noteref") || !strings.Contains(off, "[1] 屈原所作。") {
+		t.Fatalf("off mode should keep the original text: %s", off)
+	}
+
+	// 空行分段模式下注释可以跨多行，只用第一行匹配注释段落。
+	config = defaultRuleConfig()
+	config.FootnoteMode = footnoteModeOn
+	config.ParagraphMode = paragraphModeBlank
+	rules, err = compileRuleConfig(config)
+	if err != nil {
+		t.Fatalf("compile rules: %v", err)
+	}
+	blank := strings.Join([]string{
+		"注释测试",
+		"第一章 渡河",
+		"他读过《楚辞》[1]。",
+		"",
+		"[1] 屈原所作，",
+		"共二十五篇。",
+	}, "\n")
+	parsed, err = NewTextParser().Parse(context.Background(), strings.NewReader(blank), rules)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if content := parsed.Volumes[0].Chapters[0].Content.String(); !strings.Contains(content, `href="#note1"`) ||
+		!strings.Contains(content, `<a href="#noteref1">[1]</a> 屈原所作，<br/>共二十五篇。</p></aside>`) {
+		t.Fatalf("expected multi-line note in blank paragraph mode: %s", content)
+	}
+
+	config = defaultRuleConfig()
+	config.FootnoteNotePatterns = []string{`^\[(\d+)\]`}
+	if _, err := compileRuleConfig(config); err == nil {
+		t.Fatal("expected note pattern without a text group to fail")
+	}
+}
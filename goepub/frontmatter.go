@@ -0,0 +1,62 @@
+package goepub
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 首章之前正文的处理方式。
+const (
+	// frontMatterKeep 把首个卷章之前的正文保留为单独的“前言”章节，放在第一卷之前。
+	frontMatterKeep = "keep"
+	// frontMatterDrop 丢弃这些正文，只在报告中记录。
+	frontMatterDrop = "drop"
+	// frontMatterIntro 把这些正文并入书籍简介。
+	frontMatterIntro = "intro"
+)
+
+// frontMatterTitles 是各语言预设下保留下来的首章之前正文的章节标题，默认使用中文“前言”。
+var frontMatterTitles = map[string]string{
+	englishRulePreset:  "Preface",
+	japaneseRulePreset: "まえがき",
+}
+
+// frontMatterTitle 返回 lang 对应的前言章节标题。
+func frontMatterTitle(lang string) string {
+	if title, ok := frontMatterTitles[languageRulePreset(lang)]; ok {
+		return title
+	}
+	return "前言"
+}
+
+func normalizeFrontMatterMode(value string) (string, error) {
+	switch value = strings.ToLower(strings.TrimSpace(value)); value {
+	case "", frontMatterKeep:
+		return frontMatterKeep, nil
+	case frontMatterDrop, frontMatterIntro:
+		return value, nil
+	}
+	return "", fmt.Errorf("不支持的首章前正文处理方式: %s", value)
+}
+
+// appendFrontMatter 按 FrontMatter 规则处理首个部、卷或章节出现之前的正文行。
+// 书名、作者和简介在此之前已经被消耗，这里收到的是序言、版权声明、作者前言等内容。
+func (s *textParseState) appendFrontMatter(raw, line string) {
+	report := &s.parsed.Report
+	report.FrontMatterLines++
+	switch s.frontMatter {
+	case frontMatterDrop:
+		report.DiscardedLines = append(report.DiscardedLines, reportLine(s.lineNo, line))
+	case frontMatterIntro:
+		if text := s.rules.ApplyReplacements(line, ReplacementScopeBody); text != "" {
+			s.frontMatterLines = append(s.frontMatterLines, text)
+		}
+	default:
+		if s.currentCh == nil {
+			// 前言放在匿名卷中，随后出现的无卷章节会继续挂在同一个匿名卷下。
+			s.currentVol = s.newVolume("")
+			s.currentCh = &Chapter{Title: frontMatterTitle(s.lang), Line: s.lineNo}
+		}
+		s.appendBody(raw, line)
+	}
+}
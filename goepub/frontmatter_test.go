@@ -0,0 +1,144 @@
+package goepub
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFrontMatterModes(t *testing.T) {
+	source := strings.Join([]string{
+		"前言测试",
+		"作者：周九",
+		"本书纯属虚构。",
+		"",
+		"感谢各位读者。",
+		"第一卷 起点",
+		"卷首语不属于任何章节。",
+		"第一章 开始",
+		"第一段内容",
+	}, "\n")
+
+	parse := func(mode string) *ParsedBook {
+		t.Helper()
+		config := defaultRuleConfig()
+		config.FrontMatter = mode
+		rules, err := compileRuleConfig(config)
+		if err != nil {
+			t.Fatalf("compile rules: %v", err)
+		}
+		parsed, err := NewTextParser().Parse(context.Background(), strings.NewReader(source), rules)
+		if err != nil {
+			t.Fatalf("parse: %v", err)
+		}
+		if parsed.Report.FrontMatter != mode || parsed.Report.FrontMatterLines != 2 {
+			t.Fatalf("unexpected front matter report for %s: %s %d", mode, parsed.Report.FrontMatter, parsed.Report.FrontMatterLines)
+		}
+		return parsed
+	}
+
+	parsed := parse(frontMatterKeep)
+	if len(parsed.Volumes) != 2 || parsed.Volumes[0].Title != "" || parsed.Volumes[1].Title != "第一卷 起点" {
+		t.Fatalf("front matter should be placed before the first volume: %+v", parsed.Volumes)
+	}
+	front := parsed.Volumes[0].Chapters
+	if len(front) != 1 || front[0].Title != "前言" || front[0].Line != 3 {
+		t.Fatalf("unexpected front matter chapter: %+v", front)
+	}
+	if got, want := front[0].Content.String(), formatParagraph("本书纯属虚构。")+formatParagraph("感谢各位读者。"); got != want {
+		t.Fatalf("unexpected front matter content:\n got: %s\nwant: %s", got, want)
+	}
+	// 卷标题与首章之间的正文不属于前言，仍然按无归属丢弃。
+	if len(parsed.Report.DiscardedLines) != 1 || parsed.Report.DiscardedLines[0].Line != 7 {
+		t.Fatalf("unexpected discarded lines: %+v", parsed.Report.DiscardedLines)
+	}
+
+	parsed = parse(frontMatterDrop)
+	if len(parsed.Volumes) != 1 || len(parsed.Report.DiscardedLines) != 3 || parsed.Report.DiscardedLines[0].Line != 3 {
+		t.Fatalf("drop mode should only report the lines: %+v %+v", parsed.Volumes, parsed.Report.DiscardedLines)
+	}
+
+	parsed = parse(frontMatterIntro)
+	if len(parsed.Volumes) != 1 || parsed.Intro != "本书纯属虚构。\n感谢各位读者。" {
+		t.Fatalf("intro mode should move the lines into the intro: %q", parsed.Intro)
+	}
+}
+
+func TestFrontMatterCanBeSetPerChannelAndBook(t *testing.T) {
+	isolateAutoRuleConfigDiscovery(t)
+
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "rules.toml")
+	configContent := `
+[channels.clean]
+front_matter = "drop"
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	txtPath := filepath.Join(tmpDir, "story.txt")
+	content := strings.Join([]string{
+		"前言测试",
+		"本书纯属虚构。",
+		"第一章 开始",
+		"第一段内容",
+	}, "\n")
+	if err := os.WriteFile(txtPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write txt: %v", err)
+	}
+
+	book := &Book{Filename: txtPath, RuleConfigPath: configPath, RuleChannel: "clean"}
+	parsed, err := ParseBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("parse book: %v", err)
+	}
+	if parsed.Report.FrontMatter != frontMatterDrop || len(parsed.Volumes[0].Chapters) != 1 {
+		t.Fatalf("channel front matter mode should apply: %s", parsed.Report.FrontMatter)
+	}
+
+	// 调用方提供简介时 intro 模式无法生效，退回保留为前言章节。
+	book = &Book{Filename: txtPath, RuleConfigPath: configPath, RuleChannel: "clean", FrontMatter: "intro", Intro: "已有简介"}
+	parsed, err = ParseBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("parse book: %v", err)
+	}
+	if parsed.Report.FrontMatter != frontMatterKeep || parsed.Volumes[0].Chapters[0].Title != "前言" {
+		t.Fatalf("intro mode with explicit intro should keep the chapter: %s", parsed.Report.FrontMatter)
+	}
+
+	book = &Book{Filename: txtPath, FrontMatter: "append"}
+	if _, err := ParseBook(context.Background(), book); err == nil {
+		t.Fatal("expected unsupported front matter mode to fail")
+	}
+}
+
+func TestFrontMatterTitleFollowsLanguage(t *testing.T) {
+	isolateAutoRuleConfigDiscovery(t)
+
+	for lang, want := range map[string]string{"": "前言", "zh-TW": "前言", "en-US": "Preface", "ja": "まえがき"} {
+		if got := frontMatterTitle(lang); got != want {
+			t.Fatalf("frontMatterTitle(%q) = %q, want %q", lang, got, want)
+		}
+	}
+
+	txtPath := filepath.Join(t.TempDir(), "story.txt")
+	content := strings.Join([]string{
+		"The Long Road",
+		"This is a work of fiction.",
+		"Chapter 1 The Start",
+		"It was a quiet morning.",
+	}, "\n")
+	if err := os.WriteFile(txtPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write txt: %v", err)
+	}
+	parsed, err := ParseBook(context.Background(), &Book{Filename: txtPath, Lang: "en"})
+	if err != nil {
+		t.Fatalf("parse book: %v", err)
+	}
+	if chapters := parsed.Volumes[0].Chapters; len(chapters) == 0 || chapters[0].Title != "Preface" {
+		t.Fatalf("english front matter should be titled Preface: %+v", parsed.Volumes)
+	}
+}
@@ -108,8 +108,18 @@ func addEmbeddedFonts(e *epublib.Epub) (func(), error) {
 	}, nil
 }
 
+// verticalWritingCSS 是竖排时追加的样式，同时写出 EPUB 3 和旧版阅读器使用的私有前缀属性。
+const verticalWritingCSS = `@charset "utf-8";
+html {
+    -epub-writing-mode: vertical-rl;
+    -webkit-writing-mode: vertical-rl;
+    writing-mode: vertical-rl;
+}
+`
+
 // addEmbeddedStyles 将内置样式注册到 EPUB，并额外生成一个聚合样式文件统一导入。
-func addEmbeddedStyles(e *epublib.Epub) (string, func(), error) {
+// vertical 为 true 时额外注册竖排样式。
+func addEmbeddedStyles(e *epublib.Epub, vertical bool) (string, func(), error) {
 	var imports []string
 	var cleanups []func()
 
@@ -149,6 +159,20 @@ func addEmbeddedStyles(e *epublib.Epub) (string, func(), error) {
 		runCleanups(cleanups)
 		return "", nil, err
 	}
+	if vertical {
+		source, cleanup, err := writeTempAsset("vertical.css", []byte(verticalWritingCSS))
+		if err != nil {
+			runCleanups(cleanups)
+			return "", nil, err
+		}
+		cleanups = append(cleanups, cleanup)
+		style, err := e.AddCSS(source, "vertical.css")
+		if err != nil {
+			runCleanups(cleanups)
+			return "", nil, fmt.Errorf("添加竖排样式失败: %w", err)
+		}
+		imports = append(imports, fmt.Sprintf("@import url('%s');", style))
+	}
 
 	if len(imports) == 0 {
 		return "", func() {
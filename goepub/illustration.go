@@ -0,0 +1,134 @@
+package goepub
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// defaultImagePatterns 匹配 TXT 正文中的插图引用，第一个捕获组为图片路径。
+// 轻小说 TXT 常附带一个插图目录，用这几种写法标出插图的位置。
+var defaultImagePatterns = []string{
+	`(?i)\[img\]\s*([^\[\]]+?)\s*\[/img\]`,
+	`!\[[^\]]*\]\(\s*([^()\s]+)\s*\)`,
+	`<插图\s*(\d+)>`,
+}
+
+// imageSearchDirs 是在 TXT 所在目录下查找插图的子目录，空字符串表示 TXT 所在目录本身。
+var imageSearchDirs = []string{"", "插图", "插画", "images", "image", "img", "illustrations"}
+
+// imageExtensions 是插图允许的扩展名，插图引用没有扩展名时依次尝试。
+var imageExtensions = []string{".jpg", ".jpeg", ".png", ".gif", ".webp"}
+
+// findImageReference 返回 line 中最靠前的插图引用的位置，格式与 FindStringSubmatchIndex 相同。
+func (r *ParseRules) findImageReference(line string) []int {
+	var first []int
+	for _, re := range r.ImageRegexps {
+		loc := re.FindStringSubmatchIndex(line)
+		if loc == nil || loc[2] < 0 {
+			continue
+		}
+		if first == nil || loc[0] < first[0] {
+			first = loc
+		}
+	}
+	return first
+}
+
+// appendImages 处理引用了插图的正文行：插图单独成段，引用前后的文字照常作为正文。
+// 行中没有插图引用时返回 false。
+func (s *textParseState) appendImages(line string) bool {
+	loc := s.rules.findImageReference(line)
+	if loc == nil {
+		return false
+	}
+	s.flushParagraph()
+	for loc != nil {
+		s.appendImageText(line[:loc[0]])
+		s.appendImage(line[loc[2]:loc[3]])
+		line = line[loc[1]:]
+		loc = s.rules.findImageReference(line)
+	}
+	s.appendImageText(line)
+	return true
+}
+
+// appendImageText 把插图引用前后的文字作为单独的段落输出。
+func (s *textParseState) appendImageText(text string) {
+	text = s.rules.ApplyReplacements(strings.TrimSpace(text), ReplacementScopeBody)
+	if text == "" {
+		return
+	}
+	s.appendBodyLine(text, text)
+	s.flushParagraph()
+}
+
+// appendImage 登记一张插图并输出插图段落；找不到图片时从正文中去掉引用并记录到报告。
+func (s *textParseState) appendImage(ref string) {
+	source, ok := resolveTextImage(s.dir, ref)
+	if !ok {
+		log.Printf("找不到图片 %s，已从正文中去掉", ref)
+		s.parsed.Report.MissingImages = append(s.parsed.Report.MissingImages, reportLine(s.lineNo, ref))
+		return
+	}
+	s.emitParagraph(Paragraph{Image: s.images.add(source)})
+}
+
+// resolveTextImage 在 dir 及其常见的插图子目录中查找 ref 对应的图片文件，没有扩展名时依次尝试常见的图片扩展名。
+// 查找范围限制在 dir 之内，只接受 imageExtensions 中的扩展名；dir 为空时不查找。
+// 绝对路径、网络地址和跳出 dir 的相对路径都视为找不到，避免插图引用把其他文件打包进 EPUB。
+func resolveTextImage(dir, ref string) (string, bool) {
+	ref, ok := localImageRef(dir, ref)
+	if !ok {
+		return "", false
+	}
+	var names []string
+	switch ext := strings.ToLower(filepath.Ext(ref)); {
+	case ext == "":
+		for _, ext := range imageExtensions {
+			names = append(names, ref+ext)
+		}
+	case isImageExtension(ext):
+		names = []string{ref}
+	default:
+		return "", false
+	}
+	for _, sub := range imageSearchDirs {
+		for _, name := range names {
+			source := filepath.Join(dir, sub, name)
+			if info, err := os.Stat(source); err == nil && info.Mode().IsRegular() {
+				return source, true
+			}
+		}
+	}
+	return "", false
+}
+
+// localImageRef 检查图片引用是否为 dir 之内的相对路径，返回清理后的本地路径。
+// dir 为空、网络地址、绝对路径和跳出 dir 的相对路径都不接受。
+func localImageRef(dir, ref string) (string, bool) {
+	ref = strings.TrimSpace(ref)
+	if dir == "" || ref == "" || isURLorFTP(ref) {
+		return "", false
+	}
+	ref = filepath.Clean(filepath.FromSlash(ref))
+	if ref == "." || filepath.IsAbs(ref) || filepath.VolumeName(ref) != "" ||
+		ref == ".." || strings.HasPrefix(ref, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return ref, true
+}
+
+// isImageExtension 判断小写的扩展名是否在 imageExtensions 中。
+func isImageExtension(ext string) bool {
+	return slices.Contains(imageExtensions, ext)
+}
+
+// formatIllustration 把插图格式化为独占一行的 figure。
+func formatIllustration(href string) string {
+	return fmt.Sprintf("<figure class=\"illustration\"><img src=\"%s\" alt=\"\" /></figure>\n", html.EscapeString(href))
+}
@@ -0,0 +1,68 @@
+package goepub
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseBookImages(t *testing.T) {
+	isolateAutoRuleConfigDiscovery(t)
+
+	root := t.TempDir()
+	writeTestPNG(t, filepath.Join(root, "secret.png"))
+	dir := filepath.Join(root, "book")
+	writeTestPNG(t, filepath.Join(dir, "插图", "001.jpg"))
+	writeTestPNG(t, filepath.Join(dir, "插图", "02.png"))
+	writeTestPNG(t, filepath.Join(dir, "images", "map.png"))
+	content := strings.Join([]string{
+		"插图测试",
+		"第一章 出发",
+		"[img]001.jpg[/img]",
+		"他展开地图![](images/map.png)看了很久。",
+		"<插图02>",
+		"[IMG]插图/001.jpg[/IMG]",
+		"[img]404.jpg[/img]",
+		"[img]../secret.png[/img]",
+		"[img]book.txt[/img]",
+		"[img]book[/img]",
+	}, "\n")
+	txtPath := filepath.Join(dir, "book.txt")
+	if err := os.WriteFile(txtPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write txt: %v", err)
+	}
+
+	parsed, err := ParseBook(context.Background(), &Book{Filename: txtPath})
+	if err != nil {
+		t.Fatalf("parse book: %v", err)
+	}
+	got := parsed.Volumes[0].Chapters[0].Content.String()
+	want := formatIllustration("../images/inline001.jpg") +
+		formatParagraph("他展开地图") +
+		formatIllustration("../images/inline002.png") +
+		formatParagraph("看了很久。") +
+		formatIllustration("../images/inline003.png") +
+		formatIllustration("../images/inline001.jpg")
+	if got != want {
+		t.Fatalf("unexpected chapter content:\n got: %s\nwant: %s", got, want)
+	}
+	if len(parsed.Images) != 3 || parsed.Images[0].Source != filepath.Join(dir, "插图", "001.jpg") {
+		t.Fatalf("expected each image to be packed once: %+v", parsed.Images)
+	}
+	missing := parsed.Report.MissingImages
+	// 不是图片扩展名的文件即使存在也不会被打包。
+	if len(missing) != 4 || missing[0].Line != 7 || missing[0].Text != "404.jpg" || missing[1].Text != "../secret.png" ||
+		missing[2].Text != "book.txt" || missing[3].Text != "book" {
+		t.Fatalf("unexpected missing images: %+v", missing)
+	}
+
+	parsed, err = ParseBook(context.Background(), &Book{Filename: txtPath, DisableImageLookup: true})
+	if err != nil {
+		t.Fatalf("parse book: %v", err)
+	}
+	if len(parsed.Images) != 0 || len(parsed.Report.MissingImages) != 8 {
+		t.Fatalf("image lookup should be disabled: %+v %+v", parsed.Images, parsed.Report.MissingImages)
+	}
+}
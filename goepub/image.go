@@ -0,0 +1,45 @@
+package goepub
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	epublib "github.com/go-shiori/go-epub"
+)
+
+// Image 是正文中引用、需要一并写入 EPUB 的本地图片。
+type Image struct {
+	// Source 是图片在本地的绝对路径。
+	Source string
+	// Filename 是图片在 EPUB 中的文件名，正文通过 imageHref 得到的相对路径引用它。
+	Filename string
+}
+
+// imageSet 收集解析过程中引用到的图片，同一个文件只写入一次。
+type imageSet struct {
+	images   []Image
+	bySource map[string]string
+}
+
+// add 登记一张本地图片，返回正文中引用它的路径。
+func (s *imageSet) add(source string) string {
+	if href, ok := s.bySource[source]; ok {
+		return href
+	}
+	if s.bySource == nil {
+		s.bySource = make(map[string]string)
+	}
+	// 文件名加上前缀，避免与按原文件名写入的封面重名。
+	filename := fmt.Sprintf("inline%03d%s", len(s.images)+1, strings.ToLower(filepath.Ext(source)))
+	s.images = append(s.images, Image{Source: source, Filename: filename})
+	href := imageHref(filename)
+	s.bySource[source] = href
+	return href
+}
+
+// imageHref 返回章节 XHTML 中引用 EPUB 内图片的相对路径，与 go-epub 的 AddImage 返回值一致。
+func imageHref(filename string) string {
+	return path.Join("..", epublib.ImageFolderName, filename)
+}
@@ -0,0 +1,261 @@
+package goepub
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// inferMaxTitleRunes 以上的行不会被当作章节标题候选。
+	inferMaxTitleRunes = 40
+	// inferMaxPrefixRunes 是序号之前允许的最长前缀，例如“第”“Chapter ”“【”。
+	inferMaxPrefixRunes = 8
+	// inferMinMatches 是一种标题格式至少要出现的次数。
+	inferMinMatches = 3
+	// inferMinScore 是接受推断结果的最低得分。
+	inferMinScore = 0.5
+	// inferSampleSize 是推断结果中保留的示例行数。
+	inferSampleSize = 10
+)
+
+// 章节序号的写法。
+const (
+	numeralKindArabic  = "arabic"
+	numeralKindChinese = "chinese"
+)
+
+var numeralClasses = map[string]string{
+	numeralKindArabic:  `[0-9０-９]+`,
+	numeralKindChinese: `[零〇一二两三四五六七八九十百千万壹贰叁肆伍陆柒捌玖拾佰仟]+`,
+}
+
+// headingMarkers 是序号之后常见的章节单位。
+const headingMarkers = "章回节話话集篇幕"
+
+// headingStopPunctuation 结尾的行是正文句子，不会是标题。
+const headingStopPunctuation = "。，；！？!?;,"
+
+// ChapterInference 是根据正文推断出的章节标题格式。
+type ChapterInference struct {
+	// Pattern 是推断出的章节正则。
+	Pattern string `json:"pattern"`
+	// Score 是该格式的综合得分，范围 0 到 1。
+	Score float64 `json:"score"`
+	// Matches 是全文中符合该格式的行数。
+	Matches int `json:"matches"`
+	// Samples 是最先匹配的几行，便于确认推断是否正确。
+	Samples []ReportLine `json:"samples"`
+}
+
+// headingShape 是一行标题去掉具体序号和标题文字之后的形状。
+// 例如“第12章 风起”和“第13章 云涌”的形状都是：前缀“第”、阿拉伯数字、标记“章”。
+type headingShape struct {
+	prefix string
+	kind   string
+	// marker 是序号后的第一个字符；空白统一记为空格，序号在行尾时为空。
+	marker string
+}
+
+type headingCandidate struct {
+	line       ReportLine
+	number     int
+	runes      int
+	afterBlank bool
+}
+
+// InferChapterPattern 在章节正则一个章节都匹配不到时，根据正文推断章节标题的格式。
+// 它按形状把带序号的短行分组，再按序号是否连续、分布是否均匀、是否位于空行之后、
+// 长度和是否带“章”“回”等单位给每组打分，取得分最高的一组生成正则。
+// 没有足够可信的格式时返回 nil。
+func InferChapterPattern(r io.Reader) (*ChapterInference, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxScannerTokenSize)
+	var fixes TextFixes
+	scanner.Split(lineBreakCounter{fixes: &fixes}.split)
+
+	groups := make(map[headingShape][]headingCandidate)
+	lineNo := 0
+	// 文件开头视同位于空行之后。
+	afterBlank := true
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(sanitizeLine(scanner.Text(), &fixes))
+		if line == "" {
+			afterBlank = true
+			continue
+		}
+		if shape, number, ok := parseHeadingShape(line); ok {
+			groups[shape] = append(groups[shape], headingCandidate{
+				line:       reportLine(lineNo, line),
+				number:     number,
+				runes:      utf8.RuneCountInString(line),
+				afterBlank: afterBlank,
+			})
+		}
+		afterBlank = false
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("扫描 TXT 文件失败: %w", err)
+	}
+
+	var best *ChapterInference
+	bestRank := 0.0
+	shapes := make([]headingShape, 0, len(groups))
+	for shape := range groups {
+		shapes = append(shapes, shape)
+	}
+	// map 的遍历顺序不固定，先按首次出现的位置排序，保证得分相同时结果稳定。
+	sort.Slice(shapes, func(i, j int) bool {
+		return groups[shapes[i]][0].line.Line < groups[shapes[j]][0].line.Line
+	})
+	for _, shape := range shapes {
+		candidates := groups[shape]
+		if len(candidates) < inferMinMatches {
+			continue
+		}
+		score := scoreHeadingGroup(shape, candidates)
+		if score < inferMinScore {
+			continue
+		}
+		// 得分接近时偏向出现次数更多的格式。
+		rank := score * float64(len(candidates)) / float64(len(candidates)+2)
+		if best != nil && rank <= bestRank {
+			continue
+		}
+		samples := make([]ReportLine, 0, inferSampleSize)
+		for _, candidate := range candidates[:min(len(candidates), inferSampleSize)] {
+			samples = append(samples, candidate.line)
+		}
+		best = &ChapterInference{
+			Pattern: shape.pattern(),
+			Score:   score,
+			Matches: len(candidates),
+			Samples: samples,
+		}
+		bestRank = rank
+	}
+	return best, nil
+}
+
+// parseHeadingShape 判断一行是否像带序号的标题，返回它的形状和序号。
+func parseHeadingShape(line string) (headingShape, int, bool) {
+	runes := []rune(line)
+	if len(runes) > inferMaxTitleRunes || strings.ContainsRune(headingStopPunctuation, runes[len(runes)-1]) {
+		return headingShape{}, 0, false
+	}
+
+	start, kind := -1, ""
+	for i, r := range runes[:min(len(runes), inferMaxPrefixRunes+1)] {
+		if kind = numeralKind(r); kind != "" {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return headingShape{}, 0, false
+	}
+	end := start
+	for end < len(runes) && numeralKind(runes[end]) == kind {
+		end++
+	}
+	number, ok := parseNumeral(string(runes[start:end]))
+	if !ok {
+		return headingShape{}, 0, false
+	}
+
+	shape := headingShape{
+		prefix: strings.Join(strings.Fields(string(runes[:start])), " "),
+		kind:   kind,
+	}
+	if end < len(runes) {
+		if unicode.IsSpace(runes[end]) {
+			shape.marker = " "
+		} else {
+			shape.marker = string(runes[end])
+		}
+	}
+	return shape, number, true
+}
+
+func numeralKind(r rune) string {
+	switch {
+	case r >= '0' && r <= '9', r >= '０' && r <= '９':
+		return numeralKindArabic
+	case strings.ContainsRune("零〇一二两三四五六七八九十百千万壹贰叁肆伍陆柒捌玖拾佰仟", r):
+		return numeralKindChinese
+	}
+	return ""
+}
+
+// scoreHeadingGroup 给同一形状的候选行打分，各项得分都在 0 到 1 之间。
+func scoreHeadingGroup(shape headingShape, candidates []headingCandidate) float64 {
+	sequential, afterBlank, short := 0, 0, 0.0
+	gaps := make([]float64, 0, len(candidates)-1)
+	for i, candidate := range candidates {
+		if candidate.afterBlank {
+			afterBlank++
+		}
+		short += 1 - float64(candidate.runes)/inferMaxTitleRunes
+		if i == 0 {
+			continue
+		}
+		previous := candidates[i-1]
+		if candidate.number == previous.number+1 {
+			sequential++
+		}
+		gaps = append(gaps, float64(candidate.line.Line-previous.line.Line))
+	}
+
+	// 章节在全书中大致均匀分布，用间隔的变异系数衡量；目录页和正文中的列表都会拉低这一项。
+	mean := 0.0
+	for _, gap := range gaps {
+		mean += gap
+	}
+	mean /= float64(len(gaps))
+	variance := 0.0
+	for _, gap := range gaps {
+		variance += (gap - mean) * (gap - mean)
+	}
+	regularity := 1 / (1 + math.Sqrt(variance/float64(len(gaps)))/mean)
+
+	marker := 0.0
+	if shape.marker != "" && strings.Contains(headingMarkers, shape.marker) {
+		marker = 1
+	}
+
+	n := float64(len(candidates))
+	return 0.35*float64(sequential)/float64(len(gaps)) +
+		0.2*regularity +
+		0.15*float64(afterBlank)/n +
+		0.1*short/n +
+		0.2*marker
+}
+
+// pattern 把标题形状转换为章节正则，前缀中的空白允许宽度不同。
+func (s headingShape) pattern() string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i, part := range strings.Split(s.prefix, " ") {
+		if i > 0 {
+			b.WriteString(`[\s　]*`)
+		}
+		b.WriteString(regexp.QuoteMeta(part))
+	}
+	b.WriteString(numeralClasses[s.kind])
+	switch s.marker {
+	case "":
+		b.WriteString("$")
+	case " ":
+		fmt.Fprintf(&b, `[\s　].{0,%d}$`, inferMaxTitleRunes)
+	default:
+		fmt.Fprintf(&b, `%s.{0,%d}$`, regexp.QuoteMeta(s.marker), inferMaxTitleRunes)
+	}
+	return b.String()
+}
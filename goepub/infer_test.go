@@ -0,0 +1,98 @@
+package goepub
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// inferSample 生成一本章节标题格式特殊、默认规则识别不到的小说。
+func inferSample(heading func(int) string) string {
+	lines := []string{"推断测试", "作者：吴十", ""}
+	for i := 1; i <= 12; i++ {
+		lines = append(lines, heading(i), "")
+		for j := 0; j < 5+i%3; j++ {
+			lines = append(lines, fmt.Sprintf("第%d段正文，讲述了一些事情。", j+1))
+		}
+		if i%4 == 0 {
+			// 正文中的编号列表不应被当作章节。
+			lines = append(lines, "1. 带上干粮", "2. 带上水", "3. 出发")
+		}
+		lines = append(lines, "")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestInferChapterPattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		heading func(int) string
+		want    string
+	}{
+		{"bracket", func(i int) string { return fmt.Sprintf("【%d】风起云涌", i) }, "【12】终章"},
+		{"bare number", func(i int) string { return fmt.Sprintf("%03d", i) }, "013"},
+		{"chinese list", func(i int) string {
+			return fmt.Sprintf("%s、出发", []string{"一", "二", "三", "四", "五", "六", "七", "八", "九", "十", "十一", "十二"}[i-1])
+		}, "十三、归来"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inference, err := InferChapterPattern(strings.NewReader(inferSample(tt.heading)))
+			if err != nil {
+				t.Fatalf("infer: %v", err)
+			}
+			if inference == nil {
+				t.Fatal("expected a chapter pattern")
+			}
+			if inference.Matches != 12 || len(inference.Samples) != inferSampleSize || inference.Samples[0].Text != tt.heading(1) {
+				t.Fatalf("unexpected inference: %+v", inference)
+			}
+			pattern := regexp.MustCompile(inference.Pattern)
+			if !pattern.MatchString(tt.want) {
+				t.Fatalf("pattern %s should match %q", inference.Pattern, tt.want)
+			}
+			if pattern.MatchString("1. 带上干粮") || pattern.MatchString("第1段正文，讲述了一些事情。") {
+				t.Fatalf("pattern %s matches body lines", inference.Pattern)
+			}
+		})
+	}
+
+	inference, err := InferChapterPattern(strings.NewReader("只有一段正文。\n没有任何标题。\n"))
+	if err != nil || inference != nil {
+		t.Fatalf("expected no inference, got %+v %v", inference, err)
+	}
+}
+
+func TestParseBookInfersChapterRegex(t *testing.T) {
+	isolateAutoRuleConfigDiscovery(t)
+
+	txtPath := filepath.Join(t.TempDir(), "story.txt")
+	content := inferSample(func(i int) string { return fmt.Sprintf("【%d】风起云涌", i) })
+	if err := os.WriteFile(txtPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write txt: %v", err)
+	}
+
+	_, err := ParseBook(context.Background(), &Book{Filename: txtPath})
+	if err == nil || !strings.Contains(err.Error(), "【") || !strings.Contains(err.Error(), "auto") {
+		t.Fatalf("expected error with inferred pattern, got %v", err)
+	}
+
+	book := &Book{Filename: txtPath, InferChapterRegex: true}
+	parsed, err := ParseBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("parse book: %v", err)
+	}
+	if got := countParsedChapters(parsed); got != 12 {
+		t.Fatalf("expected 12 chapters, got %d", got)
+	}
+	if parsed.Report.InferredChapter == nil || book.ChapterRegex.String() != parsed.Report.InferredChapter.Pattern {
+		t.Fatalf("inferred pattern should be reported and applied: %+v", parsed.Report.InferredChapter)
+	}
+	if chapters := parsed.Volumes[0].Chapters; chapters[0].Title != "【1】风起云涌" || !strings.Contains(chapters[0].Content.String(), "第1段正文") {
+		t.Fatalf("unexpected first chapter: %+v", chapters[0])
+	}
+}
@@ -0,0 +1,43 @@
+package goepub
+
+import "unicode"
+
+// japaneseRulePreset 是日文小说规则预设的名称。
+const japaneseRulePreset = "ja"
+
+// japaneseScriptMinKana 和 japaneseScriptMinShare 是判定文本为日文所需的最少假名数和假名在文字中的最低占比。
+// 中文正文偶尔也会出现的“・”“ー”不属于平假名或片假名，不计入假名。
+const (
+	japaneseScriptMinKana  = 50
+	japaneseScriptMinShare = 0.1
+)
+
+// japaneseNumeral 是日文标题中的序号，包含汉数字、〇和全角数字。
+const japaneseNumeral = `[〇零一二三四五六七八九十百千0-9０-９]+`
+
+// 日文网络小说以“話”为章节，“章”既可能是章节也可能是包含若干話的大章，这里按章节处理，
+// 需要三级结构时可以用 -volume-regexp 把“章”设为卷。
+const (
+	japaneseVolumePattern  = `^第` + japaneseNumeral + `[巻部編](?:[\s　:：\-—].{0,30})?$`
+	japaneseChapterPattern = `^(?:第` + japaneseNumeral + `[話章]|プロローグ|エピローグ|幕間|閑話|序章|終章).{0,40}$`
+	japaneseExtraPattern   = `^(?:番外編|外伝|SS)(?:[\s　:：\-—].{0,30})?$`
+	japaneseAuthorPattern  = `^(?:作者|著者)[:：]\s*(.+)$`
+	japaneseIntroPattern   = `^(?:あらすじ|紹介|まえがき)$`
+	// japaneseChapterNumberPattern 从“第十二話”“第１２話”中提取序号。
+	japaneseChapterNumberPattern = `^第\s*(` + japaneseNumeral + `)\s*[話章]`
+)
+
+// detectJapaneseScript 判断文本是否为日文：平假名和片假名要达到一定数量和占比。
+func detectJapaneseScript(text string) bool {
+	kana, letters := 0, 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) {
+			kana++
+		}
+	}
+	return kana >= japaneseScriptMinKana && float64(kana) >= float64(letters)*japaneseScriptMinShare
+}
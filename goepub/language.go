@@ -0,0 +1,29 @@
+package goepub
+
+import "strings"
+
+// languageRulePresets 把语言标识的主语言部分映射到对应的规则预设。
+// 默认规则面向中文，其他语言的规则以预设的形式提供，也可以通过 -rule-preset 显式指定。
+var languageRulePresets = map[string]string{
+	"en": englishRulePreset,
+	"ja": japaneseRulePreset,
+}
+
+// languageRulePreset 返回语言标识对应的规则预设，例如 en、en-US 对应 en；中文等没有预设的语言返回空。
+func languageRulePreset(lang string) string {
+	primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(lang)), "-")
+	primary, _, _ = strings.Cut(primary, "_")
+	return languageRulePresets[primary]
+}
+
+// detectTextLanguage 根据文字种类判断正文的语言，无法判断或是中文时返回空。
+// 日文先于英文判断：日文正文夹杂的拉丁字母远少于假名。
+func detectTextLanguage(text string) string {
+	switch {
+	case detectJapaneseScript(text):
+		return japaneseRulePreset
+	case detectLatinScript(text):
+		return englishRulePreset
+	}
+	return ""
+}
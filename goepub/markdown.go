@@ -0,0 +1,517 @@
+package goepub
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/russross/blackfriday/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// 输入文件的格式。
+const (
+	// inputFormatText 是默认的纯文本小说，卷章结构由解析规则识别。
+	inputFormatText = "txt"
+	// inputFormatMarkdown 是 Markdown 文稿，卷章结构由 # 和 ## 标题给出。
+	inputFormatMarkdown = "markdown"
+)
+
+// markdownExtensions 是解析 Markdown 时启用的语法扩展，与常见的 GitHub 风格基本一致。
+// 中文词与词之间没有空格，“说：*走吧*。”这样的强调会被 NoIntraEmphasis 当作词内的星号，因此去掉它。
+const markdownExtensions = blackfriday.CommonExtensions &^ blackfriday.NoIntraEmphasis
+
+// normalizeInputFormat 规范化输入格式；留空时按扩展名判断，.md 和 .markdown 为 Markdown，其余按 TXT 处理。
+func normalizeInputFormat(value, filename string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "":
+		switch strings.ToLower(filepath.Ext(filename)) {
+		case ".md", ".markdown":
+			return inputFormatMarkdown, nil
+		}
+		return inputFormatText, nil
+	case "txt", "text":
+		return inputFormatText, nil
+	case "md", "markdown":
+		return inputFormatMarkdown, nil
+	}
+	return "", fmt.Errorf("不支持的输入格式: %s", value)
+}
+
+// markdownParser 把 Markdown 文稿解析为卷章结构。
+// 同时出现 # 和 ## 标题时，# 是卷、## 是章节；只出现在开头一次的 # 是书名；只有一级标题时它就是章节。
+// 更深的标题留在章节正文中。解析规则里只有标题替换和 front_matter 对 Markdown 生效，
+// 设置了行过滤器或章节过滤器时直接报错，避免它们被悄悄忽略。
+type markdownParser struct {
+	name   string
+	author string
+	intro  string
+	// dir 是 Markdown 文件所在的目录，头信息中的封面以它为基准。
+	dir string
+	// imageDir 是查找正文图片的目录，图片只能是其中的相对路径；为空时不查找图片。
+	imageDir string
+	// lang 是书籍的语言，决定前言章节的标题；inferLang 表示它可以被头信息中的 lang 覆盖，与 applyTo 一致。
+	lang      string
+	inferLang bool
+}
+
+// NewMarkdownParser 创建 Markdown 解析器，dir 是解析相对图片路径时使用的目录，为空时使用当前目录。
+func NewMarkdownParser(dir string) Parser {
+	if dir == "" {
+		dir = "."
+	}
+	return &markdownParser{dir: dir, imageDir: dir, inferLang: true}
+}
+
+// newBookMarkdownParser 创建一个了解 Book 中已知元信息的 Markdown 解析器。
+func newBookMarkdownParser(book *Book) *markdownParser {
+	return &markdownParser{
+		name:      book.Name,
+		author:    book.Author,
+		intro:     book.Intro,
+		dir:       filepath.Dir(book.Filename),
+		imageDir:  bookImageDir(book),
+		lang:      book.Lang,
+		inferLang: book.inferLang,
+	}
+}
+
+// markdownState 保存一次 Markdown 解析过程中的状态。
+type markdownState struct {
+	ctx    context.Context
+	rules  *ParseRules
+	parsed *ParsedBook
+	// dir 是查找图片的目录，为空时不查找图片。
+	dir      string
+	renderer *blackfriday.HTMLRenderer
+	images   imageSet
+
+	// lines 是头信息之后的正文行，offset 是它们之前的行数。
+	// blackfriday 不记录节点的位置，行号按节点文字在 lines 中依次查找得到，cursor 是查找的起点。
+	lines  []string
+	offset int
+	cursor int
+
+	// titleLevel、volumeLevel、chapterLevel 分别是书名、卷和章节对应的标题级别，0 表示没有。
+	titleLevel   int
+	volumeLevel  int
+	chapterLevel int
+
+	frontMatter      string
+	frontMatterLines []string
+	structured       bool
+	lang             string
+	currentVol       *Volume
+	currentCh        *Chapter
+}
+
+// Parse 读取 YAML 头信息，再按标题划分卷章，把各块内容渲染为 XHTML。
+func (p *markdownParser) Parse(ctx context.Context, r io.Reader, rules *ParseRules) (*ParsedBook, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if rules == nil {
+		var err error
+		rules, err = compileRuleConfig(defaultRuleConfig())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(rules.LineFilters) > 0 || len(rules.ChapterFilters) > 0 {
+		// 过滤器处理的是 TXT 的原始行和纯文本段落，Markdown 渲染后的内容无法交给它们。
+		return nil, errors.New("Markdown 输入不支持行过滤器和章节过滤器")
+	}
+
+	parsed := &ParsedBook{
+		Name:   p.name,
+		Author: p.author,
+		Intro:  p.intro,
+	}
+	lines, err := readMarkdownLines(r, &parsed.Report.TextFixes)
+	if err != nil {
+		return nil, err
+	}
+	header, bodyStart, err := parseMarkdownHeader(lines)
+	if err != nil {
+		return nil, err
+	}
+	p.applyHeader(parsed, header)
+
+	state := &markdownState{
+		ctx:    ctx,
+		rules:  rules,
+		parsed: parsed,
+		dir:    p.imageDir,
+		renderer: blackfriday.NewHTMLRenderer(blackfriday.HTMLRendererParameters{
+			// 原始 HTML 无法保证是合法的 XHTML，直接跳过。
+			Flags: blackfriday.UseXHTML | blackfriday.SkipHTML,
+		}),
+		lines:       lines[bodyStart:],
+		offset:      bodyStart,
+		frontMatter: rules.FrontMatter,
+		lang:        p.lang,
+	}
+	if p.inferLang && parsed.Metadata.Lang != "" {
+		state.lang = parsed.Metadata.Lang
+	}
+	switch {
+	case state.frontMatter == "":
+		state.frontMatter = frontMatterKeep
+	case state.frontMatter == frontMatterIntro && p.intro != "":
+		// 与 TXT 一致，调用方提供了简介时改为保留为前言章节。
+		state.frontMatter = frontMatterKeep
+	}
+	parsed.Report.FrontMatter = state.frontMatter
+
+	doc := blackfriday.New(blackfriday.WithExtensions(markdownExtensions)).Parse([]byte(strings.Join(state.lines, "\n")))
+	state.titleLevel, state.volumeLevel, state.chapterLevel = markdownHeadingLevels(doc)
+	if state.chapterLevel == 0 {
+		return nil, errors.New("Markdown 文件中没有 # 或 ## 标题，无法划分章节")
+	}
+	for node := doc.FirstChild; node != nil; node = node.Next {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		state.handleBlock(node)
+	}
+	state.flushChapter()
+	state.flushVolume()
+
+	if len(state.frontMatterLines) > 0 {
+		parsed.Intro = strings.TrimSpace(parsed.Intro + "\n" + strings.Join(state.frontMatterLines, "\n"))
+	}
+	parsed.Images = state.images.images
+	return parsed, nil
+}
+
+// applyHeader 用 YAML 头信息补齐调用方没有提供的元信息。
+func (p *markdownParser) applyHeader(parsed *ParsedBook, header map[string]any) {
+	value := func(keys ...string) string {
+		for _, key := range keys {
+			if v := yamlText(header[key]); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+	if parsed.Name == "" {
+		parsed.Name = value("title")
+	}
+	if parsed.Author == "" {
+		parsed.Author = value("author", "authors", "creator")
+	}
+	if parsed.Intro == "" {
+		parsed.Intro = value("description", "summary", "intro", "synopsis", "abstract")
+	}
+	parsed.Metadata = Metadata{
+		Lang:        value("lang", "language"),
+		Cover:       value("cover", "cover-image", "cover_image"),
+		Publisher:   value("publisher"),
+		PublishDate: value("date", "published"),
+	}
+	if cover := parsed.Metadata.Cover; cover != "" && !isURLorFTP(cover) && !filepath.IsAbs(cover) {
+		parsed.Metadata.Cover = filepath.Join(p.dir, filepath.FromSlash(cover))
+	}
+}
+
+// handleBlock 处理文档的一个顶层块：卷章标题开启新的卷章，其余内容渲染到当前章节。
+func (s *markdownState) handleBlock(node *blackfriday.Node) {
+	if node.Type == blackfriday.Heading && !node.IsTitleblock {
+		switch node.Level {
+		case s.titleLevel:
+			title := markdownText(node)
+			s.lineOf(title, true)
+			if s.parsed.Name == "" {
+				s.parsed.Name = s.rules.replaceTitle(title)
+				log.Printf("小说标题: %s", s.parsed.Name)
+			}
+			return
+		case s.volumeLevel:
+			s.flushChapter()
+			s.flushVolume()
+			s.structured = true
+			title := markdownText(node)
+			s.currentVol = &Volume{Title: s.rules.replaceTitle(title), Line: s.lineOf(title, true)}
+			log.Printf("解析卷: %s", s.currentVol.Title)
+			return
+		case s.chapterLevel:
+			s.flushChapter()
+			if s.currentVol == nil {
+				s.currentVol = &Volume{}
+			}
+			s.structured = true
+			title := markdownText(node)
+			s.currentCh = &Chapter{Title: s.rules.replaceTitle(title), Line: s.lineOf(title, true)}
+			log.Printf("解析章节: %s", s.currentCh.Title)
+			return
+		}
+	}
+
+	if !s.structured {
+		s.appendFrontMatter(node)
+		return
+	}
+	if s.currentCh != nil {
+		s.currentCh.Content.WriteString(s.render(node))
+		return
+	}
+	// 卷标题与该卷首章之间的内容没有归属，与 TXT 一样记录后丢弃。
+	text := markdownText(node)
+	s.parsed.Report.DiscardedLines = append(s.parsed.Report.DiscardedLines, reportLine(s.lineOf(text, false), firstLine(text)))
+}
+
+// appendFrontMatter 按 FrontMatter 规则处理首个卷章标题之前的内容块。
+func (s *markdownState) appendFrontMatter(node *blackfriday.Node) {
+	text := markdownText(node)
+	report := &s.parsed.Report
+	if text != "" {
+		report.FrontMatterLines += strings.Count(text, "\n") + 1
+	}
+	switch s.frontMatter {
+	case frontMatterDrop:
+		report.DiscardedLines = append(report.DiscardedLines, reportLine(s.lineOf(text, false), firstLine(text)))
+	case frontMatterIntro:
+		if text != "" {
+			s.frontMatterLines = append(s.frontMatterLines, text)
+		}
+	default:
+		if s.currentCh == nil {
+			s.currentVol = &Volume{}
+			s.currentCh = &Chapter{Title: frontMatterTitle(s.lang), Line: s.lineOf(text, false)}
+		}
+		s.currentCh.Content.WriteString(s.render(node))
+	}
+}
+
+func (s *markdownState) flushChapter() {
+	if s.currentVol == nil || s.currentCh == nil {
+		return
+	}
+	s.currentVol.Chapters = append(s.currentVol.Chapters, *s.currentCh)
+	s.currentCh = nil
+}
+
+func (s *markdownState) flushVolume() {
+	if s.currentVol == nil {
+		return
+	}
+	if len(s.currentVol.Chapters) == 0 && strings.TrimSpace(s.currentVol.Title) == "" {
+		return
+	}
+	s.parsed.Volumes = append(s.parsed.Volumes, *s.currentVol)
+	s.currentVol = nil
+}
+
+// render 把一个内容块渲染为 XHTML。
+// 本地图片会登记到图片列表并改写为 EPUB 内的路径；找不到的图片和远程图片输出替代文字并记录到报告；
+// 指向本地文件的链接在 EPUB 中无法打开，只保留链接文字。
+func (s *markdownState) render(node *blackfriday.Node) string {
+	var buf bytes.Buffer
+	node.Walk(func(n *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		switch n.Type {
+		case blackfriday.Image:
+			if !entering {
+				break
+			}
+			href, ok := s.resolveImage(n)
+			if !ok {
+				buf.WriteString(html.EscapeString(markdownText(n)))
+				return blackfriday.SkipChildren
+			}
+			n.LinkData.Destination = []byte(href)
+		case blackfriday.Link:
+			if !isExternalLink(string(n.LinkData.Destination)) {
+				return blackfriday.GoToNext
+			}
+		case blackfriday.HorizontalRule:
+			// 分隔线与 TXT 的场景分隔使用同一种样式。
+			buf.WriteString(SceneBreak)
+			return blackfriday.GoToNext
+		}
+		return s.renderer.RenderNode(&buf, n, entering)
+	})
+	return buf.String()
+}
+
+// resolveImage 查找图片对应的本地文件，返回它在 EPUB 中的路径。
+// 与 TXT 插图一样，只接受 Markdown 文件所在目录之内、扩展名在 imageExtensions 中的图片。
+func (s *markdownState) resolveImage(node *blackfriday.Node) (string, bool) {
+	dest := strings.TrimSpace(string(node.LinkData.Destination))
+	if !strings.HasPrefix(dest, "data:") {
+		source := dest
+		if unescaped, err := url.PathUnescape(source); err == nil {
+			source = unescaped
+		}
+		if ref, ok := localImageRef(s.dir, source); ok && isImageExtension(strings.ToLower(filepath.Ext(ref))) {
+			source = filepath.Join(s.dir, ref)
+			if info, err := os.Stat(source); err == nil && info.Mode().IsRegular() {
+				return s.images.add(source), true
+			}
+		}
+	}
+	log.Printf("找不到图片 %s，改为输出替代文字", dest)
+	s.parsed.Report.MissingImages = append(s.parsed.Report.MissingImages, reportLine(s.lineOf(dest, false), dest))
+	return "", false
+}
+
+// lineOf 从 cursor 开始查找包含 text 首行的源文件行，返回它的行号；找不到时返回 0。
+// heading 为 true 时该行整行是标题，之后的查找从下一行开始。
+func (s *markdownState) lineOf(text string, heading bool) int {
+	key := firstLine(text)
+	if key == "" {
+		return 0
+	}
+	for i := s.cursor; i < len(s.lines); i++ {
+		if strings.Contains(s.lines[i], key) {
+			s.cursor = i
+			if heading {
+				s.cursor++
+			}
+			return s.offset + i + 1
+		}
+	}
+	return 0
+}
+
+// markdownHeadingLevels 根据顶层的 # 和 ## 标题确定书名、卷和章节分别对应的级别。
+func markdownHeadingLevels(doc *blackfriday.Node) (title, volume, chapter int) {
+	counts := make(map[int]int)
+	first := 0
+	for node := doc.FirstChild; node != nil; node = node.Next {
+		if node.Type != blackfriday.Heading || node.IsTitleblock || node.Level > 2 {
+			continue
+		}
+		counts[node.Level]++
+		if first == 0 {
+			first = node.Level
+		}
+	}
+	switch {
+	case counts[1] > 0 && counts[2] > 0:
+		if counts[1] == 1 && first == 1 {
+			// 开头唯一的 # 是书名，## 是章节。
+			return 1, 0, 2
+		}
+		return 0, 1, 2
+	case counts[1] > 0:
+		return 0, 0, 1
+	case counts[2] > 0:
+		return 0, 0, 2
+	}
+	return 0, 0, 0
+}
+
+// markdownText 提取节点中的纯文字，块与块之间用换行分隔。
+func markdownText(node *blackfriday.Node) string {
+	var b strings.Builder
+	node.Walk(func(n *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		switch n.Type {
+		case blackfriday.Text, blackfriday.Code, blackfriday.CodeBlock:
+			if entering {
+				b.Write(n.Literal)
+			}
+		case blackfriday.Softbreak, blackfriday.Hardbreak:
+			b.WriteByte('\n')
+		case blackfriday.Paragraph, blackfriday.Heading, blackfriday.Item:
+			if !entering {
+				b.WriteByte('\n')
+			}
+		}
+		return blackfriday.GoToNext
+	})
+	return strings.TrimSpace(b.String())
+}
+
+// isExternalLink 判断链接能否在 EPUB 之外打开。
+func isExternalLink(dest string) bool {
+	parsedURL, err := url.Parse(dest)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(parsedURL.Scheme) {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+// firstLine 返回文本去掉首尾空白后的第一行。
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	return strings.TrimSpace(line)
+}
+
+// readMarkdownLines 读取全部文本行，并像 TXT 一样统一换行、清理非法字符。
+func readMarkdownLines(r io.Reader, fixes *TextFixes) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxScannerTokenSize)
+	scanner.Split(lineBreakCounter{fixes: fixes}.split)
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, sanitizeLine(scanner.Text(), fixes))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("扫描 Markdown 文件失败: %w", err)
+	}
+	return lines, nil
+}
+
+// parseMarkdownHeader 解析文件开头由 --- 包围的 YAML 头信息，返回键名转为小写的值和正文开始的行下标。
+// 没有头信息时返回 nil 和 0；头信息不是合法的 YAML 时返回错误。
+func parseMarkdownHeader(lines []string) (map[string]any, int, error) {
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return nil, 0, nil
+	}
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		if line := strings.TrimRight(lines[i], " \t"); line == "---" || line == "..." {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return nil, 0, nil
+	}
+
+	var header map[string]any
+	if err := yaml.Unmarshal([]byte(strings.Join(lines[1:end], "\n")), &header); err != nil {
+		return nil, 0, fmt.Errorf("解析 Markdown 头信息失败: %w", err)
+	}
+	values := make(map[string]any, len(header))
+	for key, value := range header {
+		values[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+	return values, end + 1, nil
+}
+
+// yamlText 把头信息中的值转换为文本：列表用逗号连接，日期只保留年月日，嵌套的映射无法对应到元信息，返回空。
+func yamlText(value any) string {
+	switch v := value.(type) {
+	case nil, map[string]any:
+		return ""
+	case string:
+		return strings.TrimSpace(v)
+	case time.Time:
+		return v.Format(time.DateOnly)
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			if text := yamlText(item); text != "" {
+				items = append(items, text)
+			}
+		}
+		return strings.Join(items, ", ")
+	}
+	return strings.TrimSpace(fmt.Sprint(value))
+}
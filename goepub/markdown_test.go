@@ -0,0 +1,356 @@
+package goepub
+
+import (
+	"archive/zip"
+	"context"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseMarkdownHeader(t *testing.T) {
+	lines := strings.Split(strings.Join([]string{
+		"---",
+		`title: "长夜: 上"`,
+		"author:",
+		"  - 张三",
+		"  - 李四",
+		"tags: [奇幻, 'B 级']  # 注释",
+		"description: |",
+		"  第一行",
+		"  第二行",
+		"summary: >",
+		"  折叠的",
+		"  一段",
+		"Date: 2024-05-01",
+		"---",
+		"# 正文",
+	}, "\n"), "\n")
+
+	values, bodyStart, err := parseMarkdownHeader(lines)
+	if err != nil {
+		t.Fatalf("parse header: %v", err)
+	}
+	if bodyStart != 14 {
+		t.Fatalf("expected body to start after the header, got %d", bodyStart)
+	}
+	want := map[string]string{
+		"title":       "长夜: 上",
+		"author":      "张三, 李四",
+		"tags":        "奇幻, B 级",
+		"description": "第一行\n第二行",
+		"summary":     "折叠的 一段",
+		"date":        "2024-05-01",
+	}
+	for key, value := range want {
+		if got := yamlText(values[key]); got != value {
+			t.Fatalf("%s = %q, want %q", key, got, value)
+		}
+	}
+
+	if values, bodyStart, err := parseMarkdownHeader([]string{"---", "title: 未闭合"}); values != nil || bodyStart != 0 || err != nil {
+		t.Fatalf("unterminated header should be treated as body, got %v %d %v", values, bodyStart, err)
+	}
+	if _, _, err := parseMarkdownHeader([]string{"---", "title: [未闭合", "---"}); err == nil {
+		t.Fatal("expected invalid yaml header to fail")
+	}
+}
+
+func TestParseBookMarkdown(t *testing.T) {
+	isolateAutoRuleConfigDiscovery(t)
+
+	dir := t.TempDir()
+	writeTestPNG(t, filepath.Join(dir, "images", "map.png"))
+	content := strings.Join([]string{
+		"---",
+		"title: 长夜",
+		"author: 佚名",
+		"lang: zh-TW",
+		"cover: images/map.png",
+		"---",
+		"写在前面的话。",
+		"",
+		"# 第一卷 启程",
+		"",
+		"卷首语。",
+		"",
+		"## 第一章 出发",
+		"",
+		"他说：*走吧*。",
+		"",
+		"> 引用的信。",
+		"",
+		"- 干粮",
+		"- 地图",
+		"",
+		"![地图](images/map.png)",
+		"",
+		"---",
+		"",
+		"<script>alert(1)</script>",
+		"",
+		"### 小节",
+		"",
+		"见[附录](appendix.md)与[官网](https://example.com)。",
+		"",
+		"## 第二章 夜路",
+		"",
+		"![旧照片](images/missing.jpg) 与 ![地图](images/map.png)",
+		"",
+		"# 第二卷 归来",
+		"",
+		"## 第三章 回家",
+		"",
+		"结束。",
+	}, "\n")
+	mdPath := filepath.Join(dir, "book.md")
+	if err := os.WriteFile(mdPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write markdown: %v", err)
+	}
+
+	book := &Book{Filename: mdPath}
+	parsed, err := ParseBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("parse book: %v", err)
+	}
+	if book.InputFormat != inputFormatMarkdown {
+		t.Fatalf("expected format from extension, got %s", book.InputFormat)
+	}
+	if parsed.Name != "长夜" || parsed.Author != "佚名" {
+		t.Fatalf("unexpected metadata: %q %q", parsed.Name, parsed.Author)
+	}
+	if parsed.Metadata.Lang != "zh-TW" || parsed.Metadata.Cover != filepath.Join(dir, "images", "map.png") {
+		t.Fatalf("unexpected header metadata: %+v", parsed.Metadata)
+	}
+
+	if len(parsed.Volumes) != 3 || parsed.Volumes[0].Chapters[0].Title != "前言" ||
+		parsed.Volumes[1].Title != "第一卷 启程" || parsed.Volumes[2].Title != "第二卷 归来" {
+		t.Fatalf("unexpected volumes: %+v", parsed.Outline())
+	}
+	if volume := parsed.Volumes[1]; volume.Line != 9 || len(volume.Chapters) != 2 || volume.Chapters[1].Line != 32 {
+		t.Fatalf("unexpected lines: %+v", parsed.Outline())
+	}
+	if number := parsed.Volumes[2].Chapters[0].Number; number != 3 {
+		t.Fatalf("expected chapter number from the title, got %d", number)
+	}
+	if len(parsed.Report.DiscardedLines) != 1 || parsed.Report.DiscardedLines[0].Line != 11 {
+		t.Fatalf("expected the text between volume and chapter to be discarded: %+v", parsed.Report.DiscardedLines)
+	}
+
+	first := parsed.Volumes[1].Chapters[0].Content.String()
+	for _, want := range []string{
+		"<em>走吧</em>",
+		"<blockquote>",
+		"<li>干粮</li>",
+		`<img src="../images/inline001.png" alt="地图" />`,
+		SceneBreak,
+		"<h3",
+		"见附录与",
+		`<a href="https://example.com">官网</a>`,
+	} {
+		if !strings.Contains(first, want) {
+			t.Fatalf("expected %q in chapter: %s", want, first)
+		}
+	}
+	if strings.Contains(first, "script") || strings.Contains(first, "appendix.md") {
+		t.Fatalf("raw html and local links should be dropped: %s", first)
+	}
+	second := parsed.Volumes[1].Chapters[1].Content.String()
+	if !strings.Contains(second, "旧照片 与 <img") {
+		t.Fatalf("expected alt text for the missing image: %s", second)
+	}
+	if len(parsed.Images) != 1 {
+		t.Fatalf("expected the same image to be packed once: %+v", parsed.Images)
+	}
+	if missing := parsed.Report.MissingImages; len(missing) != 1 || missing[0].Line != 34 || missing[0].Text != "images/missing.jpg" {
+		t.Fatalf("unexpected missing images: %+v", missing)
+	}
+
+	// 显式指定格式和 front_matter 时按指定的处理。
+	txtPath := filepath.Join(dir, "book.txt")
+	if err := os.WriteFile(txtPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write txt: %v", err)
+	}
+	book = &Book{Filename: txtPath, InputFormat: "md", FrontMatter: frontMatterIntro}
+	if parsed, err = ParseBook(context.Background(), book); err != nil {
+		t.Fatalf("parse book with input format: %v", err)
+	}
+	if parsed.Intro != "写在前面的话。" || len(parsed.Volumes) != 2 {
+		t.Fatalf("expected front matter in intro: %q %d", parsed.Intro, len(parsed.Volumes))
+	}
+
+	if _, err := ParseBook(context.Background(), &Book{Filename: txtPath, InputFormat: "docx"}); err == nil {
+		t.Fatal("expected unsupported input format to fail")
+	}
+}
+
+func TestParseBookMarkdownFrontMatterTitleFollowsHeaderLang(t *testing.T) {
+	isolateAutoRuleConfigDiscovery(t)
+
+	content := strings.Join([]string{
+		"---",
+		"lang: en",
+		"---",
+		"一些写在前面的话。",
+		"",
+		"## 第一章 出发",
+		"",
+		"正文。",
+	}, "\n")
+	mdPath := filepath.Join(t.TempDir(), "book.md")
+	if err := os.WriteFile(mdPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write markdown: %v", err)
+	}
+
+	for _, tc := range []struct {
+		lang string
+		want string
+	}{
+		{"", "Preface"},
+		// 调用方指定的语言优先于头信息。
+		{"zh-CN", "前言"},
+	} {
+		book := &Book{Filename: mdPath, Lang: tc.lang}
+		parsed, err := ParseBook(context.Background(), book)
+		if err != nil {
+			t.Fatalf("parse book: %v", err)
+		}
+		if title := parsed.Volumes[0].Chapters[0].Title; title != tc.want {
+			t.Fatalf("lang %q: expected front matter title %q, got %q", tc.lang, tc.want, title)
+		}
+	}
+}
+
+func TestParseBookMarkdownRejectsFilters(t *testing.T) {
+	isolateAutoRuleConfigDiscovery(t)
+
+	mdPath := filepath.Join(t.TempDir(), "book.md")
+	if err := os.WriteFile(mdPath, []byte("## 第一章 出发\n\n正文。\n"), 0o644); err != nil {
+		t.Fatalf("write markdown: %v", err)
+	}
+	filter := ChapterFilterFunc(func(ctx context.Context, chapter *ChapterText) error { return nil })
+	book := &Book{Filename: mdPath, ChapterFilters: []ChapterFilter{filter}}
+	if _, err := ParseBook(context.Background(), book); err == nil || !strings.Contains(err.Error(), "过滤器") {
+		t.Fatalf("expected markdown with filters to fail, got %v", err)
+	}
+}
+
+func TestParseBookMarkdownImagesStayInDir(t *testing.T) {
+	isolateAutoRuleConfigDiscovery(t)
+
+	root := t.TempDir()
+	secret := filepath.Join(root, "secret.png")
+	writeTestPNG(t, secret)
+	dir := filepath.Join(root, "book")
+	writeTestPNG(t, filepath.Join(dir, "images", "map.png"))
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not an image"), 0o644); err != nil {
+		t.Fatalf("write notes: %v", err)
+	}
+	content := strings.Join([]string{
+		"## 第一章 出发",
+		"",
+		"![地图](images/map.png)",
+		"",
+		"![绝对路径](" + filepath.ToSlash(secret) + ")",
+		"",
+		"![上级目录](../secret.png)",
+		"",
+		"![笔记](notes.txt)",
+	}, "\n")
+	mdPath := filepath.Join(dir, "book.md")
+	if err := os.WriteFile(mdPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write markdown: %v", err)
+	}
+
+	parsed, err := ParseBook(context.Background(), &Book{Filename: mdPath})
+	if err != nil {
+		t.Fatalf("parse book: %v", err)
+	}
+	if len(parsed.Images) != 1 || parsed.Images[0].Source != filepath.Join(dir, "images", "map.png") {
+		t.Fatalf("only the image inside the markdown directory should be packed: %+v", parsed.Images)
+	}
+	missing := parsed.Report.MissingImages
+	if len(missing) != 3 || missing[0].Text != filepath.ToSlash(secret) || missing[1].Text != "../secret.png" || missing[2].Text != "notes.txt" {
+		t.Fatalf("unexpected missing images: %+v", missing)
+	}
+
+	parsed, err = ParseBook(context.Background(), &Book{Filename: mdPath, DisableImageLookup: true})
+	if err != nil {
+		t.Fatalf("parse book: %v", err)
+	}
+	if len(parsed.Images) != 0 || len(parsed.Report.MissingImages) != 4 {
+		t.Fatalf("image lookup should be disabled: %+v %+v", parsed.Images, parsed.Report.MissingImages)
+	}
+}
+
+func TestEPUBConverterConvertMarkdownPacksImages(t *testing.T) {
+	isolateAutoRuleConfigDiscovery(t)
+
+	dir := t.TempDir()
+	writeTestPNG(t, filepath.Join(dir, "cover.png"))
+	writeTestPNG(t, filepath.Join(dir, "fig.png"))
+	content := "---\ntitle: Night Walk\nlang: en\ncover: cover.png\n---\n# Night Walk\n\n## One\n\n![fig](fig.png)\n\n## Two\n\nThe end.\n"
+	mdPath := filepath.Join(dir, "book.md")
+	if err := os.WriteFile(mdPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write markdown: %v", err)
+	}
+	outputPath := filepath.Join(dir, "book.epub")
+	book := &Book{Filename: mdPath, Output: outputPath}
+	if err := NewEPUBConverter().Convert(context.Background(), book); err != nil {
+		t.Fatalf("convert: %v", err)
+	}
+	if book.Lang != "en" || book.Name != "Night Walk" {
+		t.Fatalf("expected metadata from the header: lang=%s name=%s", book.Lang, book.Name)
+	}
+
+	reader, err := zip.OpenReader(outputPath)
+	if err != nil {
+		t.Fatalf("open epub: %v", err)
+	}
+	defer reader.Close()
+
+	files := make(map[string]bool)
+	var opf string
+	for _, file := range reader.File {
+		files[filepath.Base(file.Name)] = true
+		if !strings.HasSuffix(file.Name, ".opf") {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", file.Name, err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("read %s: %v", file.Name, err)
+		}
+		opf = string(data)
+	}
+	if !files["inline001.png"] || !files["cover.png"] {
+		t.Fatalf("expected inline image and cover to be packed: %v", files)
+	}
+	if !strings.Contains(opf, "<dc:language>en</dc:language>") {
+		t.Fatalf("expected language from the header: %s", opf)
+	}
+}
+
+// writeTestPNG 写出一张 1x1 的 PNG 图片。
+func writeTestPNG(t *testing.T, path string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("create image dir: %v", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create image: %v", err)
+	}
+	defer file.Close()
+	if err := png.Encode(file, image.NewRGBA(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatalf("encode image: %v", err)
+	}
+}
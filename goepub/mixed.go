@@ -0,0 +1,236 @@
+package goepub
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+const (
+	// mojibakeCheckBelow 是触发乱码还原的置信度上限，正常中文行的得分通常高于它。
+	mojibakeCheckBelow = 0.7
+	// mojibakeMinConfidence 和 mojibakeMinGain 是接受还原结果的最低得分和最少提升，
+	// 避免把本来就少见的用字误当成乱码。
+	mojibakeMinConfidence = 0.6
+	mojibakeMinGain       = 0.3
+	// mojibakeMaxLossRatio 要求还原出的非 ASCII 字符至少是丢失位置的这么多倍。
+	mojibakeMaxLossRatio = 3
+)
+
+// EncodingRepair 记录 auto 模式下与主编码不同、被单独解码或还原的一段连续文本。
+type EncodingRepair struct {
+	StartLine int `json:"start_line"`
+	EndLine   int `json:"end_line"`
+	// Encoding 是这一段实际使用的编码；乱码还原时是当初被误用来解码的编码。
+	Encoding string `json:"encoding"`
+	// Mojibake 表示这一段原本是 UTF-8 文本被误按 Encoding 解码后保存下来的乱码，已反向还原。
+	Mojibake bool `json:"mojibake"`
+	// Sample 是这一段修复后的第一行，便于定位。
+	Sample string `json:"sample"`
+}
+
+// segmentDecoder 按行解码混合编码的文本。
+// 合集类 TXT 经常把 UTF-8 和 GBK 的章节拼在同一个文件里，整份文件只用一种编码解码
+// 要么直接失败，要么把其中一半变成乱码。逐行判断可以让每一段都用各自的编码解码，
+// 同时还原“UTF-8 被当作 GBK 读取后又保存”的经典乱码。
+// 换行符在这些编码中都不会出现在多字节字符内部，因此按 \n 和 \r 切分是安全的。
+type segmentDecoder struct {
+	scanner *bufio.Scanner
+	primary string
+	// validUTF8 表示整份文件都是有效的 UTF-8，此时只有带 Latin-1 区字符的行才可能是乱码。
+	validUTF8 bool
+	pending   []byte
+	lineNo    int
+	done      bool
+
+	repairs []EncodingRepair
+	// interrupted 表示最近一段修复之后出现过按主编码正常解码的非 ASCII 行，
+	// 之后的修复需要另起一段。
+	interrupted bool
+}
+
+func newSegmentDecoder(r io.Reader, primary string) *segmentDecoder {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxScannerTokenSize)
+	scanner.Split(scanRawLines)
+	return &segmentDecoder{scanner: scanner, primary: primary}
+}
+
+// Read 实现 io.Reader，每次解码一整行。
+func (d *segmentDecoder) Read(p []byte) (int, error) {
+	for len(d.pending) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if !d.scanner.Scan() {
+			d.done = true
+			if err := d.scanner.Err(); err != nil {
+				return 0, err
+			}
+			return 0, io.EOF
+		}
+		d.lineNo++
+		d.pending = d.decodeLine(d.scanner.Bytes())
+	}
+	n := copy(p, d.pending)
+	d.pending = d.pending[n:]
+	return n, nil
+}
+
+// decodeLine 解码一行，返回 UTF-8 字节并记录修复情况。换行符原样保留，交给解析器统一处理。
+func (d *segmentDecoder) decodeLine(line []byte) []byte {
+	content := bytes.TrimRight(line, "\r\n")
+	newline := line[len(content):]
+	if isASCII(content) {
+		return line
+	}
+
+	text, encoding := d.decodeContent(content)
+	mojibake := false
+	if encoding == encodingUTF8 && d.mayBeMojibake(text) {
+		if restored, ok := restoreMojibake(text); ok {
+			text, encoding, mojibake = restored, encodingGB18030, true
+		}
+	}
+	if encoding == d.primary && !mojibake {
+		d.interrupted = true
+	} else {
+		d.recordRepair(encoding, mojibake, text)
+	}
+	// 解析器还会把 U+2028、U+2029 当作换行，行号要与之保持一致。
+	d.lineNo += strings.Count(text, string(lineSeparator)) + strings.Count(text, string(paragraphSeparator))
+	return append([]byte(text), newline...)
+}
+
+// mayBeMojibake 判断一行按 UTF-8 解码的文本是否值得尝试还原乱码。
+// 文件本身混有其他编码时每行都要检查；整份文件都是有效 UTF-8 时，
+// 只检查带有乱码常见的 Latin-1 区字符的行，避免逐行给正常正文评分。
+func (d *segmentDecoder) mayBeMojibake(text string) bool {
+	if !d.validUTF8 {
+		return true
+	}
+	for _, r := range text {
+		if r >= 0x80 && r <= 0xff {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeContent 为一行选择编码：有效 UTF-8 与主编码之间取得分高的一方，
+// 其余情况先用主编码，解码出错时再在常见多字节编码中择优。
+func (d *segmentDecoder) decodeContent(content []byte) (string, string) {
+	if utf8.Valid(content) {
+		if d.primary == encodingUTF8 {
+			return string(content), encodingUTF8
+		}
+		// 很短的 GBK 行偶尔也是合法的 UTF-8，只有 UTF-8 得分更高时才按 UTF-8 处理。
+		decoded := decodeLegacy(content, d.primary)
+		utf8Score, _ := scoreText(string(content))
+		primaryScore, invalid := scoreText(decoded)
+		if invalid == 0 && primaryScore >= utf8Score {
+			return decoded, d.primary
+		}
+		return string(content), encodingUTF8
+	}
+
+	if d.primary != encodingUTF8 {
+		if decoded := decodeLegacy(content, d.primary); !strings.ContainsRune(decoded, utf8.RuneError) {
+			return decoded, d.primary
+		}
+	}
+	best, bestEncoding, bestScore := "", "", -1.0
+	for _, name := range legacyEncodingCandidates {
+		decoded := decodeLegacy(content, name)
+		if score, _ := scoreText(decoded); score > bestScore {
+			best, bestEncoding, bestScore = decoded, name, score
+		}
+	}
+	return best, bestEncoding
+}
+
+// recordRepair 把修复过的行并入上一段，或开始新的一段。
+// 夹在中间的纯 ASCII 行（例如空行）不会打断一段修复。
+func (d *segmentDecoder) recordRepair(encoding string, mojibake bool, text string) {
+	if n := len(d.repairs); n > 0 && !d.interrupted {
+		last := &d.repairs[n-1]
+		if last.Encoding == encoding && last.Mojibake == mojibake {
+			last.EndLine = d.lineNo
+			return
+		}
+	}
+	d.repairs = append(d.repairs, EncodingRepair{
+		StartLine: d.lineNo,
+		EndLine:   d.lineNo,
+		Encoding:  encoding,
+		Mojibake:  mojibake,
+		Sample:    strings.TrimSpace(text),
+	})
+	d.interrupted = false
+}
+
+// decodeLegacy 用多字节编码解码一行，无效字节替换为 U+FFFD。
+func decodeLegacy(content []byte, name string) string {
+	decoded, err := textEncoding(name).NewDecoder().Bytes(content)
+	if err != nil {
+		return string(utf8.RuneError)
+	}
+	return string(decoded)
+}
+
+// restoreMojibake 尝试还原“UTF-8 字节被按 GBK 解码”产生的乱码：
+// 把文本重新编码回 GBK 得到原始字节，再按 UTF-8 解读。
+// 乱码中已经变成 U+FFFD 的字节无法找回，还原结果在对应位置保留一个替换字符，
+// 评分时不计入这些位置，但丢失过多时放弃还原。
+func restoreMojibake(text string) (string, bool) {
+	current, _ := scoreText(text)
+	if current >= mojibakeCheckBelow {
+		return "", false
+	}
+
+	var raw strings.Builder
+	for _, part := range strings.Split(text, string(utf8.RuneError)) {
+		// GBK 与 GB18030 对单字节 0x80（€）的编码不同，优先按 Windows 常用的 GBK 还原。
+		encoded, err := simplifiedchinese.GBK.NewEncoder().String(part)
+		if err != nil {
+			if encoded, err = simplifiedchinese.GB18030.NewEncoder().String(part); err != nil {
+				return "", false
+			}
+		}
+		raw.WriteString(encoded)
+	}
+	restored := strings.ToValidUTF8(raw.String(), string(utf8.RuneError))
+	if restored == text {
+		return "", false
+	}
+
+	clean := strings.ReplaceAll(restored, string(utf8.RuneError), "")
+	lost := strings.Count(restored, string(utf8.RuneError))
+	kept := 0
+	for _, r := range clean {
+		if r >= utf8.RuneSelf {
+			kept++
+		}
+	}
+	if kept == 0 || kept < lost*mojibakeMaxLossRatio {
+		return "", false
+	}
+	score, _ := scoreText(clean)
+	if score < mojibakeMinConfidence || score-current < mojibakeMinGain {
+		return "", false
+	}
+	return restored, true
+}
+
+func isASCII(content []byte) bool {
+	for _, b := range content {
+		if b >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
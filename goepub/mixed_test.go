@@ -0,0 +1,124 @@
+package goepub
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/transform"
+)
+
+func TestOpenTextReaderRepairsMixedEncodings(t *testing.T) {
+	utf8Part := "第一章 开始\n　　这是一段用 UTF-8 保存的正文。\n\n"
+	gbkPart := "第二章 继续\n　　后面的章节来自另一份 GBK 文件。\n"
+	gbk, _, err := transform.String(simplifiedchinese.GBK.NewEncoder(), gbkPart)
+	if err != nil {
+		t.Fatalf("encode gbk: %v", err)
+	}
+
+	for i, raw := range []string{utf8Part + gbk, gbk + utf8Part} {
+		txtPath := filepath.Join(t.TempDir(), fmt.Sprintf("mixed-%d.txt", i))
+		if err := os.WriteFile(txtPath, []byte(raw), 0o644); err != nil {
+			t.Fatalf("write txt: %v", err)
+		}
+		decoded, _ := readDetectedText(t, txtPath, "")
+		if !strings.Contains(decoded, utf8Part) || !strings.Contains(decoded, gbkPart) {
+			t.Fatalf("mixed content should be decoded per segment: %q", decoded)
+		}
+	}
+}
+
+func TestOpenTextReaderSkipsMojibakeCheckForValidUTF8(t *testing.T) {
+	// 这一行看起来像乱码，但整份文件是有效的 UTF-8 且不含 Latin-1 区字符，应当原样保留。
+	garbled, _, err := transform.String(simplifiedchinese.GBK.NewDecoder(), "　　这一行曾经被错误地按照国标编码读取过。")
+	if err != nil {
+		t.Fatalf("make mojibake: %v", err)
+	}
+	source := "第一章 开始\n" + garbled + "\n"
+	txtPath := filepath.Join(t.TempDir(), "utf8.txt")
+	if err := os.WriteFile(txtPath, []byte(source), 0o644); err != nil {
+		t.Fatalf("write txt: %v", err)
+	}
+
+	decoded, detection := readDetectedText(t, txtPath, "")
+	if decoded != source || len(detection.segments.repairs) != 0 {
+		t.Fatalf("valid utf-8 lines should not be repaired: %q %+v", decoded, detection.segments.repairs)
+	}
+}
+
+func TestRestoreMojibake(t *testing.T) {
+	original := "　　他说：“我们明天再见吧。”然后转身离开了。"
+	garbled, _, err := transform.String(simplifiedchinese.GBK.NewDecoder(), original)
+	if err != nil {
+		t.Fatalf("make mojibake: %v", err)
+	}
+
+	// 奇数字节处的 UTF-8 片段在误解码时已经丢失，只能还原其余部分。
+	restored, ok := restoreMojibake(garbled)
+	if !ok || !strings.HasPrefix(restored, "　　他") || !strings.Contains(restored, "天再见吧。”然后转") {
+		t.Fatalf("expected mojibake to be restored, got %q (%v) from %q", restored, ok, garbled)
+	}
+	for _, text := range []string{original, "　　魑魅魍魉，饕餮鸱鸮。", "안녕하세요 여러분"} {
+		if _, ok := restoreMojibake(text); ok {
+			t.Fatalf("normal text should not be treated as mojibake: %q", text)
+		}
+	}
+}
+
+func TestParseBookReportsEncodingRepairs(t *testing.T) {
+	isolateAutoRuleConfigDiscovery(t)
+
+	garbled, _, err := transform.String(simplifiedchinese.GBK.NewDecoder(), "　　这一行曾经被错误地按照国标编码读取过。")
+	if err != nil {
+		t.Fatalf("make mojibake: %v", err)
+	}
+	gbk, _, err := transform.String(simplifiedchinese.GBK.NewEncoder(), "第三章 合集\n　　这一章来自国标编码的文件。\n\n　　同一章的第二段。\n")
+	if err != nil {
+		t.Fatalf("encode gbk: %v", err)
+	}
+	content := strings.Join([]string{
+		"混合编码",
+		"第一章 开始",
+		"　　这是一段用 UTF-8 保存的正常正文，内容比后面拼接进来的章节更长一些。",
+		"　　第二段正文同样是 UTF-8，合集文件的主体通常就是这样的编码。",
+		"第二章 乱码",
+		garbled,
+		gbk,
+	}, "\n")
+
+	txtPath := filepath.Join(t.TempDir(), "mixed.txt")
+	if err := os.WriteFile(txtPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write txt: %v", err)
+	}
+	parsed, err := ParseBook(context.Background(), &Book{Filename: txtPath})
+	if err != nil {
+		t.Fatalf("parse book: %v", err)
+	}
+
+	if parsed.Report.Encoding != encodingUTF8 {
+		t.Fatalf("expected utf-8 as primary encoding, got %s", parsed.Report.Encoding)
+	}
+	repairs := parsed.Report.EncodingRepairs
+	if len(repairs) != 2 {
+		t.Fatalf("unexpected repairs: %+v", repairs)
+	}
+	if mojibake := repairs[0]; mojibake.StartLine != 6 || mojibake.EndLine != 6 || !mojibake.Mojibake || !strings.HasPrefix(mojibake.Sample, "这一行") {
+		t.Fatalf("unexpected mojibake repair: %+v", mojibake)
+	}
+	want := EncodingRepair{StartLine: 7, EndLine: 10, Encoding: encodingGB18030, Sample: "第三章 合集"}
+	if repairs[1] != want {
+		t.Fatalf("unexpected mixed encoding repair: %+v", repairs[1])
+	}
+
+	chapters := parsed.Volumes[0].Chapters
+	if len(chapters) != 3 || chapters[2].Title != "第三章 合集" {
+		t.Fatalf("unexpected chapters: %+v", chapters)
+	}
+	if !strings.Contains(chapters[1].Content.String(), "按照国标编码") {
+		t.Fatalf("mojibake should be restored: %s", chapters[1].Content.String())
+	}
+}
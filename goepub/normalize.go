@@ -0,0 +1,180 @@
+package goepub
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	lineSeparator      = '\u2028'
+	paragraphSeparator = '\u2029'
+)
+
+// TextFixes 统计文本规范化阶段所做的修正。
+// 规范化发生在行过滤器之前，统一换行符并清理 XHTML 中不允许出现的字符，
+// 避免严格的阅读器拒绝打开生成的 EPUB。
+type TextFixes struct {
+	// CRLineBreaks 是旧版 Mac 格式中单独作为换行的 \r。
+	CRLineBreaks int `json:"cr_line_breaks"`
+	// CRLFLineBreaks 是 Windows 格式的 \r\n 换行。
+	CRLFLineBreaks int `json:"crlf_line_breaks"`
+	// UnicodeLineBreaks 是 U+2028 行分隔符和 U+2029 段分隔符。
+	UnicodeLineBreaks int `json:"unicode_line_breaks"`
+	// InvalidXMLChars 是被删除的 XML 1.0 不允许的控制字符以及 U+FFFE、U+FFFF。
+	InvalidXMLChars int `json:"invalid_xml_chars"`
+	// InvalidUTF8 是被替换为 U+FFFD 的无效 UTF-8 字节序列，例如孤立的代理项。
+	InvalidUTF8 int `json:"invalid_utf8"`
+	// ZeroWidthChars 是被删除的零宽字符。
+	ZeroWidthChars int `json:"zero_width_chars"`
+}
+
+// Total 返回修正的总数。
+func (f TextFixes) Total() int {
+	return f.CRLineBreaks + f.CRLFLineBreaks + f.UnicodeLineBreaks + f.InvalidXMLChars + f.InvalidUTF8 + f.ZeroWidthChars
+}
+
+// indexLineBreak 返回 data 中第一个 \r 或 \n 的位置。
+func indexLineBreak(data []byte) int {
+	cr, lf := bytes.IndexByte(data, '\r'), bytes.IndexByte(data, '\n')
+	if cr < 0 || (lf >= 0 && lf < cr) {
+		return lf
+	}
+	return cr
+}
+
+// scanRawLines 是按 \n、\r\n 或单独的 \r 切分字节流的 bufio.SplitFunc，返回的行保留换行符。
+// 它用于尚未解码的字节流，\r 和 \n 在支持的多字节编码中都不会出现在字符内部。
+func scanRawLines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := indexLineBreak(data); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i+1], nil
+		}
+		if i+1 == len(data) && !atEOF {
+			// 还不知道 \r 后面是不是 \n，需要更多数据。
+			return 0, nil, nil
+		}
+		if i+1 < len(data) && data[i+1] == '\n' {
+			return i + 2, data[:i+2], nil
+		}
+		return i + 1, data[:i+1], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// lineBreakCounter 为已解码的文本提供通用换行的 bufio.SplitFunc，
+// 把 \n、\r\n、\r、U+2028 和 U+2029 都当作换行，返回的行不含换行符。
+type lineBreakCounter struct {
+	fixes *TextFixes
+}
+
+func (c lineBreakCounter) split(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	for i := 0; i < len(data); {
+		switch data[i] {
+		case '\n':
+			return i + 1, data[:i], nil
+		case '\r':
+			if i+1 == len(data) && !atEOF {
+				return 0, nil, nil
+			}
+			if i+1 < len(data) && data[i+1] == '\n' {
+				c.fixes.CRLFLineBreaks++
+				return i + 2, data[:i], nil
+			}
+			c.fixes.CRLineBreaks++
+			return i + 1, data[:i], nil
+		}
+		if data[i] < utf8.RuneSelf {
+			i++
+			continue
+		}
+		if !utf8.FullRune(data[i:]) && !atEOF {
+			return 0, nil, nil
+		}
+		r, size := utf8.DecodeRune(data[i:])
+		if r == lineSeparator || r == paragraphSeparator {
+			c.fixes.UnicodeLineBreaks++
+			return i + size, data[:i], nil
+		}
+		i += size
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// universalLineBreaks 把各种换行统一为 \n。
+var universalLineBreaks = strings.NewReplacer("\r\n", "\n", "\r", "\n", string(lineSeparator), "\n", string(paragraphSeparator), "\n")
+
+// splitUniversalLines 按与解析器相同的通用换行规则切分一段文本。
+func splitUniversalLines(text string) []string {
+	return strings.Split(universalLineBreaks.Replace(text), "\n")
+}
+
+// sanitizeLine 删除 XML 1.0 不允许的字符和零宽字符，把无效的 UTF-8 替换为 U+FFFD。
+// 行内没有需要处理的字符时直接返回原字符串，不额外分配内存。
+func sanitizeLine(line string, fixes *TextFixes) string {
+	clean := true
+	for _, r := range line {
+		if r == utf8.RuneError || isInvalidXMLRune(r) || isZeroWidthRune(r) {
+			clean = false
+			break
+		}
+	}
+	if clean {
+		return line
+	}
+
+	var b strings.Builder
+	b.Grow(len(line))
+	for i := 0; i < len(line); {
+		r, size := utf8.DecodeRuneInString(line[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			fixes.InvalidUTF8++
+			b.WriteRune(utf8.RuneError)
+		case isInvalidXMLRune(r):
+			fixes.InvalidXMLChars++
+		case isZeroWidthRune(r):
+			fixes.ZeroWidthChars++
+		default:
+			b.WriteString(line[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// isInvalidXMLRune 判断字符是否不在 XML 1.0 允许的字符范围内。
+// 制表符、换行和回车是允许的；代理项无法出现在合法的 UTF-8 中，解码时已作为无效字节处理。
+func isInvalidXMLRune(r rune) bool {
+	switch {
+	case r == '\t' || r == '\n' || r == '\r':
+		return false
+	case r < 0x20:
+		return true
+	default:
+		return r == 0xFFFE || r == 0xFFFF
+	}
+}
+
+// isZeroWidthRune 判断是否为零宽空格、词连接符或夹在正文中的 BOM。
+// 它们常被用作网站防复制的水印，会打断章节标题和替换规则的匹配。
+// 零宽连接符和零宽非连接符（U+200D、U+200C）要保留，组合 emoji 和部分文字的排版依赖它们。
+func isZeroWidthRune(r rune) bool {
+	switch r {
+	case '\u200B', '\u2060', '\uFEFF':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package goepub
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/transform"
+)
+
+func TestParseBookHandlesUniversalNewlines(t *testing.T) {
+	isolateAutoRuleConfigDiscovery(t)
+
+	body := strings.Repeat("　　旧版 Mac 格式的正文只用回车换行。", 20)
+	lines := []string{"换行测试", "第一章 开始", body, "第二章 继续", body, "第三章 结束", "　　最后一段。"}
+	content := strings.Join(lines[:3], "\r") + "\r\n" + lines[3] + "\u2028" + lines[4] + "\u2029" + strings.Join(lines[5:], "\n")
+	// 单独用 \r 换行的大文件也不能被当作一整行。
+	content += "\r" + strings.Repeat(body+"\r", maxScannerTokenSize/len(body)+1)
+
+	txtPath := filepath.Join(t.TempDir(), "newline.txt")
+	if err := os.WriteFile(txtPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write txt: %v", err)
+	}
+	parsed, err := ParseBook(context.Background(), &Book{Filename: txtPath})
+	if err != nil {
+		t.Fatalf("parse book: %v", err)
+	}
+
+	chapters := parsed.Volumes[0].Chapters
+	if parsed.Name != "换行测试" || len(chapters) != 3 || chapters[1].Title != "第二章 继续" || chapters[1].Line != 4 || chapters[2].Line != 6 {
+		t.Fatalf("unexpected chapters: %s %+v", parsed.Name, chapters)
+	}
+	fixes := parsed.Report.TextFixes
+	if fixes.CRLineBreaks < maxScannerTokenSize/len(body) || fixes.CRLFLineBreaks != 1 || fixes.UnicodeLineBreaks != 2 {
+		t.Fatalf("unexpected line break fixes: %+v", fixes)
+	}
+}
+
+func TestSanitizeLineRemovesInvalidXMLAndZeroWidthCharacters(t *testing.T) {
+	var fixes TextFixes
+	got := sanitizeLine("第\u200B一章\x00 开\x1b始\uFFFE\uFFFF\uFEFF\xed\xa0\x80。\t", &fixes)
+	if got != "第一章 开始\uFFFD\uFFFD\uFFFD。\t" {
+		t.Fatalf("unexpected sanitized line: %q", got)
+	}
+	want := TextFixes{InvalidXMLChars: 4, InvalidUTF8: 3, ZeroWidthChars: 2}
+	if fixes != want || fixes.Total() != 9 {
+		t.Fatalf("unexpected fixes: %+v", fixes)
+	}
+
+	clean := "　　正常的一行，保留 U+FFFD：\uFFFD，以及组合 emoji 👨\u200D👩\u200D👧 和 می\u200Cخواهم"
+	if got := sanitizeLine(clean, &fixes); got != clean || fixes != want {
+		t.Fatalf("clean line should be unchanged: %q %+v", got, fixes)
+	}
+}
+
+func TestSegmentDecoderSplitsCarriageReturnLines(t *testing.T) {
+	gbk, _, err := transform.String(simplifiedchinese.GBK.NewEncoder(), "第二章 继续\r　　国标编码的正文。\r")
+	if err != nil {
+		t.Fatalf("encode gbk: %v", err)
+	}
+	decoder := newSegmentDecoder(strings.NewReader("第一章 开始\r　　UTF-8 正文。\r"+gbk), encodingUTF8)
+	var decoded strings.Builder
+	buf := make([]byte, 7)
+	for {
+		n, err := decoder.Read(buf)
+		decoded.Write(buf[:n])
+		if err != nil {
+			break
+		}
+	}
+
+	if decoded.String() != "第一章 开始\r　　UTF-8 正文。\r第二章 继续\r　　国标编码的正文。\r" {
+		t.Fatalf("unexpected decoded text: %q", decoded.String())
+	}
+	if len(decoder.repairs) != 1 || decoder.repairs[0].StartLine != 3 || decoder.repairs[0].EndLine != 4 {
+		t.Fatalf("unexpected repairs: %+v", decoder.repairs)
+	}
+}
@@ -0,0 +1,363 @@
+package goepub
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// defaultChapterNumberPattern 从“第一百二十三章”“第123章”这类标题中提取序号。
+const defaultChapterNumberPattern = `^第\s*([零〇一二两三四五六七八九十百千万亿壹贰叁肆伍陆柒捌玖拾佰仟0-9０-９]+)\s*[章回节]`
+
+// 章节序号检查策略。
+const (
+	numberingPolicyOff  = "off"
+	numberingPolicyWarn = "warn"
+	numberingPolicyFix  = "fix"
+)
+
+// 章节序号问题类型。
+const (
+	NumberingIssueMissing    = "missing"
+	NumberingIssueDuplicate  = "duplicate"
+	NumberingIssueOutOfOrder = "out_of_order"
+)
+
+// NumberingIssue 描述章节序号检查发现的一处问题。
+type NumberingIssue struct {
+	// Kind 是问题类型：missing、duplicate、out_of_order。
+	Kind string `json:"kind"`
+	// Number 是相关章节序号；缺号时为缺失区间的起始序号。
+	Number int `json:"number"`
+	// To 仅用于缺号，表示缺失区间的结束序号。
+	To int `json:"to,omitempty"`
+	// Title 和 Line 指向出问题的章节，缺号时为缺口之后的第一章。
+	Title string `json:"title,omitempty"`
+	Line  int    `json:"line,omitempty"`
+	// Fixed 表示该问题已按 fix 策略自动修正。
+	Fixed bool `json:"fixed"`
+}
+
+// String 返回适合日志和命令行报告的问题描述。
+func (i NumberingIssue) String() string {
+	switch i.Kind {
+	case NumberingIssueMissing:
+		if i.To > i.Number {
+			return fmt.Sprintf("缺少第 %d-%d 章", i.Number, i.To)
+		}
+		return fmt.Sprintf("缺少第 %d 章", i.Number)
+	case NumberingIssueDuplicate:
+		return fmt.Sprintf("第 %d 章重复: %s", i.Number, i.Title)
+	case NumberingIssueOutOfOrder:
+		return fmt.Sprintf("第 %d 章顺序错乱: %s", i.Number, i.Title)
+	default:
+		return i.Kind
+	}
+}
+
+func normalizeNumberingPolicy(value string, allowFix bool) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", numberingPolicyWarn:
+		return numberingPolicyWarn, nil
+	case numberingPolicyOff:
+		return numberingPolicyOff, nil
+	case numberingPolicyFix:
+		if allowFix {
+			return numberingPolicyFix, nil
+		}
+	}
+	return "", fmt.Errorf("不支持的策略: %s", value)
+}
+
+// ChapterNumber 使用章节序号正则从标题中解析序号。
+// 如果正则带有捕获组，则只解析第一个捕获组。
+func (r *ParseRules) ChapterNumber(title string) (int, bool) {
+	if r.ChapterNumberRegex == nil {
+		return 0, false
+	}
+	matches := r.ChapterNumberRegex.FindStringSubmatch(strings.TrimSpace(title))
+	if len(matches) == 0 {
+		return 0, false
+	}
+	value := matches[0]
+	if len(matches) > 1 {
+		value = matches[1]
+	}
+	value = strings.TrimSpace(value)
+	if number, ok := parseNumeral(value); ok {
+		return number, true
+	}
+	return parseLatinNumeral(value)
+}
+
+var numeralDigits = map[rune]int{
+	'零': 0, '〇': 0,
+	'一': 1, '壹': 1,
+	'二': 2, '两': 2, '贰': 2,
+	'三': 3, '叁': 3,
+	'四': 4, '肆': 4,
+	'五': 5, '伍': 5,
+	'六': 6, '陆': 6,
+	'七': 7, '柒': 7,
+	'八': 8, '捌': 8,
+	'九': 9, '玖': 9,
+}
+
+var numeralUnits = map[rune]int{
+	'十': 10, '拾': 10,
+	'百': 100, '佰': 100,
+	'千': 1000, '仟': 1000,
+}
+
+// parseNumeral 将中文数字、阿拉伯数字或全角数字解析为整数。
+// 同时支持“一百二十三”这种带单位的写法和“一二三”“二〇二三”这种逐位写法。
+func parseNumeral(value string) (int, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	hasUnit := false
+	for _, r := range value {
+		if _, ok := numeralUnits[r]; ok || r == '万' || r == '亿' {
+			hasUnit = true
+			break
+		}
+	}
+
+	if !hasUnit {
+		number := 0
+		for _, r := range value {
+			digit, ok := numeralDigit(r)
+			if !ok {
+				return 0, false
+			}
+			number = number*10 + digit
+		}
+		return number, true
+	}
+
+	total, section, digit := 0, 0, 0
+	for _, r := range value {
+		if d, ok := numeralDigit(r); ok {
+			digit = d
+			continue
+		}
+		if unit, ok := numeralUnits[r]; ok {
+			// “十二”省略了前面的“一”。
+			if digit == 0 && unit == 10 {
+				digit = 1
+			}
+			section += digit * unit
+			digit = 0
+			continue
+		}
+		switch r {
+		case '万':
+			total += (section + digit) * 10000
+		case '亿':
+			total = (total + section + digit) * 100000000
+		default:
+			return 0, false
+		}
+		section, digit = 0, 0
+	}
+	return total + section + digit, true
+}
+
+func numeralDigit(r rune) (int, bool) {
+	switch {
+	case r >= '0' && r <= '9':
+		return int(r - '0'), true
+	case r >= '０' && r <= '９':
+		return int(r - '０'), true
+	}
+	digit, ok := numeralDigits[r]
+	return digit, ok
+}
+
+// chapterRef 定位卷章树中的一个章节。
+type chapterRef struct {
+	volume  int
+	chapter int
+}
+
+// checkChapterNumbering 为章节填充序号，并按规则中的策略检查缺号、重复和乱序。
+// 若某卷的首个编号章节从 1 开始，视为编号在该卷重新计数，检查按段分别进行。
+// fix 策略下会删除序号和正文都相同的重复章节，并在卷内按序号重新排序。
+func checkChapterNumbering(parsed *ParsedBook, rules *ParseRules) {
+	for vi := range parsed.Volumes {
+		chapters := parsed.Volumes[vi].Chapters
+		for ci := range chapters {
+			if chapters[ci].Number == 0 {
+				chapters[ci].Number, _ = rules.ChapterNumber(chapters[ci].Title)
+			}
+		}
+	}
+
+	dropped := make(map[chapterRef]struct{})
+	sortVolumes := make(map[int]struct{})
+	var issues []NumberingIssue
+
+	for _, segment := range chapterNumberingSegments(parsed.Volumes) {
+		seen := make(map[int]chapterRef)
+		numbers := make([]int, 0)
+		maxNumber := 0
+
+		for _, vi := range segment {
+			volumeMax := 0
+			for ci := range parsed.Volumes[vi].Chapters {
+				chapter := &parsed.Volumes[vi].Chapters[ci]
+				if chapter.Number <= 0 {
+					continue
+				}
+				ref := chapterRef{volume: vi, chapter: ci}
+
+				if first, ok := seen[chapter.Number]; ok {
+					if rules.DuplicateChapterPolicy == numberingPolicyOff {
+						continue
+					}
+					issue := NumberingIssue{
+						Kind:   NumberingIssueDuplicate,
+						Number: chapter.Number,
+						Title:  chapter.Title,
+						Line:   chapter.Line,
+					}
+					original := &parsed.Volumes[first.volume].Chapters[first.chapter]
+					if rules.DuplicateChapterPolicy == numberingPolicyFix && isExactDuplicateChapter(original, chapter) {
+						dropped[ref] = struct{}{}
+						issue.Fixed = true
+					}
+					issues = append(issues, issue)
+					continue
+				}
+				seen[chapter.Number] = ref
+				numbers = append(numbers, chapter.Number)
+
+				if chapter.Number < maxNumber && rules.ChapterOrderPolicy != numberingPolicyOff {
+					issue := NumberingIssue{
+						Kind:   NumberingIssueOutOfOrder,
+						Number: chapter.Number,
+						Title:  chapter.Title,
+						Line:   chapter.Line,
+					}
+					// 排序只在卷内进行，跨卷的乱序只能提示。
+					if rules.ChapterOrderPolicy == numberingPolicyFix && chapter.Number < volumeMax {
+						sortVolumes[vi] = struct{}{}
+						issue.Fixed = true
+					}
+					issues = append(issues, issue)
+				}
+				maxNumber = max(maxNumber, chapter.Number)
+				volumeMax = max(volumeMax, chapter.Number)
+			}
+		}
+
+		if rules.MissingChapterPolicy != numberingPolicyOff {
+			issues = append(issues, missingChapterIssues(parsed.Volumes, seen, numbers)...)
+		}
+	}
+
+	for _, issue := range issues {
+		if issue.Fixed {
+			log.Printf("章节序号已修正: %s", issue)
+		} else {
+			log.Printf("章节序号警告: %s", issue)
+		}
+	}
+	parsed.Report.NumberingIssues = issues
+
+	for vi := range parsed.Volumes {
+		volume := &parsed.Volumes[vi]
+		if len(dropped) > 0 {
+			kept := make([]Chapter, 0, len(volume.Chapters))
+			for ci := range volume.Chapters {
+				if _, ok := dropped[chapterRef{volume: vi, chapter: ci}]; ok {
+					continue
+				}
+				kept = append(kept, volume.Chapters[ci])
+			}
+			volume.Chapters = kept
+		}
+		if _, ok := sortVolumes[vi]; ok {
+			sortNumberedChapters(volume.Chapters)
+		}
+	}
+}
+
+// chapterNumberingSegments 按编号是否重新计数把卷划分为若干检查段。
+func chapterNumberingSegments(volumes []Volume) [][]int {
+	var segments [][]int
+	var current []int
+	numbered := false
+
+	for vi, volume := range volumes {
+		first := 0
+		for _, chapter := range volume.Chapters {
+			if chapter.Number > 0 {
+				first = chapter.Number
+				break
+			}
+		}
+		if first == 1 && numbered {
+			segments = append(segments, current)
+			current = nil
+			numbered = false
+		}
+		current = append(current, vi)
+		if first > 0 {
+			numbered = true
+		}
+	}
+	if len(current) > 0 {
+		segments = append(segments, current)
+	}
+	return segments
+}
+
+// missingChapterIssues 找出一段编号中最小值到最大值之间缺失的区间。
+func missingChapterIssues(volumes []Volume, seen map[int]chapterRef, numbers []int) []NumberingIssue {
+	sorted := append([]int(nil), numbers...)
+	sort.Ints(sorted)
+
+	var issues []NumberingIssue
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i]-sorted[i-1] <= 1 {
+			continue
+		}
+		next := seen[sorted[i]]
+		chapter := &volumes[next.volume].Chapters[next.chapter]
+		issues = append(issues, NumberingIssue{
+			Kind:   NumberingIssueMissing,
+			Number: sorted[i-1] + 1,
+			To:     sorted[i] - 1,
+			Title:  chapter.Title,
+			Line:   chapter.Line,
+		})
+	}
+	return issues
+}
+
+// isExactDuplicateChapter 判断两个同序号章节的正文是否完全一致。
+func isExactDuplicateChapter(a, b *Chapter) bool {
+	return strings.TrimSpace(a.Content.String()) == strings.TrimSpace(b.Content.String())
+}
+
+// sortNumberedChapters 只调整带序号章节之间的相对顺序，
+// 没有序号的章节（如楔子、番外）保持在原来的位置。
+func sortNumberedChapters(chapters []Chapter) {
+	slots := make([]int, 0, len(chapters))
+	numbered := make([]Chapter, 0, len(chapters))
+	for ci := range chapters {
+		if chapters[ci].Number > 0 {
+			slots = append(slots, ci)
+			numbered = append(numbered, chapters[ci])
+		}
+	}
+	sort.SliceStable(numbered, func(i, j int) bool {
+		return numbered[i].Number < numbered[j].Number
+	})
+	for i, ci := range slots {
+		chapters[ci] = numbered[i]
+	}
+}
@@ -0,0 +1,183 @@
+package goepub
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseNumeral(t *testing.T) {
+	cases := map[string]int{
+		"123":     123,
+		"１２３":     123,
+		"十":       10,
+		"十二":      12,
+		"二十":      20,
+		"一百零五":    105,
+		"一百二十三":   123,
+		"两千零一":    2001,
+		"一万零三百":   10300,
+		"贰佰叁拾肆":   234,
+		"一二三":     123,
+		"二〇二三":    2023,
+		"三千五百二十一": 3521,
+	}
+	for input, want := range cases {
+		got, ok := parseNumeral(input)
+		if !ok || got != want {
+			t.Fatalf("parseNumeral(%q) = %d, %v; want %d", input, got, ok, want)
+		}
+	}
+	if _, ok := parseNumeral("十二a"); ok {
+		t.Fatalf("expected invalid numeral to be rejected")
+	}
+}
+
+// newChapterTestBook 用 {标题, 正文} 生成只有一卷的书，正文按行分段，第 i 章的行号为 i*10。
+func newChapterTestBook(chapters ...[2]string) *ParsedBook {
+	volume := Volume{}
+	for i, item := range chapters {
+		chapter := Chapter{Title: item[0], Line: (i + 1) * 10}
+		for _, line := range strings.Split(item[1], "\n") {
+			chapter.Content.WriteString(formatParagraph(line))
+		}
+		volume.Chapters = append(volume.Chapters, chapter)
+	}
+	return &ParsedBook{Volumes: []Volume{volume}}
+}
+
+func chapterTitles(volume Volume) []string {
+	titles := make([]string, 0, len(volume.Chapters))
+	for _, chapter := range volume.Chapters {
+		titles = append(titles, chapter.Title)
+	}
+	return titles
+}
+
+func TestCheckChapterNumberingWarnsWithoutChangingChapters(t *testing.T) {
+	rules, err := compileRuleConfig(defaultRuleConfig())
+	if err != nil {
+		t.Fatalf("compile rules: %v", err)
+	}
+
+	parsed := newChapterTestBook(
+		[2]string{"第一章 开始", "正文一"},
+		[2]string{"第三章 跳过", "正文三"},
+		[2]string{"第二章 回来", "正文二"},
+		[2]string{"第三章 跳过", "正文三"},
+		[2]string{"第六章 结尾", "正文六"},
+	)
+	checkChapterNumbering(parsed, rules)
+
+	if got := len(parsed.Volumes[0].Chapters); got != 5 {
+		t.Fatalf("warn policy should keep all chapters, got %d", got)
+	}
+	if parsed.Volumes[0].Chapters[4].Number != 6 {
+		t.Fatalf("expected chapter number to be parsed, got %d", parsed.Volumes[0].Chapters[4].Number)
+	}
+
+	kinds := make([]string, 0, len(parsed.Report.NumberingIssues))
+	for _, issue := range parsed.Report.NumberingIssues {
+		if issue.Fixed {
+			t.Fatalf("warn policy should not fix issues: %+v", issue)
+		}
+		kinds = append(kinds, issue.Kind)
+	}
+	want := []string{NumberingIssueOutOfOrder, NumberingIssueDuplicate, NumberingIssueMissing}
+	if strings.Join(kinds, ",") != strings.Join(want, ",") {
+		t.Fatalf("unexpected issues: %+v", parsed.Report.NumberingIssues)
+	}
+	missing := parsed.Report.NumberingIssues[2]
+	if missing.Number != 4 || missing.To != 5 || missing.Title != "第六章 结尾" {
+		t.Fatalf("unexpected missing range: %+v", missing)
+	}
+}
+
+func TestCheckChapterNumberingFixDropsExactDuplicatesAndSorts(t *testing.T) {
+	cfg := defaultRuleConfig()
+	cfg.DuplicateChapterPolicy = "fix"
+	cfg.ChapterOrderPolicy = "fix"
+	rules, err := compileRuleConfig(cfg)
+	if err != nil {
+		t.Fatalf("compile rules: %v", err)
+	}
+
+	parsed := newChapterTestBook(
+		[2]string{"楔子", "开篇"},
+		[2]string{"第2章 二", "正文二"},
+		[2]string{"第1章 一", "正文一"},
+		[2]string{"第2章 二", "正文二"},
+		[2]string{"第3章 三", "正文三"},
+		[2]string{"第3章 三（修）", "修订后的正文三"},
+	)
+	checkChapterNumbering(parsed, rules)
+
+	got := strings.Join(chapterTitles(parsed.Volumes[0]), "|")
+	want := "楔子|第1章 一|第2章 二|第3章 三|第3章 三（修）"
+	if got != want {
+		t.Fatalf("unexpected chapters after fix:\n got: %s\nwant: %s", got, want)
+	}
+
+	fixed := 0
+	for _, issue := range parsed.Report.NumberingIssues {
+		if issue.Fixed {
+			fixed++
+		}
+	}
+	// 乱序和完全相同的重复章节被修正；正文不同的同序号章节只提示。
+	if fixed != 2 || len(parsed.Report.NumberingIssues) != 3 {
+		t.Fatalf("unexpected issues: %+v", parsed.Report.NumberingIssues)
+	}
+}
+
+func TestCheckChapterNumberingRestartsPerVolume(t *testing.T) {
+	rules, err := compileRuleConfig(defaultRuleConfig())
+	if err != nil {
+		t.Fatalf("compile rules: %v", err)
+	}
+
+	first := newChapterTestBook([2]string{"第一章 甲", "a"}, [2]string{"第二章 乙", "b"})
+	second := newChapterTestBook([2]string{"第一章 丙", "c"}, [2]string{"第二章 丁", "d"})
+	parsed := &ParsedBook{Volumes: []Volume{first.Volumes[0], second.Volumes[0]}}
+	checkChapterNumbering(parsed, rules)
+
+	if len(parsed.Report.NumberingIssues) != 0 {
+		t.Fatalf("per-volume numbering should not be reported: %+v", parsed.Report.NumberingIssues)
+	}
+}
+
+func TestParseBookReportsNumberingPolicyFromBook(t *testing.T) {
+	isolateAutoRuleConfigDiscovery(t)
+
+	tmpDir := t.TempDir()
+	txtPath := filepath.Join(tmpDir, "shuffled.txt")
+	content := strings.Join([]string{
+		"乱序测试",
+		"第二章 二",
+		"正文二",
+		"第一章 一",
+		"正文一",
+	}, "\n")
+	if err := os.WriteFile(txtPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write txt: %v", err)
+	}
+
+	book := &Book{Filename: txtPath, ChapterOrderPolicy: "fix"}
+	parsed, err := ParseBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("parse book: %v", err)
+	}
+	if got := strings.Join(chapterTitles(parsed.Volumes[0]), "|"); got != "第一章 一|第二章 二" {
+		t.Fatalf("expected chapters to be sorted, got %s", got)
+	}
+	if len(parsed.Report.NumberingIssues) != 1 || !parsed.Report.NumberingIssues[0].Fixed {
+		t.Fatalf("unexpected issues: %+v", parsed.Report.NumberingIssues)
+	}
+
+	book = &Book{Filename: txtPath, MissingChapterPolicy: "fix"}
+	if _, err := ParseBook(context.Background(), book); err == nil {
+		t.Fatalf("expected fix to be rejected for missing chapter policy")
+	}
+}
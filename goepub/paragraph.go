@@ -0,0 +1,93 @@
+package goepub
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+)
+
+// 段落划分模式。
+const (
+	// paragraphModeLine 每个非空行（或重排后的段落）输出为一段。
+	paragraphModeLine = "line"
+	// paragraphModeBlank 以空行分段，段内换行和缩进原样保留。
+	paragraphModeBlank = "blank"
+)
+
+// lineBlockIndentColumns 是普通段首缩进的最大宽度，两个全角空格或四个半角空格。
+// 超过这个宽度的缩进视为有意的排版（如诗歌、落款），需要保留下来。
+const lineBlockIndentColumns = 4
+
+func normalizeParagraphMode(value string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", paragraphModeLine:
+		return paragraphModeLine, nil
+	case paragraphModeBlank:
+		return paragraphModeBlank, nil
+	}
+	return "", fmt.Errorf("不支持的段落划分模式: %s", value)
+}
+
+// formatLineBlock 把空行分段模式下的一段输出为 XHTML。
+// 只有一行且缩进普通的段落与 line 模式的输出一致；
+// 多行或缩进较深的段落使用 lines 样式，行间用 <br/> 分隔并保留行首缩进。
+func formatLineBlock(lines []string) string {
+	if len(lines) == 1 {
+		indent, _ := splitLeadingIndent(lines[0])
+		if indentColumns(indent) <= lineBlockIndentColumns {
+			return formatParagraph(strings.TrimSpace(lines[0]))
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(LineBlockStart)
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteString("<br/>\n")
+		}
+		indent, text := splitLeadingIndent(line)
+		b.WriteString(preserveIndent(indent))
+		b.WriteString(html.EscapeString(text))
+	}
+	b.WriteString(ParagraphEnd)
+	return b.String()
+}
+
+func splitLeadingIndent(line string) (string, string) {
+	text := strings.TrimLeftFunc(line, unicode.IsSpace)
+	return line[:len(line)-len(text)], text
+}
+
+// indentColumns 计算缩进的显示宽度，全角空格按两列、制表符按四列计算。
+func indentColumns(indent string) int {
+	columns := 0
+	for _, r := range indent {
+		switch r {
+		case '\u3000':
+			columns += 2
+		case '\t':
+			columns += 4
+		default:
+			columns++
+		}
+	}
+	return columns
+}
+
+// preserveIndent 把行首空白转换为阅读器不会折叠的字符。
+// 全角空格本身不会被折叠，原样保留；其他空白转换为不换行空格，制表符按四个计算。
+func preserveIndent(indent string) string {
+	var b strings.Builder
+	for _, r := range indent {
+		switch r {
+		case '\u3000':
+			b.WriteRune(r)
+		case '\t':
+			b.WriteString(strings.Repeat("\u00a0", 4))
+		default:
+			b.WriteRune('\u00a0')
+		}
+	}
+	return b.String()
+}
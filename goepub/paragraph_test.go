@@ -0,0 +1,78 @@
+package goepub
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFormatLineBlock(t *testing.T) {
+	if got, want := formatLineBlock([]string{"　　普通的一段。"}), formatParagraph("普通的一段。"); got != want {
+		t.Fatalf("single line paragraph should match line mode:\n got: %s\nwant: %s", got, want)
+	}
+
+	got := formatLineBlock([]string{"床前明月光，", "\t疑是地上霜。", "　　　　——李白"})
+	want := LineBlockStart + "床前明月光，<br/>\n" +
+		"    疑是地上霜。<br/>\n" +
+		"　　　　——李白" + ParagraphEnd
+	if got != want {
+		t.Fatalf("unexpected line block:\n got: %s\nwant: %s", got, want)
+	}
+
+	if got := formatLineBlock([]string{"　　　　落款 <某人>"}); !strings.HasPrefix(got, LineBlockStart) || !strings.Contains(got, "&lt;某人&gt;") {
+		t.Fatalf("deeply indented line should keep its indentation: %s", got)
+	}
+}
+
+func TestParagraphModeCanBeSetPerChannelAndBook(t *testing.T) {
+	isolateAutoRuleConfigDiscovery(t)
+
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "rules.toml")
+	configContent := `
+[channels.poetry]
+paragraph_mode = "blank"
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	txtPath := filepath.Join(tmpDir, "poetry.txt")
+	content := strings.Join([]string{
+		"诗集",
+		"第一章 静夜思",
+		"床前明月光，",
+		"疑是地上霜。",
+		"",
+		"　　后记一段。",
+	}, "\n")
+	if err := os.WriteFile(txtPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write txt: %v", err)
+	}
+
+	book := &Book{Filename: txtPath, RuleConfigPath: configPath, RuleChannel: "poetry"}
+	parsed, err := ParseBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("parse book: %v", err)
+	}
+	want := LineBlockStart + "床前明月光，<br/>\n疑是地上霜。" + ParagraphEnd + formatParagraph("后记一段。")
+	if got := parsed.Volumes[0].Chapters[0].Content.String(); got != want {
+		t.Fatalf("unexpected blank mode content:\n got: %s\nwant: %s", got, want)
+	}
+
+	book = &Book{Filename: txtPath, RuleConfigPath: configPath, RuleChannel: "poetry", ParagraphMode: "line"}
+	parsed, err = ParseBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("parse book: %v", err)
+	}
+	if got := parsed.Volumes[0].Chapters[0].Content.String(); strings.Contains(got, LineBlockStart) {
+		t.Fatalf("book paragraph mode should override the channel: %s", got)
+	}
+
+	book = &Book{Filename: txtPath, ParagraphMode: "stanza"}
+	if _, err := ParseBook(context.Background(), book); err == nil {
+		t.Fatal("expected unsupported paragraph mode to fail")
+	}
+}
@@ -0,0 +1,700 @@
+package goepub
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Parser 定义把 TXT 文本解析为卷章结构的接口。
+// 实现只负责“文本 -> ParsedBook”，编码探测和 EPUB 输出都不在它的职责范围内。
+type Parser interface {
+	// Parse 从已经解码为 UTF-8 的 r 中读取文本，并按 rules 解析出卷章结构。
+	Parse(ctx context.Context, r io.Reader, rules *ParseRules) (*ParsedBook, error)
+}
+
+// ParsedBook 是解析阶段的产物。
+// 它可以单独使用，也可以交给 ParsedConverter 复用 EPUB 写出流程。
+type ParsedBook struct {
+	// Name 是从文本中识别到的书名。
+	Name string
+	// Author 是从文本中识别到的作者。
+	Author string
+	// Intro 是从前缀式简介中收集到的简介。
+	Intro string
+	// Volumes 是解析后的卷章树。
+	Volumes []Volume
+	// Images 是正文引用的本地图片，写出 EPUB 时会一并打包。
+	Images []Image
+	// Metadata 是输入文件自带的其他元信息，目前只有 Markdown 的 YAML 头信息会提供。
+	Metadata Metadata
+	// Report 是解析过程中的诊断信息，例如被忽略或丢弃的行。
+	Report ParseReport
+}
+
+// Metadata 是输入文件中声明的书名、作者和简介之外的元信息，和 Book 的同名字段一样只用于补齐空缺。
+type Metadata struct {
+	// Lang 只在调用方没有指定语言时使用。
+	Lang string
+	// Cover 是封面的网络地址或本地路径，本地路径已经按输入文件所在目录解析。
+	Cover       string
+	Publisher   string
+	PublishDate string
+}
+
+// textParser 是默认的正则解析器。
+// name、author、intro 用于告知解析器哪些元信息已由调用方提供，
+// 这样对应的行不会再被当作书名、作者或简介消耗掉。
+type textParser struct {
+	name   string
+	author string
+	intro  string
+	// dir 是 TXT 文件所在的目录，正文中插图引用的相对路径以它为基准；为空时不查找插图。
+	dir string
+	// lang 是书籍的语言，决定前言等生成章节的标题。
+	lang string
+}
+
+// NewTextParser 创建默认的正则 TXT 解析器。
+func NewTextParser() Parser {
+	return &textParser{}
+}
+
+// newBookTextParser 创建一个了解 Book 中已知元信息的默认解析器。
+func newBookTextParser(book *Book) *textParser {
+	return &textParser{
+		name:   book.Name,
+		author: book.Author,
+		intro:  book.Intro,
+		dir:    bookImageDir(book),
+		lang:   book.Lang,
+	}
+}
+
+// bookImageDir 返回查找正文图片的目录，即输入文件所在的目录；关闭了图片查找时为空。
+func bookImageDir(book *Book) string {
+	if book.DisableImageLookup {
+		return ""
+	}
+	return filepath.Dir(book.Filename)
+}
+
+// textParseState 保存一次解析过程中的游标状态。
+type textParseState struct {
+	ctx    context.Context
+	rules  *ParseRules
+	parsed *ParsedBook
+
+	lineNo          int
+	currentPart     string
+	currentPartLine int
+	// partUsed 表示当前部之下已经有卷或章节，没有用到的部标题会记录为丢弃的行。
+	partUsed        bool
+	currentVol      *Volume
+	currentCh       *Chapter
+	introLines      []string
+	collectingIntro bool
+
+	// frontMatter 是首个部、卷或章节之前正文的处理方式，structured 表示它们已经出现过。
+	frontMatter      string
+	frontMatterLines []string
+	structured       bool
+	// lang 是书籍的语言，决定前言章节的标题。
+	lang string
+
+	// aozora 是青空文库注记模式下跨行的状态。
+	aozora aozoraState
+	// dir 是解析插图相对路径的目录，images 收集正文引用的插图。
+	dir    string
+	images imageSet
+
+	// footnotes 是已分配的注释编号，全书连续编号，保证合并后的文件中 id 不重复。
+	footnotes int
+
+	// wrap 是 auto 重排模式下推断出的折行特征。
+	wrap wrapLayout
+	// paragraph 暂存尚未输出的段落行，paragraphLastRaw 是其中最后一行的原始文本。
+	paragraph        []string
+	paragraphLastRaw string
+	// paragraphs 是当前章节已成段、尚未格式化的段落。
+	paragraphs []Paragraph
+	// err 记录收束章节时章节过滤器返回的错误。
+	err error
+}
+
+// Parse 按行扫描文本，识别书名、作者、简介、卷和章节。
+func (p *textParser) Parse(ctx context.Context, r io.Reader, rules *ParseRules) (*ParsedBook, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if rules == nil {
+		var err error
+		rules, err = compileRuleConfig(defaultRuleConfig())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	state := &textParseState{
+		ctx:   ctx,
+		rules: rules,
+		parsed: &ParsedBook{
+			Name:   p.name,
+			Author: p.author,
+			Intro:  p.intro,
+		},
+		frontMatter: rules.FrontMatter,
+		lang:        p.lang,
+		dir:         p.dir,
+	}
+	switch {
+	case state.frontMatter == "":
+		state.frontMatter = frontMatterKeep
+	case state.frontMatter == frontMatterIntro && p.intro != "":
+		// 调用方提供的简介会原样使用，并入简介也不会生效，改为保留为前言章节。
+		state.frontMatter = frontMatterKeep
+	}
+	state.parsed.Report.FrontMatter = state.frontMatter
+	if state.lang == "" && rules.Markup == markupAozora {
+		// 不知道书籍语言时，青空文库注记的文本按日文处理。
+		state.lang = japaneseRulePreset
+	}
+
+	scanner := bufio.NewScanner(r)
+	// 默认 Scanner 单行长度限制较小，小说正文里常见的超长段落会直接触发错误，
+	// 这里主动放大缓冲区以提升兼容性。
+	scanner.Buffer(make([]byte, 64*1024), maxScannerTokenSize)
+	// 旧版 Mac 格式只用 \r 换行，按默认规则会被当作一整行，超过缓冲区上限后直接失败。
+	scanner.Split(lineBreakCounter{fixes: &state.parsed.Report.TextFixes}.split)
+
+	// auto 重排需要先看一段样本才能判断折行宽度，样本行随后照常参与解析。
+	var sample []string
+	if rules.ReflowMode == reflowModeAuto && rules.ParagraphMode != paragraphModeBlank {
+		for len(sample) < reflowSampleLines && scanner.Scan() {
+			sample = append(sample, scanner.Text())
+		}
+		state.wrap = detectWrapLayout(sample)
+	}
+	for _, raw := range sample {
+		if err := state.processLine(raw); err != nil {
+			return nil, err
+		}
+	}
+	sample = nil
+
+	for scanner.Scan() {
+		if err := state.processLine(scanner.Text()); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("扫描 TXT 文件失败: %w", err)
+	}
+
+	state.flushChapter()
+	if state.err != nil {
+		return nil, state.err
+	}
+	state.flushVolume()
+	state.discardUnusedPart()
+
+	parsed := state.parsed
+	parsed.Images = state.images.images
+	if !state.structured {
+		// 一个卷章都没有识别到时，整本书都会被当成前言，这种情况仍按未解析到章节处理。
+		parsed.Volumes = nil
+	}
+	parsed.Report.WrapWidth = state.wrap.width
+	if parsed.Intro == "" && len(state.introLines) > 0 {
+		parsed.Intro = strings.TrimSpace(strings.Join(state.introLines, "\n"))
+	}
+	if len(state.frontMatterLines) > 0 {
+		parsed.Intro = strings.TrimSpace(parsed.Intro + "\n" + strings.Join(state.frontMatterLines, "\n"))
+	}
+	if rules.Markup == markupAozora {
+		// 元信息不支持注音等标记，只保留正文文字。
+		parsed.Name = aozoraPlainText(parsed.Name)
+		parsed.Author = aozoraPlainText(parsed.Author)
+		parsed.Intro = aozoraPlainText(parsed.Intro)
+	}
+	return parsed, nil
+}
+
+// processLine 先清理非法字符并执行行过滤器，再交给 handleLine 分类处理。
+func (s *textParseState) processLine(raw string) error {
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+	s.lineNo++
+	raw = sanitizeLine(raw, &s.parsed.Report.TextFixes)
+	raw, keep, err := s.filterLine(raw)
+	if err != nil {
+		return err
+	}
+	if keep {
+		s.handleLine(raw)
+	}
+	return s.err
+}
+
+// handleLine 处理一行原始文本。
+func (s *textParseState) handleLine(raw string) {
+	rules := s.rules
+	parsed := s.parsed
+
+	line := strings.TrimSpace(raw)
+	if line == "" {
+		// 空行总是段落边界。
+		s.flushParagraph()
+		return
+	}
+
+	if rules.Markup == markupAozora {
+		var handled bool
+		if line, handled = s.handleAozoraLine(line); handled {
+			return
+		}
+	}
+
+	if rules.ShouldIgnoreLine(line) {
+		parsed.Report.IgnoredLines = append(parsed.Report.IgnoredLines, reportLine(s.lineNo, line))
+		return
+	}
+
+	if parsed.Name == "" || parsed.Author == "" {
+		title, author, ok := rules.ParseInlineTitleAndAuthor(line)
+		if ok {
+			if parsed.Name == "" {
+				parsed.Name = title
+				log.Printf("小说标题: %s", parsed.Name)
+			}
+			if parsed.Author == "" {
+				parsed.Author = author
+				log.Printf("小说作者: %s", parsed.Author)
+			}
+			return
+		}
+	}
+
+	if parsed.Name == "" {
+		if title, ok := rules.ParseTitle(line); ok {
+			parsed.Name = title
+			log.Printf("小说标题: %s", parsed.Name)
+			return
+		}
+	}
+
+	if parsed.Author == "" {
+		if author, ok := rules.ParseAuthor(line); ok {
+			parsed.Author = author
+			log.Printf("小说作者: %s", parsed.Author)
+			return
+		}
+	}
+
+	if parsed.Intro == "" {
+		if introText, ok := rules.ParsePrefixedIntro(line); ok {
+			s.collectingIntro = true
+			if introText != "" {
+				s.introLines = append(s.introLines, introText)
+			}
+			return
+		}
+		if s.collectingIntro {
+			if rules.IsStructuralLine(line) {
+				parsed.Intro = strings.TrimSpace(strings.Join(s.introLines, "\n"))
+				s.collectingIntro = false
+			} else {
+				if text := rules.ApplyReplacements(line, ReplacementScopeBody); text != "" {
+					s.introLines = append(s.introLines, text)
+				}
+				return
+			}
+		}
+	}
+
+	switch {
+	case rules.PartRegex != nil && rules.PartRegex.MatchString(line):
+		// 部只作为之后各卷的归属，本身不单独成卷。
+		s.flushChapter()
+		s.flushVolume()
+		s.currentVol = nil
+		s.discardUnusedPart()
+		s.structured = true
+		s.currentPart = rules.replaceTitle(line)
+		s.currentPartLine = s.lineNo
+		s.partUsed = false
+		log.Printf("解析部: %s", s.currentPart)
+		return
+	case rules.VolumeRegex != nil && rules.VolumeRegex.MatchString(line):
+		// 遇到新卷时，先收束当前章节和当前卷，再开启下一卷。
+		s.flushChapter()
+		s.flushVolume()
+		s.structured = true
+		title := rules.replaceTitle(line)
+		s.currentVol = s.newVolume(title)
+		log.Printf("解析卷: %s", title)
+		return
+	case rules.ChapterRegex != nil && rules.ChapterRegex.MatchString(line):
+		title := rules.replaceTitle(line)
+		s.startChapter(title)
+		log.Printf("解析章节: %s", title)
+		return
+	case rules.ExtraRegex != nil && rules.ExtraRegex.MatchString(line):
+		title := rules.replaceTitle(line)
+		s.startChapter(title)
+		log.Printf("解析番外: %s", title)
+		return
+	case rules.IsSpecialChapterTitle(line):
+		title := rules.replaceTitle(line)
+		s.startChapter(title)
+		log.Printf("解析特殊章节: %s", title)
+		return
+	}
+
+	if !s.structured {
+		s.appendFrontMatter(raw, line)
+		return
+	}
+	if s.currentCh != nil {
+		s.appendBody(raw, line)
+		return
+	}
+	// 卷标题与该卷首章之间的正文没有归属，记录下来供报告使用。
+	parsed.Report.DiscardedLines = append(parsed.Report.DiscardedLines, reportLine(s.lineNo, line))
+}
+
+// appendBody 把一行正文追加到当前章节。
+func (s *textParseState) appendBody(raw, line string) {
+	if s.rules.IsSceneBreak(line) {
+		// 分隔行同时也是段落边界。
+		s.flushParagraph()
+		s.emitParagraph(Paragraph{SceneBreak: true})
+		return
+	}
+	if s.appendImages(line) {
+		return
+	}
+	// 行内替换只改写正文内容，折行判断仍使用原始行宽。
+	line = s.rules.ApplyReplacements(line, ReplacementScopeBody)
+	if line == "" {
+		return
+	}
+	// 普通正文仅归属到当前章节，在收束章节时统一做 HTML 转义。
+	s.appendBodyLine(raw, line)
+}
+
+// startChapter 收束上一章并开启新章节。
+// 无卷小说也允许直接挂章节，因此需要时会自动创建匿名卷。
+func (s *textParseState) startChapter(title string) {
+	if s.currentVol == nil {
+		s.currentVol = s.newVolume("")
+	}
+	s.structured = true
+	s.flushChapter()
+	s.currentCh = &Chapter{Title: title, Line: s.lineNo}
+}
+
+// newVolume 创建挂在当前部下的卷；title 为空时是匿名卷。
+func (s *textParseState) newVolume(title string) *Volume {
+	volume := &Volume{Title: title, Part: s.currentPart, PartLine: s.currentPartLine}
+	s.partUsed = true
+	if title != "" {
+		volume.Line = s.lineNo
+	}
+	return volume
+}
+
+// discardUnusedPart 把之后没有任何卷或章节的部标题记入报告，部本身不会出现在 EPUB 中。
+func (s *textParseState) discardUnusedPart() {
+	if s.currentPart == "" || s.partUsed {
+		return
+	}
+	s.parsed.Report.DiscardedLines = append(s.parsed.Report.DiscardedLines, reportLine(s.currentPartLine, s.currentPart))
+}
+
+func (s *textParseState) flushChapter() {
+	if s.currentVol == nil || s.currentCh == nil {
+		return
+	}
+	s.flushParagraph()
+	if err := s.renderChapter(s.currentCh); err != nil && s.err == nil {
+		s.err = err
+	}
+	s.currentVol.Chapters = append(s.currentVol.Chapters, *s.currentCh)
+	s.currentCh = nil
+}
+
+func (s *textParseState) flushVolume() {
+	if s.currentVol == nil {
+		return
+	}
+	if len(s.currentVol.Chapters) == 0 && strings.TrimSpace(s.currentVol.Title) == "" {
+		return
+	}
+	s.parsed.Volumes = append(s.parsed.Volumes, *s.currentVol)
+	s.currentVol = nil
+}
+
+// ParseBook 按 Book 中的配置读取并解析 TXT，但不写出 EPUB。
+// 它会先调用 FullDefault，并使用 Book.Parser；未设置时使用默认正则解析器。
+func ParseBook(ctx context.Context, book *Book) (*ParsedBook, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if book == nil {
+		return nil, errors.New("book 不能为空")
+	}
+	if err := book.FullDefault(); err != nil {
+		return nil, err
+	}
+	return parseBookSource(ctx, book)
+}
+
+// parseBookSource 串联“流式解码、预设探测、文本解析、相似章节与序号检查”几个步骤。
+// 调用前 Book 必须已经执行过 FullDefault。
+func parseBookSource(ctx context.Context, book *Book) (*ParsedBook, error) {
+	parsed, detection, detections, err := parseBookText(ctx, book)
+	if err != nil {
+		return nil, err
+	}
+	var inference *ChapterInference
+	// Markdown 的章节来自标题语法，推断章节正则对它没有意义。
+	if countParsedChapters(parsed) == 0 && book.InputFormat != inputFormatMarkdown {
+		if inference, err = inferBookChapterPattern(book); err != nil {
+			return nil, err
+		}
+		switch {
+		case inference != nil && book.InferChapterRegex:
+			log.Printf("章节正则未匹配到任何章节，改用推断的章节正则: %s（匹配 %d 行，得分 %.2f）", inference.Pattern, inference.Matches, inference.Score)
+			book.ChapterRegex = regexp.MustCompile(inference.Pattern)
+			rules, err := buildParseRules(book)
+			if err != nil {
+				return nil, err
+			}
+			book.setParseRules(rules)
+			if parsed, detection, detections, err = parseBookText(ctx, book); err != nil {
+				return nil, err
+			}
+		case inference != nil:
+			log.Printf("章节正则未匹配到任何章节，推断的章节正则: %s（匹配 %d 行，例如 %s），可以使用 -chapter-regexp=auto 自动应用",
+				inference.Pattern, inference.Matches, inference.Samples[0].Text)
+		}
+	}
+	if parsed == nil || len(parsed.Volumes) == 0 {
+		if inference != nil && !book.InferChapterRegex {
+			return nil, fmt.Errorf("未解析到任何章节，请检查章节正则是否正确；根据正文推断的章节正则为 %s，例如 %s，可以将章节正则设为 auto 自动使用",
+				inference.Pattern, inference.Samples[0].Text)
+		}
+		return nil, errors.New("未解析到任何章节，请检查章节正则是否正确")
+	}
+	rules := book.parseRules
+	// 先合并相似章节，序号检查就不会再把同一章的修订版报告为重复。
+	detectSimilarChapters(parsed, rules)
+	checkChapterNumbering(parsed, rules)
+	if err := convertChineseScript(parsed, book.ChineseConversion); err != nil {
+		return nil, err
+	}
+	parsed.Report.Encoding = detection.encoding
+	parsed.Report.EncodingConfidence = detection.confidence
+	parsed.Report.EncodingCandidates = detection.candidates
+	parsed.Report.EncodingRepairs = detection.repairs()
+	if len(parsed.Report.EncodingRepairs) > 0 {
+		log.Printf("检测到 %d 段文本与主编码 %s 不同，已按行单独解码或还原乱码", len(parsed.Report.EncodingRepairs), detection.encoding)
+	}
+	if fixes := parsed.Report.TextFixes; fixes.Total() > 0 {
+		log.Printf("文本规范化: 统一换行 %d 处，删除非法字符 %d 个、零宽字符 %d 个，替换无效字节 %d 处",
+			fixes.CRLineBreaks+fixes.CRLFLineBreaks+fixes.UnicodeLineBreaks, fixes.InvalidXMLChars, fixes.ZeroWidthChars, fixes.InvalidUTF8)
+	}
+	// 丢弃的行数始终输出，方便确认没有正文被悄悄吞掉。
+	log.Printf("首章之前的正文 %d 行，处理方式: %s；共丢弃无归属的正文 %d 行",
+		parsed.Report.FrontMatterLines, parsed.Report.FrontMatter, len(parsed.Report.DiscardedLines))
+	parsed.Report.InferredChapter = inference
+	parsed.Report.DetectedPresets = detections
+	parsed.Report.AppliedPresets = appliedRulePresets(book)
+	return parsed, nil
+}
+
+// parseBookText 打开并解码源文件，探测预设后交给解析器。
+// 推断章节正则后需要按新规则重新解析，因此每次调用都会重新打开文件。
+func parseBookText(ctx context.Context, book *Book) (*ParsedBook, encodingDetection, []DetectedRulePreset, error) {
+	rules := book.parseRules
+	source, detection, err := openTextReader(book.Filename, book.Encoding)
+	if err != nil {
+		return nil, detection, nil, err
+	}
+	defer func() {
+		_ = source.Close()
+	}()
+	log.Printf("检测到文本编码: %s（置信度 %.2f）", detection.encoding, detection.confidence)
+
+	// 解码后的文本直接以流的方式交给解析器；预设探测只窥视开头有限的一段，
+	// 这样整本书不会再以原始字节、解码字符串等多份副本同时驻留内存。
+	reader := bufio.NewReaderSize(source, presetDetectionPrefixSize)
+	// 站点预设针对的是 TXT 的卷章格式，Markdown 只需要判断语言。
+	detectPresets := len(book.RulePresets) == 0 && book.RulePresetMode != presetModeOff && book.InputFormat != inputFormatMarkdown
+	var prefix string
+	if detectPresets || book.detectLanguage {
+		if prefix, err = peekDetectionPrefix(reader); err != nil {
+			return nil, detection, nil, err
+		}
+	}
+	if book.detectLanguage {
+		if rules, err = applyDetectedLanguage(book, rules, prefix); err != nil {
+			return nil, detection, nil, err
+		}
+	}
+	var detections []DetectedRulePreset
+	if detectPresets {
+		rules, detections, err = applyDetectedRulePresets(book, rules, prefix)
+		if err != nil {
+			return nil, detection, nil, err
+		}
+	}
+
+	var parser Parser = book.Parser
+	switch {
+	case parser != nil:
+	case book.InputFormat == inputFormatMarkdown:
+		parser = newBookMarkdownParser(book)
+	default:
+		parser = newBookTextParser(book)
+	}
+	parsed, err := parser.Parse(ctx, reader, rules)
+	if err != nil {
+		return nil, detection, nil, err
+	}
+	return parsed, detection, detections, nil
+}
+
+// inferBookChapterPattern 重新读取源文件，推断章节标题的格式。
+func inferBookChapterPattern(book *Book) (*ChapterInference, error) {
+	source, _, err := openTextReader(book.Filename, book.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = source.Close()
+	}()
+	return InferChapterPattern(source)
+}
+
+// countParsedChapters 统计解析结果中的章节总数。
+func countParsedChapters(parsed *ParsedBook) int {
+	if parsed == nil {
+		return 0
+	}
+	total := 0
+	for _, volume := range parsed.Volumes {
+		total += len(volume.Chapters)
+	}
+	return total
+}
+
+// applyTo 将解析结果同步回 Book。
+// 调用方显式提供的书名、作者、简介等元信息优先，解析结果只用于补齐空缺。
+func (p *ParsedBook) applyTo(book *Book) {
+	if book.Name == "" {
+		book.Name = p.Name
+	}
+	if book.Author == "" {
+		book.Author = p.Author
+	}
+	if book.Intro == "" {
+		book.Intro = p.Intro
+	}
+	if book.inferLang && p.Metadata.Lang != "" {
+		book.Lang = p.Metadata.Lang
+	}
+	if book.Cover == "" {
+		book.Cover = p.Metadata.Cover
+	}
+	if book.Publisher == "" {
+		book.Publisher = p.Metadata.Publisher
+	}
+	if book.PublishDate == "" {
+		book.PublishDate = p.Metadata.PublishDate
+	}
+	book.Volumes = p.Volumes
+	book.Images = p.Images
+
+	if book.Name == "" && book.Filename != "" {
+		book.Name = strings.TrimSuffix(filepath.Base(book.Filename), filepath.Ext(book.Filename))
+	}
+	if book.Intro == "" {
+		book.Intro = deriveIntro(book)
+	}
+}
+
+// applyDetectedRulePresets 根据文本开头的内容探测规则预设。
+// apply 模式下会重建解析规则并返回新的规则，其余模式只输出推荐日志。
+func applyDetectedRulePresets(book *Book, rules *ParseRules, prefix string) (*ParseRules, []DetectedRulePreset, error) {
+	detections := DetectRulePresets(prefix)
+	if len(detections) == 0 {
+		return rules, nil, nil
+	}
+
+	names := make([]string, 0, len(detections))
+	reasonParts := make([]string, 0, len(detections))
+	for _, detected := range detections {
+		names = append(names, detected.Name)
+		reasonParts = append(reasonParts, fmt.Sprintf("%s(score=%d, hit=%s)", detected.Name, detected.Score, strings.Join(detected.Reasons, " | ")))
+	}
+
+	switch book.RulePresetMode {
+	case presetModeApply:
+		book.detectedRulePresets = names
+		rebuilt, err := buildParseRules(book)
+		if err != nil {
+			return nil, nil, err
+		}
+		book.setParseRules(rebuilt)
+		log.Printf("自动应用规则预设: %s", strings.Join(reasonParts, "; "))
+		return rebuilt, detections, nil
+	default:
+		log.Printf("检测到推荐规则预设: %s", strings.Join(reasonParts, "; "))
+		log.Printf("如需自动应用，可使用 -rule-preset-mode=apply；如需手动指定，可使用 -rule-preset=%s", strings.Join(names, ","))
+		return rules, detections, nil
+	}
+}
+
+// applyDetectedLanguage 在调用方没有指定语言时，根据正文的文字种类选择语言规则。
+// 目前区分中文、含假名的日文和拉丁字母书写的英文，识别为日文或英文时同时把 EPUB 语言设为 ja 或 en。
+func applyDetectedLanguage(book *Book, rules *ParseRules, prefix string) (*ParseRules, error) {
+	book.detectLanguage = false
+	lang := detectTextLanguage(prefix)
+	if lang == "" {
+		return rules, nil
+	}
+	book.Lang = lang
+	book.languageRulePreset = languageRulePreset(book.Lang)
+	rebuilt, err := buildParseRules(book)
+	if err != nil {
+		return nil, err
+	}
+	book.setParseRules(rebuilt)
+	log.Printf("根据正文的文字种类，语言设为 %s 并使用 %s 规则预设", book.Lang, book.languageRulePreset)
+	return rebuilt, nil
+}
+
+// peekDetectionPrefix 在不消耗读取器的前提下取出用于预设探测的文本前缀。
+// 如果前缀没有读到文件末尾，会丢弃最后一个可能被截断的行。
+func peekDetectionPrefix(reader *bufio.Reader) (string, error) {
+	prefix, err := reader.Peek(presetDetectionPrefixSize)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return "", fmt.Errorf("读取文本前缀失败: %w", err)
+	}
+	if err == nil || errors.Is(err, bufio.ErrBufferFull) {
+		if index := bytes.LastIndexAny(prefix, "\r\n"); index >= 0 {
+			prefix = prefix[:index]
+		}
+	}
+	return string(prefix), nil
+}
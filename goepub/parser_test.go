@@ -0,0 +1,205 @@
+package goepub
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTextParserBuildsVolumeTreeWithoutWritingEPUB(t *testing.T) {
+	rules, err := compileRuleConfig(defaultRuleConfig())
+	if err != nil {
+		t.Fatalf("compile default rules: %v", err)
+	}
+
+	source := strings.Join([]string{
+		"解析器测试",
+		"作者：赵六",
+		"第一卷 起点",
+		"第一章 开始",
+		"第一段内容",
+		"第二章 继续",
+		"第二段内容",
+	}, "\n")
+
+	parsed, err := NewTextParser().Parse(context.Background(), strings.NewReader(source), rules)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if parsed.Name != "解析器测试" || parsed.Author != "赵六" {
+		t.Fatalf("unexpected metadata: name=%q author=%q", parsed.Name, parsed.Author)
+	}
+	if len(parsed.Volumes) != 1 || parsed.Volumes[0].Title != "第一卷 起点" {
+		t.Fatalf("unexpected volumes: %+v", parsed.Volumes)
+	}
+	chapters := parsed.Volumes[0].Chapters
+	if len(chapters) != 2 || chapters[1].Title != "第二章 继续" {
+		t.Fatalf("unexpected chapters: %d", len(chapters))
+	}
+	if !strings.Contains(chapters[0].Content.String(), "第一段内容") {
+		t.Fatalf("unexpected chapter content: %s", chapters[0].Content.String())
+	}
+}
+
+// staticParser 是测试用的自定义解析器，忽略输入并返回固定的卷章结构。
+type staticParser struct{}
+
+func (staticParser) Parse(_ context.Context, r io.Reader, _ *ParseRules) (*ParsedBook, error) {
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return nil, err
+	}
+	parsed := &ParsedBook{Name: "自定义解析", Author: "测试"}
+	chapter := Chapter{Title: "唯一章节"}
+	chapter.Content.WriteString(formatParagraph("来自自定义解析器"))
+	parsed.Volumes = []Volume{{Chapters: []Chapter{chapter}}}
+	return parsed, nil
+}
+
+func TestParseBookUsesCustomParser(t *testing.T) {
+	isolateAutoRuleConfigDiscovery(t)
+
+	tmpDir := t.TempDir()
+	txtPath := filepath.Join(tmpDir, "odd-source.txt")
+	if err := os.WriteFile(txtPath, []byte("任意格式的原始内容"), 0o644); err != nil {
+		t.Fatalf("write txt: %v", err)
+	}
+
+	book := &Book{Filename: txtPath, Parser: staticParser{}}
+	parsed, err := ParseBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("parse book: %v", err)
+	}
+	if parsed.Name != "自定义解析" || len(parsed.Volumes) != 1 {
+		t.Fatalf("unexpected parsed book: %+v", parsed)
+	}
+}
+
+func TestConvertParsedReusesEPUBWriter(t *testing.T) {
+	isolateAutoRuleConfigDiscovery(t)
+
+	tmpDir := t.TempDir()
+	outputPath := filepath.Join(tmpDir, "parsed.epub")
+
+	parsed, err := staticParser{}.Parse(context.Background(), strings.NewReader(""), nil)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	book := &Book{Output: outputPath}
+	if err := NewEPUBConverter().ConvertParsed(context.Background(), book, parsed); err != nil {
+		t.Fatalf("convert parsed: %v", err)
+	}
+	if book.Name != "自定义解析" || book.Author != "测试" {
+		t.Fatalf("expected parsed metadata to fill book, got name=%q author=%q", book.Name, book.Author)
+	}
+	if _, err := os.Stat(outputPath); err != nil {
+		t.Fatalf("expected epub output: %v", err)
+	}
+}
+
+func TestTextParserRendersSceneBreaks(t *testing.T) {
+	rules, err := compileRuleConfig(defaultRuleConfig())
+	if err != nil {
+		t.Fatalf("compile default rules: %v", err)
+	}
+
+	source := strings.Join([]string{
+		"分隔测试",
+		"第一章 开始",
+		"第一段内容",
+		"* * *",
+		"第二段内容",
+		"☆☆☆",
+		"——————",
+		"◆",
+		"——他说。",
+	}, "\n")
+	parsed, err := NewTextParser().Parse(context.Background(), strings.NewReader(source), rules)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := formatParagraph("第一段内容") + SceneBreak +
+		formatParagraph("第二段内容") + SceneBreak + SceneBreak + SceneBreak +
+		formatParagraph("——他说。")
+	if got := parsed.Volumes[0].Chapters[0].Content.String(); got != want {
+		t.Fatalf("unexpected scene break content:\n got: %s\nwant: %s", got, want)
+	}
+}
+
+func TestTextParserGroupsVolumesUnderParts(t *testing.T) {
+	source := strings.Join([]string{
+		"三级结构测试",
+		"作者：钱七",
+		"第一部 地球往事",
+		"第一卷 疯狂年代",
+		"第一章 开始",
+		"第一段内容",
+		"第二卷 寂静的春天",
+		"第二章 继续",
+		"第二段内容",
+		"第二部 黑暗森林",
+		"第三章 面壁者",
+		"第三段内容",
+	}, "\n")
+
+	config := defaultRuleConfig()
+	config.PartRegex = PartPattern
+	rules, err := compileRuleConfig(config)
+	if err != nil {
+		t.Fatalf("compile part rules: %v", err)
+	}
+	parsed, err := NewTextParser().Parse(context.Background(), strings.NewReader(source), rules)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := []struct{ part, volume string }{
+		{"第一部 地球往事", "第一卷 疯狂年代"},
+		{"第一部 地球往事", "第二卷 寂静的春天"},
+		{"第二部 黑暗森林", ""},
+	}
+	if len(parsed.Volumes) != len(want) {
+		t.Fatalf("unexpected volumes: %+v", parsed.Volumes)
+	}
+	for i, item := range want {
+		volume := parsed.Volumes[i]
+		if volume.Part != item.part || volume.Title != item.volume || len(volume.Chapters) != 1 {
+			t.Fatalf("volume %d = part %q title %q chapters %d, want %q %q", i, volume.Part, volume.Title, len(volume.Chapters), item.part, item.volume)
+		}
+	}
+	if parsed.Volumes[0].PartLine != 3 || parsed.Volumes[2].PartLine != 10 {
+		t.Fatalf("unexpected part lines: %d %d", parsed.Volumes[0].PartLine, parsed.Volumes[2].PartLine)
+	}
+	if len(parsed.Report.DiscardedLines) != 0 {
+		t.Fatalf("unexpected discarded lines: %+v", parsed.Report.DiscardedLines)
+	}
+
+	// 之后没有卷或章节的部标题不会进入 EPUB，需要记录在报告中。
+	parsed, err = NewTextParser().Parse(context.Background(), strings.NewReader(source+"\n第三部 死神永生"), rules)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(parsed.Volumes) != len(want) || len(parsed.Report.DiscardedLines) != 1 ||
+		parsed.Report.DiscardedLines[0].Line != 13 || parsed.Report.DiscardedLines[0].Text != "第三部 死神永生" {
+		t.Fatalf("expected the trailing part to be discarded: %+v %+v", parsed.Volumes, parsed.Report.DiscardedLines)
+	}
+
+	// 未启用部正则时，“第X部”仍按卷处理，两级结构保持不变。
+	rules, err = compileRuleConfig(defaultRuleConfig())
+	if err != nil {
+		t.Fatalf("compile default rules: %v", err)
+	}
+	parsed, err = NewTextParser().Parse(context.Background(), strings.NewReader(source), rules)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	for _, volume := range parsed.Volumes {
+		if volume.Part != "" {
+			t.Fatalf("unexpected part without part regex: %+v", volume)
+		}
+	}
+	if len(parsed.Volumes) != 4 || parsed.Volumes[0].Title != "第一部 地球往事" || parsed.Volumes[3].Title != "第二部 黑暗森林" {
+		t.Fatalf("unexpected two-level volumes: %+v", parsed.Volumes)
+	}
+}
@@ -0,0 +1,185 @@
+package goepub
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/width"
+)
+
+// 折行重排模式。
+const (
+	reflowModeAuto  = "auto"
+	reflowModeOff   = "off"
+	reflowModeForce = "force"
+)
+
+const (
+	// reflowSampleLines 是 auto 模式下用于推断折行宽度的开头行数。
+	reflowSampleLines = 2000
+	// reflowMinimumSample 以下的非空行数不足以判断是否为硬折行文本。
+	reflowMinimumSample = 20
+	// reflowMinimumWidth 以下的主导行宽不视为折行宽度，避免把短对话误判为折行。
+	reflowMinimumWidth = 20
+)
+
+// sentenceEndings 是段落结尾常见的句末标点和收尾引号。
+const sentenceEndings = "。！？!?…」』”’\"~～）)"
+
+func normalizeReflowMode(value string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", reflowModeAuto:
+		return reflowModeAuto, nil
+	case reflowModeOff:
+		return reflowModeOff, nil
+	case reflowModeForce:
+		return reflowModeForce, nil
+	}
+	return "", fmt.Errorf("不支持的折行重排模式: %s", value)
+}
+
+// wrapLayout 是从文本样本中推断出的硬折行特征。
+type wrapLayout struct {
+	// width 是主导行宽（按显示宽度计算）；0 表示不是硬折行文本。
+	width int
+	// indented 表示段首普遍带有缩进，可以用缩进作为分段依据。
+	indented bool
+}
+
+// detectWrapLayout 统计样本行的显示宽度分布。
+// 硬折行文本中大部分行都恰好停在同一宽度附近，只有段尾行会更短；
+// 自然换行的文本行宽分散，不会出现这样集中的峰值。
+func detectWrapLayout(lines []string) wrapLayout {
+	histogram := make(map[int]int)
+	total, indented := 0, 0
+	for _, raw := range lines {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		total++
+		histogram[lineDisplayWidth(raw)]++
+		if hasLeadingIndent(raw) {
+			indented++
+		}
+	}
+	if total < reflowMinimumSample {
+		return wrapLayout{}
+	}
+
+	peak := 0
+	for lineWidth, count := range histogram {
+		if lineWidth < reflowMinimumWidth {
+			continue
+		}
+		if count > histogram[peak] || (count == histogram[peak] && lineWidth > peak) {
+			peak = lineWidth
+		}
+	}
+	if peak == 0 {
+		return wrapLayout{}
+	}
+	// 避头尾规则会让个别行多出或少一个字符，因此峰值附近的行都算作满行。
+	near := histogram[peak-2] + histogram[peak-1] + histogram[peak] + histogram[peak+1] + histogram[peak+2]
+	if near*10 < total*4 {
+		return wrapLayout{}
+	}
+	return wrapLayout{
+		width:    peak,
+		indented: indented*10 >= total,
+	}
+}
+
+// lineDisplayWidth 计算去掉行尾空白后的显示宽度，全角字符按两列计算。
+// 固定字节宽度折行的 GBK 文本在中英文混排时字符数并不一致，按显示宽度更稳定。
+func lineDisplayWidth(raw string) int {
+	total := 0
+	for _, r := range strings.TrimRightFunc(raw, unicode.IsSpace) {
+		switch width.LookupRune(r).Kind() {
+		case width.EastAsianWide, width.EastAsianFullwidth:
+			total += 2
+		default:
+			total++
+		}
+	}
+	return total
+}
+
+func hasLeadingIndent(raw string) bool {
+	r, _ := utf8.DecodeRuneInString(raw)
+	return r != utf8.RuneError && unicode.IsSpace(r)
+}
+
+func endsSentence(line string) bool {
+	r, _ := utf8.DecodeLastRuneInString(strings.TrimSpace(line))
+	return r != utf8.RuneError && strings.ContainsRune(sentenceEndings, r)
+}
+
+// continuesParagraph 判断 raw 是否应接在当前待输出段落之后。
+func (s *textParseState) continuesParagraph(raw string) bool {
+	if len(s.paragraph) == 0 || hasLeadingIndent(raw) {
+		return false
+	}
+	last := s.paragraphLastRaw
+	switch s.rules.ReflowMode {
+	case reflowModeForce:
+		return !endsSentence(last)
+	case reflowModeAuto:
+		if s.wrap.width == 0 || lineDisplayWidth(last) < s.wrap.width-2 {
+			return false
+		}
+		// 没有段首缩进的文本只能依靠句末标点分段。
+		return s.wrap.indented || !endsSentence(last)
+	default:
+		return false
+	}
+}
+
+// appendBodyLine 把正文行追加到当前章节。
+// 关闭重排时每行直接成段；否则先暂存，直到遇到分段信号再整体输出。
+func (s *textParseState) appendBodyLine(raw, line string) {
+	if s.rules.ParagraphMode == paragraphModeBlank {
+		// 空行分段模式下段内每一行都是有意的换行，保留原始缩进。
+		indent, _ := splitLeadingIndent(raw)
+		s.paragraph = append(s.paragraph, indent+line)
+		return
+	}
+	if s.rules.ReflowMode == reflowModeOff {
+		s.emitParagraph(Paragraph{Text: line})
+		return
+	}
+	if !s.continuesParagraph(raw) {
+		s.flushParagraph()
+	}
+	s.paragraph = append(s.paragraph, line)
+	s.paragraphLastRaw = raw
+}
+
+// flushParagraph 输出暂存的段落，折行处的中文直接拼接，西文单词之间补一个空格。
+func (s *textParseState) flushParagraph() {
+	if len(s.paragraph) == 0 {
+		return
+	}
+	if s.rules.ParagraphMode == paragraphModeBlank {
+		s.emitParagraph(Paragraph{Text: strings.Join(s.paragraph, "\n")})
+		s.paragraph = s.paragraph[:0]
+		return
+	}
+	var joined strings.Builder
+	for i, line := range s.paragraph {
+		if i > 0 && needsJoinSpace(s.paragraph[i-1], line) {
+			joined.WriteByte(' ')
+		}
+		joined.WriteString(line)
+	}
+	s.emitParagraph(Paragraph{Text: joined.String()})
+	s.paragraph = s.paragraph[:0]
+	s.paragraphLastRaw = ""
+}
+
+func needsJoinSpace(previous, next string) bool {
+	last, _ := utf8.DecodeLastRuneInString(previous)
+	first, _ := utf8.DecodeRuneInString(next)
+	return last < utf8.RuneSelf && first < utf8.RuneSelf && last != '-'
+}
@@ -0,0 +1,111 @@
+package goepub
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// hardWrap 把段落按固定字符数折行，段首加两个全角空格，模拟老式 TXT 的排版。
+func hardWrap(paragraphs []string, columns int, indent bool) []string {
+	lines := make([]string, 0)
+	for _, paragraph := range paragraphs {
+		runes := []rune(paragraph)
+		if indent {
+			runes = append([]rune("　　"), runes...)
+		}
+		for len(runes) > columns {
+			lines = append(lines, string(runes[:columns]))
+			runes = runes[columns:]
+		}
+		lines = append(lines, string(runes))
+	}
+	return lines
+}
+
+func wrappedTestParagraphs(count int) []string {
+	paragraphs := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		paragraphs = append(paragraphs, strings.Repeat("山路蜿蜒向前延伸，他背着行囊慢慢走着，", 4)+"直到天黑才停下脚步。")
+	}
+	return paragraphs
+}
+
+func parseWithReflow(t *testing.T, mode string, lines []string) *ParsedBook {
+	t.Helper()
+	cfg := defaultRuleConfig()
+	cfg.ReflowMode = mode
+	rules, err := compileRuleConfig(cfg)
+	if err != nil {
+		t.Fatalf("compile rules: %v", err)
+	}
+	source := "折行测试\n第一章 开始\n" + strings.Join(lines, "\n")
+	parsed, err := NewTextParser().Parse(context.Background(), strings.NewReader(source), rules)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	return parsed
+}
+
+func TestDetectWrapLayout(t *testing.T) {
+	wrapped := hardWrap(wrappedTestParagraphs(10), 30, true)
+	layout := detectWrapLayout(wrapped)
+	if layout.width != 60 || !layout.indented {
+		t.Fatalf("unexpected layout for wrapped text: %+v", layout)
+	}
+
+	natural := make([]string, 0, 30)
+	for i := 0; i < 30; i++ {
+		natural = append(natural, strings.Repeat("字", 5+i*3))
+	}
+	if layout := detectWrapLayout(natural); layout.width != 0 {
+		t.Fatalf("natural text should not be detected as wrapped: %+v", layout)
+	}
+}
+
+func TestTextParserReflowsHardWrappedParagraphs(t *testing.T) {
+	paragraphs := wrappedTestParagraphs(10)
+	lines := hardWrap(paragraphs, 30, true)
+
+	parsed := parseWithReflow(t, reflowModeAuto, lines)
+	if parsed.Report.WrapWidth != 60 {
+		t.Fatalf("expected wrap width 60, got %d", parsed.Report.WrapWidth)
+	}
+	content := parsed.Volumes[0].Chapters[0].Content.String()
+	if got := strings.Count(content, "<p "); got != len(paragraphs) {
+		t.Fatalf("expected %d paragraphs, got %d:\n%s", len(paragraphs), got, content)
+	}
+	if !strings.Contains(content, ">"+paragraphs[0]+"</p>") {
+		t.Fatalf("expected wrapped lines to be joined:\n%s", content)
+	}
+
+	parsed = parseWithReflow(t, reflowModeOff, lines)
+	content = parsed.Volumes[0].Chapters[0].Content.String()
+	if got := strings.Count(content, "<p "); got != len(lines) {
+		t.Fatalf("off mode should keep every line, expected %d paragraphs, got %d", len(lines), got)
+	}
+}
+
+func TestTextParserForceReflowUsesSentenceEndings(t *testing.T) {
+	lines := []string{
+		"这一行没有结束",
+		"所以要接上。",
+		"第二段开始了！",
+		"Hard wrapped English",
+		"text joins with a space.",
+	}
+	parsed := parseWithReflow(t, reflowModeForce, lines)
+	content := parsed.Volumes[0].Chapters[0].Content.String()
+	want := formatParagraph("这一行没有结束所以要接上。") +
+		formatParagraph("第二段开始了！") +
+		formatParagraph("Hard wrapped English text joins with a space.")
+	if content != want {
+		t.Fatalf("unexpected force reflow result:\n got: %s\nwant: %s", content, want)
+	}
+
+	// 自然分行的短文本在 auto 模式下不会被误合并。
+	parsed = parseWithReflow(t, reflowModeAuto, lines)
+	if got := strings.Count(parsed.Volumes[0].Chapters[0].Content.String(), "<p "); got != len(lines) {
+		t.Fatalf("auto mode should not reflow short samples, got %d paragraphs", got)
+	}
+}
@@ -0,0 +1,117 @@
+package goepub
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// 替换规则的作用范围。
+const (
+	ReplacementScopeBody  = "body"
+	ReplacementScopeTitle = "title"
+	ReplacementScopeAll   = "all"
+)
+
+// ReplacementRule 是规则文件中的一条行内替换规则，对应 TOML 中的 [[replacements]]。
+// 它用于去掉夹在正文中间的站点名、网址等水印，而不必丢弃整行。
+type ReplacementRule struct {
+	// Pattern 是要匹配的正则。
+	Pattern string `json:"pattern" toml:"pattern"`
+	// Replacement 是替换文本，支持 $1、${name} 形式引用捕获组；留空表示删除。
+	Replacement string `json:"replacement" toml:"replacement"`
+	// Scope 是作用范围：body、title、all，默认 body。
+	Scope string `json:"scope" toml:"scope"`
+}
+
+// String 返回适合命令行展示的描述。
+func (r ReplacementRule) String() string {
+	scope := r.Scope
+	if strings.TrimSpace(scope) == "" {
+		scope = ReplacementScopeBody
+	}
+	return fmt.Sprintf("[%s] %s => %q", scope, r.Pattern, r.Replacement)
+}
+
+// TextReplacement 是编译后的替换规则。
+type TextReplacement struct {
+	Regex       *regexp.Regexp
+	Replacement string
+	Scope       string
+}
+
+func normalizeReplacementScope(value string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", ReplacementScopeBody:
+		return ReplacementScopeBody, nil
+	case ReplacementScopeTitle:
+		return ReplacementScopeTitle, nil
+	case ReplacementScopeAll:
+		return ReplacementScopeAll, nil
+	}
+	return "", fmt.Errorf("不支持的替换范围: %s", value)
+}
+
+func compileReplacementRules(rules []ReplacementRule) ([]TextReplacement, error) {
+	compiled := make([]TextReplacement, 0, len(rules))
+	for _, rule := range rules {
+		if strings.TrimSpace(rule.Pattern) == "" {
+			continue
+		}
+		regex, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("替换规则正则无效 %q: %w", rule.Pattern, err)
+		}
+		scope, err := normalizeReplacementScope(rule.Scope)
+		if err != nil {
+			return nil, fmt.Errorf("替换规则 %q: %w", rule.Pattern, err)
+		}
+		compiled = append(compiled, TextReplacement{Regex: regex, Replacement: rule.Replacement, Scope: scope})
+	}
+	return compiled, nil
+}
+
+// appendUniqueReplacements 按顺序追加替换规则，完全相同的规则只保留一条。
+func appendUniqueReplacements(base []ReplacementRule, additions []ReplacementRule) []ReplacementRule {
+	result := append([]ReplacementRule(nil), base...)
+	for _, addition := range additions {
+		exists := false
+		for _, item := range result {
+			if item == addition {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			result = append(result, addition)
+		}
+	}
+	return result
+}
+
+// ApplyReplacements 按配置顺序对文本应用 scope 范围内的替换规则，并去掉首尾空白。
+// scope 取 ReplacementScopeBody 或 ReplacementScopeTitle，all 范围的规则对两者都生效。
+func (r *ParseRules) ApplyReplacements(text string, scope string) string {
+	if len(r.Replacements) == 0 {
+		return text
+	}
+	for _, replacement := range r.Replacements {
+		if replacement.Scope != ReplacementScopeAll && replacement.Scope != scope {
+			continue
+		}
+		text = replacement.Regex.ReplaceAllString(text, replacement.Replacement)
+	}
+	return strings.TrimSpace(text)
+}
+
+// replaceTitle 对卷章标题应用替换规则；替换后为空时保留原标题，避免出现无名章节。
+// 青空文库注记模式下会先去掉标题中的注音和注记。
+func (r *ParseRules) replaceTitle(line string) string {
+	if r.Markup == markupAozora {
+		line = aozoraPlainText(line)
+	}
+	if title := r.ApplyReplacements(line, ReplacementScopeTitle); title != "" {
+		return title
+	}
+	return line
+}
@@ -0,0 +1,87 @@
+package goepub
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReplacementsApplyByScopeAndOrder(t *testing.T) {
+	isolateAutoRuleConfigDiscovery(t)
+
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "rules.toml")
+	configContent := `
+[[replacements]]
+pattern = "请记住本站域名[:：]?\\s*\\S+?\\.com"
+
+[[replacements]]
+pattern = "(?i)www\\.example\\.com"
+scope = "all"
+
+[[replacements]]
+pattern = "（(求月票|求订阅)）"
+scope = "title"
+
+[channels.pirate]
+extends_presets = ["serial"]
+
+[[channels.pirate.replacements]]
+pattern = "笔趣阁"
+replacement = "某站"
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	txtPath := filepath.Join(tmpDir, "pirate.txt")
+	content := strings.Join([]string{
+		"替换测试",
+		"第一章 开始（求月票）www.example.com",
+		"他推开门，请记住本站域名：abc.com走了进去。",
+		"请记住本站域名 abc.com",
+		"笔趣阁首发。",
+	}, "\n")
+	if err := os.WriteFile(txtPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write txt: %v", err)
+	}
+
+	parsed, err := ParseBook(context.Background(), &Book{Filename: txtPath, RuleConfigPath: configPath})
+	if err != nil {
+		t.Fatalf("parse book: %v", err)
+	}
+	chapter := parsed.Volumes[0].Chapters[0]
+	if chapter.Title != "第一章 开始" {
+		t.Fatalf("unexpected chapter title: %q", chapter.Title)
+	}
+	want := formatParagraph("他推开门，走了进去。") + formatParagraph("笔趣阁首发。")
+	if got := chapter.Content.String(); got != want {
+		t.Fatalf("unexpected replaced content:\n got: %s\nwant: %s", got, want)
+	}
+
+	// 渠道中的替换规则会替换全局规则，而不是追加。
+	parsed, err = ParseBook(context.Background(), &Book{Filename: txtPath, RuleConfigPath: configPath, RuleChannel: "pirate"})
+	if err != nil {
+		t.Fatalf("parse book: %v", err)
+	}
+	chapter = parsed.Volumes[0].Chapters[0]
+	if !strings.Contains(chapter.Content.String(), "某站首发。") || !strings.Contains(chapter.Content.String(), "请记住本站域名") {
+		t.Fatalf("unexpected channel replaced content: %s", chapter.Content.String())
+	}
+}
+
+func TestReplacementsRejectInvalidScope(t *testing.T) {
+	cfg := defaultRuleConfig()
+	cfg.Replacements = []ReplacementRule{{Pattern: "广告", Scope: "chapter"}}
+	if _, err := compileRuleConfig(cfg); err == nil {
+		t.Fatal("expected invalid replacement scope to fail")
+	}
+
+	base := []ReplacementRule{{Pattern: "a"}}
+	merged := appendUniqueReplacements(base, []ReplacementRule{{Pattern: "a"}, {Pattern: "b", Scope: "title"}})
+	if len(merged) != 2 || merged[1].Pattern != "b" {
+		t.Fatalf("unexpected extended replacements: %+v", merged)
+	}
+}
@@ -0,0 +1,122 @@
+package goepub
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// ParseReport 记录一次解析过程中的诊断信息。
+// 它不影响转换结果，主要用于 inspect 命令检查规则是否命中预期。
+type ParseReport struct {
+	// Encoding 是实际用于解码的文本编码。
+	Encoding string `json:"encoding"`
+	// EncodingConfidence 是编码判定的置信度，显式指定、BOM 和有效 UTF-8 均为 1。
+	EncodingConfidence float64 `json:"encoding_confidence"`
+	// EncodingCandidates 是 auto 模式给各候选编码的评分，按置信度从高到低排列；
+	// 没有经过评分时为空。
+	EncodingCandidates []EncodingCandidate `json:"encoding_candidates"`
+	// EncodingRepairs 是 auto 模式下按行单独解码的混合编码段落和被还原的乱码段落。
+	EncodingRepairs []EncodingRepair `json:"encoding_repairs"`
+	// TextFixes 是文本规范化阶段统一的换行符和清理的非法字符。
+	TextFixes TextFixes `json:"text_fixes"`
+	// InferredChapter 是章节正则没有匹配到任何章节时根据正文推断出的章节格式；
+	// 只有设置 InferChapterRegex 时才会实际用于解析。
+	InferredChapter *ChapterInference `json:"inferred_chapter,omitempty"`
+	// DetectedPresets 是根据文本特征自动探测到的规则预设。
+	DetectedPresets []DetectedRulePreset `json:"detected_presets"`
+	// AppliedPresets 是最终叠加到解析规则上的预设，包括手动指定和自动应用的。
+	AppliedPresets []string `json:"applied_presets"`
+	// WrapWidth 是 auto 重排模式推断出的硬折行宽度；0 表示未检测到硬折行。
+	WrapWidth int `json:"wrap_width"`
+	// IgnoredLines 是被 ShouldIgnoreLine 判定为噪音而跳过的行。
+	IgnoredLines []ReportLine `json:"ignored_lines"`
+	// FrontMatter 是首个卷章之前正文实际采用的处理方式：keep、drop 或 intro。
+	FrontMatter string `json:"front_matter"`
+	// FrontMatterLines 是首个卷章之前、未被识别为书名/作者/简介的正文行数，不论如何处理都会统计。
+	FrontMatterLines int `json:"front_matter_lines"`
+	// DiscardedLines 是没有归属而被丢弃的正文行：front_matter 为 drop 时首个卷章之前的正文，
+	// 卷标题与该卷首章之间的正文，以及之后没有任何卷或章节的部标题。
+	DiscardedLines []ReportLine `json:"discarded_lines"`
+	// MissingImages 是正文引用了、但找不到本地文件的图片。
+	// Markdown 正文中改为输出图片的替代文字，TXT 正文中的插图引用直接去掉。
+	MissingImages []ReportLine `json:"missing_images"`
+	// NumberingIssues 是章节序号检查发现的缺号、重复和乱序问题。
+	NumberingIssues []NumberingIssue `json:"numbering_issues"`
+	// SimilarChapters 是正文高度相似的章节对，以及按策略删除的章节。
+	SimilarChapters []SimilarChapter `json:"similar_chapters"`
+}
+
+// ReportLine 表示报告中引用的一行源文本。
+type ReportLine struct {
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+// OutlineVolume 是卷章树在报告中的只读视图。
+type OutlineVolume struct {
+	Title    string           `json:"title"`
+	Line     int              `json:"line"`
+	Part     string           `json:"part,omitempty"`
+	PartLine int              `json:"part_line,omitempty"`
+	Chapters []OutlineChapter `json:"chapters"`
+}
+
+// OutlineChapter 描述单个章节的位置和正文长度。
+type OutlineChapter struct {
+	Title  string `json:"title"`
+	Line   int    `json:"line"`
+	Number int    `json:"number,omitempty"`
+	Chars  int    `json:"chars"`
+}
+
+// Outline 返回解析结果的卷章概览，章节长度按正文字符数统计。
+func (p *ParsedBook) Outline() []OutlineVolume {
+	outline := make([]OutlineVolume, 0, len(p.Volumes))
+	for _, volume := range p.Volumes {
+		item := OutlineVolume{
+			Title:    volume.Title,
+			Line:     volume.Line,
+			Part:     volume.Part,
+			PartLine: volume.PartLine,
+			Chapters: make([]OutlineChapter, 0, len(volume.Chapters)),
+		}
+		for _, chapter := range volume.Chapters {
+			item.Chapters = append(item.Chapters, OutlineChapter{
+				Title:  chapter.Title,
+				Line:   chapter.Line,
+				Number: chapter.Number,
+				Chars:  chapterTextLength(&chapter),
+			})
+		}
+		outline = append(outline, item)
+	}
+	return outline
+}
+
+// chapterTextLength 统计章节正文的可见字符数，不计空白和 XHTML 标签。
+func chapterTextLength(chapter *Chapter) int {
+	text := html.UnescapeString(removeHTMLTags(chapter.Content.String()))
+	count := 0
+	for _, r := range text {
+		if !unicode.IsSpace(r) {
+			count++
+		}
+	}
+	return count
+}
+
+// appliedRulePresets 汇总最终参与合并的预设名称。
+func appliedRulePresets(book *Book) []string {
+	var presets []string
+	if book.languageRulePreset != "" {
+		presets = append(presets, book.languageRulePreset)
+	}
+	presets = appendUniqueStrings(presets, book.RulePresets)
+	return appendUniqueStrings(presets, book.detectedRulePresets)
+}
+
+// reportLine 构造报告行，统一去掉首尾空白。
+func reportLine(line int, text string) ReportLine {
+	return ReportLine{Line: line, Text: strings.TrimSpace(text)}
+}
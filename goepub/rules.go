@@ -39,6 +39,14 @@ var defaultAuthorNoteContains = []string{
 	"卷接近尾声",
 }
 
+// defaultSceneBreakPatterns 识别常见的场景分隔行：
+// 重复的星号、花饰符号（允许中间夹空格），较长的横线，以及单独一个花饰符号。
+var defaultSceneBreakPatterns = []string{
+	`^(?:[*＊☆★◇◆○●□■△▲※#＃~～=＝·•・✦✧❖]\s*){3,}$`,
+	`^[-—―－_＿]{4,}$`,
+	`^[◆◇☆★※❖✦§]$`,
+}
+
 // BuiltinRulePreset 描述一个内置命名规则预设。
 // 预设用于在通用内置规则之上，补充少量来源或站点常见的格式特征。
 type BuiltinRulePreset struct {
@@ -57,9 +65,9 @@ type PresetDetector struct {
 
 // DetectedRulePreset 表示一次自动探测得到的预设候选及命中依据。
 type DetectedRulePreset struct {
-	Name    string
-	Score   int
-	Reasons []string
+	Name    string   `json:"name"`
+	Score   int      `json:"score"`
+	Reasons []string `json:"reasons"`
 }
 
 const (
@@ -165,6 +173,64 @@ var builtinRulePresets = map[string]BuiltinRulePreset{
 			MinimumScore: 2,
 		},
 	},
+	"parts": {
+		Name:        "parts",
+		Description: "三级结构预设，把“第X部”识别为卷之上的部，“第X卷”仍作为卷。",
+		Config: RuleConfig{
+			PartRegex: PartPattern,
+		},
+		Detector: PresetDetector{
+			// 同时出现“部”和“卷”时才推荐，只有“第X部”的书仍按两级结构把部当作卷。
+			Regexps: []string{
+				PartPattern,
+				`^第[一二三四五六七八九十百零0-9]+卷([\s　:：\-—].{0,30})?$`,
+			},
+			MinimumScore: 4,
+		},
+	},
+	englishRulePreset: {
+		Name:        englishRulePreset,
+		Description: "英文小说预设，识别 Chapter 12、Chapter Twelve 和罗马数字章节，Part One、Book II 等卷标题，Prologue、Epilogue、Interlude 以及 by 作者行。",
+		Config: RuleConfig{
+			TitleAuthorRegex: englishTitleAuthorPattern,
+			AuthorRegex:      englishAuthorPattern,
+			VolumeRegex:      englishVolumePattern,
+			ChapterRegex:     englishChapterPattern,
+			ExtraRegex:       englishChapterExtraPattern,
+			IntroRegex:       englishIntroPattern,
+			IntroPrefixes: []string{
+				"Synopsis:",
+				"Summary:",
+				"Blurb:",
+				"Description:",
+			},
+			IgnoredLinePatterns: []string{
+				englishAuthorNotePattern,
+			},
+			SceneBreakPatterns: []string{
+				englishSceneBreakPattern,
+			},
+			ChapterNumberRegex: englishChapterNumberPattern,
+		},
+		// 语言预设按 Book.Lang 或文字种类选择，不参与按关键词的自动探测。
+	},
+	japaneseRulePreset: {
+		Name:        japaneseRulePreset,
+		Description: "日文小说预设，解析青空文库注记，识别第一話、プロローグ、エピローグ等章节和第一巻等卷标题。",
+		Config: RuleConfig{
+			AuthorRegex:  japaneseAuthorPattern,
+			VolumeRegex:  japaneseVolumePattern,
+			ChapterRegex: japaneseChapterPattern,
+			ExtraRegex:   japaneseExtraPattern,
+			IntroRegex:   japaneseIntroPattern,
+			IntroPrefixes: []string{
+				"あらすじ：",
+				"あらすじ:",
+			},
+			ChapterNumberRegex: japaneseChapterNumberPattern,
+			Markup:             markupAozora,
+		},
+	},
 	"jjwxc": {
 		Name:        "jjwxc",
 		Description: "晋江类文本预设，补充入V公告、谢绝扒榜和阅读提示规则。",
@@ -207,6 +273,7 @@ type RuleConfig struct {
 	TitleRegex           string   `json:"title_regex" toml:"title_regex"`
 	TitleAuthorRegex     string   `json:"title_author_regex" toml:"title_author_regex"`
 	AuthorRegex          string   `json:"author_regex" toml:"author_regex"`
+	PartRegex            string   `json:"part_regex" toml:"part_regex"`
 	VolumeRegex          string   `json:"volume_regex" toml:"volume_regex"`
 	ChapterRegex         string   `json:"chapter_regex" toml:"chapter_regex"`
 	ExtraRegex           string   `json:"extra_regex" toml:"extra_regex"`
@@ -215,6 +282,42 @@ type RuleConfig struct {
 	SpecialChapterTitles []string `json:"special_chapter_titles" toml:"special_chapter_titles"`
 	IgnoredLinePatterns  []string `json:"ignored_line_patterns" toml:"ignored_line_patterns"`
 	IgnoredLineContains  []string `json:"ignored_line_contains" toml:"ignored_line_contains"`
+	// SceneBreakPatterns 用于识别正文中的场景分隔行，匹配的行输出为分隔线。
+	SceneBreakPatterns []string `json:"scene_break_patterns" toml:"scene_break_patterns"`
+	// Replacements 是按顺序执行的行内正则替换，用于去掉夹在正文或标题中的水印。
+	Replacements []ReplacementRule `json:"replacements" toml:"replacements"`
+	// ChapterNumberRegex 用于从章节标题中提取序号，第一个捕获组为中文或阿拉伯数字。
+	ChapterNumberRegex string `json:"chapter_number_regex" toml:"chapter_number_regex"`
+	// MissingChapterPolicy 控制缺号检查：off、warn。
+	MissingChapterPolicy string `json:"missing_chapter_policy" toml:"missing_chapter_policy"`
+	// DuplicateChapterPolicy 控制重复序号检查：off、warn、fix。
+	DuplicateChapterPolicy string `json:"duplicate_chapter_policy" toml:"duplicate_chapter_policy"`
+	// ChapterOrderPolicy 控制乱序检查：off、warn、fix。
+	ChapterOrderPolicy string `json:"chapter_order_policy" toml:"chapter_order_policy"`
+	// SimilarChapterPolicy 控制相似章节处理：off、report、keep_longest、keep_latest。
+	SimilarChapterPolicy string `json:"similar_chapter_policy" toml:"similar_chapter_policy"`
+	// SimilarChapterThreshold 是判定相似的 Jaccard 相似度阈值，范围 0 到 1。
+	SimilarChapterThreshold float64 `json:"similar_chapter_threshold" toml:"similar_chapter_threshold"`
+	// SimilarChapterWindow 是每章向前比较的章节数。
+	SimilarChapterWindow int `json:"similar_chapter_window" toml:"similar_chapter_window"`
+	// ReflowMode 控制硬折行重排：auto、off、force。
+	ReflowMode string `json:"reflow_mode" toml:"reflow_mode"`
+	// ParagraphMode 控制段落划分：line 每行一段，blank 以空行分段并保留段内换行。
+	ParagraphMode string `json:"paragraph_mode" toml:"paragraph_mode"`
+	// FrontMatter 控制首个卷章之前正文的处理：keep 保留为“前言”章节，drop 丢弃，intro 并入简介。
+	FrontMatter string `json:"front_matter" toml:"front_matter"`
+	// Markup 是正文的标记格式：plain 纯文本，aozora 解析青空文库注记。
+	Markup string `json:"markup" toml:"markup"`
+	// FootnoteMode 控制注释识别：on 转换为弹出式脚注，off 保留原文，默认 off。
+	FootnoteMode string `json:"footnote_mode" toml:"footnote_mode"`
+	// FootnoteRefPatterns 匹配正文中的注释引用，第一个捕获组为注释编号。
+	FootnoteRefPatterns []string `json:"footnote_ref_patterns" toml:"footnote_ref_patterns"`
+	// FootnoteNotePatterns 匹配单独成段的注释，第一个捕获组为注释编号，第二个为注释内容。
+	FootnoteNotePatterns []string `json:"footnote_note_patterns" toml:"footnote_note_patterns"`
+	// FootnoteInlinePatterns 匹配夹在正文中的行内注释，第一个捕获组为注释内容。
+	FootnoteInlinePatterns []string `json:"footnote_inline_patterns" toml:"footnote_inline_patterns"`
+	// ImagePatterns 匹配正文中的插图引用，第一个捕获组为相对 TXT 所在目录的图片路径。
+	ImagePatterns []string `json:"image_patterns" toml:"image_patterns"`
 }
 
 // RuleFileConfig 描述完整的规则文件结构。
@@ -258,6 +361,7 @@ type ParseRules struct {
 	TitleRegex          *regexp.Regexp
 	TitleAuthorRegex    *regexp.Regexp
 	AuthorRegex         *regexp.Regexp
+	PartRegex           *regexp.Regexp
 	VolumeRegex         *regexp.Regexp
 	ChapterRegex        *regexp.Regexp
 	ExtraRegex          *regexp.Regexp
@@ -266,6 +370,32 @@ type ParseRules struct {
 	SpecialChapterSet   map[string]struct{}
 	IgnoredLineRegexps  []*regexp.Regexp
 	IgnoredLineContains []string
+	SceneBreakRegexps   []*regexp.Regexp
+	Replacements        []TextReplacement
+	// LineFilters 和 ChapterFilters 来自 Book，只能通过代码设置，由内置解析器执行。
+	LineFilters    []LineFilter
+	ChapterFilters []ChapterFilter
+
+	ChapterNumberRegex     *regexp.Regexp
+	MissingChapterPolicy   string
+	DuplicateChapterPolicy string
+	ChapterOrderPolicy     string
+
+	SimilarChapterPolicy    string
+	SimilarChapterThreshold float64
+	SimilarChapterWindow    int
+
+	ReflowMode    string
+	ParagraphMode string
+	FrontMatter   string
+	Markup        string
+
+	FootnoteMode          string
+	FootnoteRefRegexps    []*regexp.Regexp
+	FootnoteNoteRegexps   []*regexp.Regexp
+	FootnoteInlineRegexps []*regexp.Regexp
+
+	ImageRegexps []*regexp.Regexp
 }
 
 // buildParseRules 组合内置规则、配置文件规则和代码直接传入的覆盖项。
@@ -273,6 +403,12 @@ func buildParseRules(book *Book) (*ParseRules, error) {
 	cfg := defaultRuleConfig()
 
 	var err error
+	if book.languageRulePreset != "" {
+		cfg, err = applyRulePresets(cfg, []string{book.languageRulePreset})
+		if err != nil {
+			return nil, err
+		}
+	}
 	cfg, err = applyRulePresets(cfg, book.RulePresets)
 	if err != nil {
 		return nil, err
@@ -304,7 +440,16 @@ func buildParseRules(book *Book) (*ParseRules, error) {
 		cfg = mergeRuleConfig(cfg, userCfg)
 	}
 
-	if book.VolumeRegex != nil {
+	// FullDefault 会把编译好的正则回写到 Book 上，这些值与上一次的 parseRules 是同一个对象，
+	// 只有调用方另行设置的正则才算覆盖项，否则重新组合时会盖掉自动应用的预设。
+	var built ParseRules
+	if book.parseRules != nil {
+		built = *book.parseRules
+	}
+	if book.PartRegex != nil && book.PartRegex != built.PartRegex {
+		cfg.PartRegex = book.PartRegex.String()
+	}
+	if book.VolumeRegex != nil && book.VolumeRegex != built.VolumeRegex {
 		cfg.VolumeRegex = book.VolumeRegex.String()
 	}
 	if book.TitleRegex != nil {
@@ -313,17 +458,50 @@ func buildParseRules(book *Book) (*ParseRules, error) {
 	if book.AuthorRegex != nil {
 		cfg.AuthorRegex = book.AuthorRegex.String()
 	}
-	if book.ChapterRegex != nil {
+	if book.ChapterRegex != nil && book.ChapterRegex != built.ChapterRegex {
 		cfg.ChapterRegex = book.ChapterRegex.String()
 	}
-	if book.ExtraRegex != nil {
+	if book.ExtraRegex != nil && book.ExtraRegex != built.ExtraRegex {
 		cfg.ExtraRegex = book.ExtraRegex.String()
 	}
-	if book.IntroRegex != nil {
+	if book.IntroRegex != nil && book.IntroRegex != built.IntroRegex {
 		cfg.IntroRegex = book.IntroRegex.String()
 	}
+	if strings.TrimSpace(book.MissingChapterPolicy) != "" {
+		cfg.MissingChapterPolicy = book.MissingChapterPolicy
+	}
+	if strings.TrimSpace(book.DuplicateChapterPolicy) != "" {
+		cfg.DuplicateChapterPolicy = book.DuplicateChapterPolicy
+	}
+	if strings.TrimSpace(book.ChapterOrderPolicy) != "" {
+		cfg.ChapterOrderPolicy = book.ChapterOrderPolicy
+	}
+	if strings.TrimSpace(book.SimilarChapterPolicy) != "" {
+		cfg.SimilarChapterPolicy = book.SimilarChapterPolicy
+	}
+	if strings.TrimSpace(book.ReflowMode) != "" {
+		cfg.ReflowMode = book.ReflowMode
+	}
+	if strings.TrimSpace(book.ParagraphMode) != "" {
+		cfg.ParagraphMode = book.ParagraphMode
+	}
+	if strings.TrimSpace(book.FrontMatter) != "" {
+		cfg.FrontMatter = book.FrontMatter
+	}
+	if strings.TrimSpace(book.Markup) != "" {
+		cfg.Markup = book.Markup
+	}
+	if strings.TrimSpace(book.FootnoteMode) != "" {
+		cfg.FootnoteMode = book.FootnoteMode
+	}
 
-	return compileRuleConfig(cfg)
+	rules, err := compileRuleConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	rules.LineFilters = book.LineFilters
+	rules.ChapterFilters = book.ChapterFilters
+	return rules, nil
 }
 
 // NormalizeRulePresetNames 将逗号分隔的预设名标准化为去重后的切片。
@@ -405,7 +583,28 @@ func defaultRuleConfig() RuleConfig {
 			`^第[一二三四五六七八九十百零0-9]+(卷|部|集)接近尾声.*$`,
 			`^第[一二三四五六七八九十百零0-9]+(卷|部|集)[:：].*[，,；;：:].*[。！？?!~～]\s*$`,
 		},
-		IgnoredLineContains: append([]string(nil), defaultAuthorNoteContains...),
+		IgnoredLineContains:    append([]string(nil), defaultAuthorNoteContains...),
+		SceneBreakPatterns:     append([]string(nil), defaultSceneBreakPatterns...),
+		ChapterNumberRegex:     defaultChapterNumberPattern,
+		MissingChapterPolicy:   numberingPolicyWarn,
+		DuplicateChapterPolicy: numberingPolicyWarn,
+		ChapterOrderPolicy:     numberingPolicyWarn,
+
+		SimilarChapterPolicy:    similarPolicyReport,
+		SimilarChapterThreshold: defaultSimilarChapterThreshold,
+		SimilarChapterWindow:    defaultSimilarChapterWindow,
+
+		ReflowMode:    reflowModeAuto,
+		ParagraphMode: paragraphModeLine,
+		FrontMatter:   frontMatterKeep,
+		Markup:        markupPlain,
+
+		FootnoteMode:           footnoteModeOff,
+		FootnoteRefPatterns:    append([]string(nil), defaultFootnoteRefPatterns...),
+		FootnoteNotePatterns:   append([]string(nil), defaultFootnoteNotePatterns...),
+		FootnoteInlinePatterns: append([]string(nil), defaultFootnoteInlinePatterns...),
+
+		ImagePatterns: append([]string(nil), defaultImagePatterns...),
 	}
 }
 
@@ -561,6 +760,9 @@ func summarizeRuleConfigFields(cfg RuleConfig) []string {
 	if strings.TrimSpace(cfg.AuthorRegex) != "" {
 		fields = append(fields, "author_regex")
 	}
+	if strings.TrimSpace(cfg.PartRegex) != "" {
+		fields = append(fields, "part_regex")
+	}
 	if strings.TrimSpace(cfg.VolumeRegex) != "" {
 		fields = append(fields, "volume_regex")
 	}
@@ -585,6 +787,60 @@ func summarizeRuleConfigFields(cfg RuleConfig) []string {
 	if len(cfg.IgnoredLineContains) > 0 {
 		fields = append(fields, "ignored_line_contains")
 	}
+	if len(cfg.SceneBreakPatterns) > 0 {
+		fields = append(fields, "scene_break_patterns")
+	}
+	if len(cfg.Replacements) > 0 {
+		fields = append(fields, "replacements")
+	}
+	if strings.TrimSpace(cfg.ChapterNumberRegex) != "" {
+		fields = append(fields, "chapter_number_regex")
+	}
+	if strings.TrimSpace(cfg.MissingChapterPolicy) != "" {
+		fields = append(fields, "missing_chapter_policy")
+	}
+	if strings.TrimSpace(cfg.DuplicateChapterPolicy) != "" {
+		fields = append(fields, "duplicate_chapter_policy")
+	}
+	if strings.TrimSpace(cfg.ChapterOrderPolicy) != "" {
+		fields = append(fields, "chapter_order_policy")
+	}
+	if strings.TrimSpace(cfg.SimilarChapterPolicy) != "" {
+		fields = append(fields, "similar_chapter_policy")
+	}
+	if cfg.SimilarChapterThreshold > 0 {
+		fields = append(fields, "similar_chapter_threshold")
+	}
+	if cfg.SimilarChapterWindow > 0 {
+		fields = append(fields, "similar_chapter_window")
+	}
+	if strings.TrimSpace(cfg.ReflowMode) != "" {
+		fields = append(fields, "reflow_mode")
+	}
+	if strings.TrimSpace(cfg.ParagraphMode) != "" {
+		fields = append(fields, "paragraph_mode")
+	}
+	if strings.TrimSpace(cfg.FrontMatter) != "" {
+		fields = append(fields, "front_matter")
+	}
+	if strings.TrimSpace(cfg.Markup) != "" {
+		fields = append(fields, "markup")
+	}
+	if strings.TrimSpace(cfg.FootnoteMode) != "" {
+		fields = append(fields, "footnote_mode")
+	}
+	if len(cfg.FootnoteRefPatterns) > 0 {
+		fields = append(fields, "footnote_ref_patterns")
+	}
+	if len(cfg.FootnoteNotePatterns) > 0 {
+		fields = append(fields, "footnote_note_patterns")
+	}
+	if len(cfg.FootnoteInlinePatterns) > 0 {
+		fields = append(fields, "footnote_inline_patterns")
+	}
+	if len(cfg.ImagePatterns) > 0 {
+		fields = append(fields, "image_patterns")
+	}
 	return fields
 }
 
@@ -721,6 +977,9 @@ func mergeRuleConfig(base RuleConfig, override RuleConfig) RuleConfig {
 	if strings.TrimSpace(override.AuthorRegex) != "" {
 		base.AuthorRegex = override.AuthorRegex
 	}
+	if strings.TrimSpace(override.PartRegex) != "" {
+		base.PartRegex = override.PartRegex
+	}
 	if strings.TrimSpace(override.VolumeRegex) != "" {
 		base.VolumeRegex = override.VolumeRegex
 	}
@@ -745,6 +1004,60 @@ func mergeRuleConfig(base RuleConfig, override RuleConfig) RuleConfig {
 	if len(override.IgnoredLineContains) > 0 {
 		base.IgnoredLineContains = append([]string(nil), override.IgnoredLineContains...)
 	}
+	if len(override.SceneBreakPatterns) > 0 {
+		base.SceneBreakPatterns = append([]string(nil), override.SceneBreakPatterns...)
+	}
+	if len(override.Replacements) > 0 {
+		base.Replacements = append([]ReplacementRule(nil), override.Replacements...)
+	}
+	if strings.TrimSpace(override.ChapterNumberRegex) != "" {
+		base.ChapterNumberRegex = override.ChapterNumberRegex
+	}
+	if strings.TrimSpace(override.MissingChapterPolicy) != "" {
+		base.MissingChapterPolicy = override.MissingChapterPolicy
+	}
+	if strings.TrimSpace(override.DuplicateChapterPolicy) != "" {
+		base.DuplicateChapterPolicy = override.DuplicateChapterPolicy
+	}
+	if strings.TrimSpace(override.ChapterOrderPolicy) != "" {
+		base.ChapterOrderPolicy = override.ChapterOrderPolicy
+	}
+	if strings.TrimSpace(override.SimilarChapterPolicy) != "" {
+		base.SimilarChapterPolicy = override.SimilarChapterPolicy
+	}
+	if override.SimilarChapterThreshold > 0 {
+		base.SimilarChapterThreshold = override.SimilarChapterThreshold
+	}
+	if override.SimilarChapterWindow > 0 {
+		base.SimilarChapterWindow = override.SimilarChapterWindow
+	}
+	if strings.TrimSpace(override.ReflowMode) != "" {
+		base.ReflowMode = override.ReflowMode
+	}
+	if strings.TrimSpace(override.ParagraphMode) != "" {
+		base.ParagraphMode = override.ParagraphMode
+	}
+	if strings.TrimSpace(override.FrontMatter) != "" {
+		base.FrontMatter = override.FrontMatter
+	}
+	if strings.TrimSpace(override.Markup) != "" {
+		base.Markup = override.Markup
+	}
+	if strings.TrimSpace(override.FootnoteMode) != "" {
+		base.FootnoteMode = override.FootnoteMode
+	}
+	if len(override.FootnoteRefPatterns) > 0 {
+		base.FootnoteRefPatterns = append([]string(nil), override.FootnoteRefPatterns...)
+	}
+	if len(override.FootnoteNotePatterns) > 0 {
+		base.FootnoteNotePatterns = append([]string(nil), override.FootnoteNotePatterns...)
+	}
+	if len(override.FootnoteInlinePatterns) > 0 {
+		base.FootnoteInlinePatterns = append([]string(nil), override.FootnoteInlinePatterns...)
+	}
+	if len(override.ImagePatterns) > 0 {
+		base.ImagePatterns = append([]string(nil), override.ImagePatterns...)
+	}
 	return base
 }
 
@@ -850,7 +1163,7 @@ func detectSinglePreset(name string, detector PresetDetector, lines []string) *D
 
 func buildDetectionLines(text string) []string {
 	const maxLines = 400
-	lines := strings.Split(text, "\n")
+	lines := splitUniversalLines(text)
 	filtered := make([]string, 0, min(len(lines), maxLines))
 
 	for _, line := range lines {
@@ -885,6 +1198,9 @@ func extendRuleConfig(base RuleConfig, extension RuleConfig) RuleConfig {
 	if strings.TrimSpace(extension.AuthorRegex) != "" {
 		base.AuthorRegex = extension.AuthorRegex
 	}
+	if strings.TrimSpace(extension.PartRegex) != "" {
+		base.PartRegex = extension.PartRegex
+	}
 	if strings.TrimSpace(extension.VolumeRegex) != "" {
 		base.VolumeRegex = extension.VolumeRegex
 	}
@@ -897,11 +1213,53 @@ func extendRuleConfig(base RuleConfig, extension RuleConfig) RuleConfig {
 	if strings.TrimSpace(extension.IntroRegex) != "" {
 		base.IntroRegex = extension.IntroRegex
 	}
+	if strings.TrimSpace(extension.ChapterNumberRegex) != "" {
+		base.ChapterNumberRegex = extension.ChapterNumberRegex
+	}
+	if strings.TrimSpace(extension.MissingChapterPolicy) != "" {
+		base.MissingChapterPolicy = extension.MissingChapterPolicy
+	}
+	if strings.TrimSpace(extension.DuplicateChapterPolicy) != "" {
+		base.DuplicateChapterPolicy = extension.DuplicateChapterPolicy
+	}
+	if strings.TrimSpace(extension.ChapterOrderPolicy) != "" {
+		base.ChapterOrderPolicy = extension.ChapterOrderPolicy
+	}
+	if strings.TrimSpace(extension.SimilarChapterPolicy) != "" {
+		base.SimilarChapterPolicy = extension.SimilarChapterPolicy
+	}
+	if extension.SimilarChapterThreshold > 0 {
+		base.SimilarChapterThreshold = extension.SimilarChapterThreshold
+	}
+	if extension.SimilarChapterWindow > 0 {
+		base.SimilarChapterWindow = extension.SimilarChapterWindow
+	}
+	if strings.TrimSpace(extension.ReflowMode) != "" {
+		base.ReflowMode = extension.ReflowMode
+	}
+	if strings.TrimSpace(extension.ParagraphMode) != "" {
+		base.ParagraphMode = extension.ParagraphMode
+	}
+	if strings.TrimSpace(extension.FrontMatter) != "" {
+		base.FrontMatter = extension.FrontMatter
+	}
+	if strings.TrimSpace(extension.Markup) != "" {
+		base.Markup = extension.Markup
+	}
+	if strings.TrimSpace(extension.FootnoteMode) != "" {
+		base.FootnoteMode = extension.FootnoteMode
+	}
 
 	base.IntroPrefixes = appendUniqueStrings(base.IntroPrefixes, extension.IntroPrefixes)
 	base.SpecialChapterTitles = appendUniqueStrings(base.SpecialChapterTitles, extension.SpecialChapterTitles)
 	base.IgnoredLinePatterns = appendUniqueStrings(base.IgnoredLinePatterns, extension.IgnoredLinePatterns)
 	base.IgnoredLineContains = appendUniqueStrings(base.IgnoredLineContains, extension.IgnoredLineContains)
+	base.SceneBreakPatterns = appendUniqueStrings(base.SceneBreakPatterns, extension.SceneBreakPatterns)
+	base.Replacements = appendUniqueReplacements(base.Replacements, extension.Replacements)
+	base.FootnoteRefPatterns = appendUniqueStrings(base.FootnoteRefPatterns, extension.FootnoteRefPatterns)
+	base.FootnoteNotePatterns = appendUniqueStrings(base.FootnoteNotePatterns, extension.FootnoteNotePatterns)
+	base.FootnoteInlinePatterns = appendUniqueStrings(base.FootnoteInlinePatterns, extension.FootnoteInlinePatterns)
+	base.ImagePatterns = appendUniqueStrings(base.ImagePatterns, extension.ImagePatterns)
 	return base
 }
 
@@ -962,6 +1320,12 @@ func compileRuleConfig(cfg RuleConfig) (*ParseRules, error) {
 	if err != nil {
 		return nil, fmt.Errorf("作者正则无效: %w", err)
 	}
+	var partRegex *regexp.Regexp
+	if strings.TrimSpace(cfg.PartRegex) != "" {
+		if partRegex, err = regexp.Compile(cfg.PartRegex); err != nil {
+			return nil, fmt.Errorf("部正则无效: %w", err)
+		}
+	}
 	volumeRegex, err := regexp.Compile(cfg.VolumeRegex)
 	if err != nil {
 		return nil, fmt.Errorf("卷正则无效: %w", err)
@@ -978,6 +1342,73 @@ func compileRuleConfig(cfg RuleConfig) (*ParseRules, error) {
 	if err != nil {
 		return nil, fmt.Errorf("简介正则无效: %w", err)
 	}
+	chapterNumberRegex, err := regexp.Compile(cfg.ChapterNumberRegex)
+	if err != nil {
+		return nil, fmt.Errorf("章节序号正则无效: %w", err)
+	}
+	missingChapterPolicy, err := normalizeNumberingPolicy(cfg.MissingChapterPolicy, false)
+	if err != nil {
+		return nil, fmt.Errorf("缺号检查策略无效: %w", err)
+	}
+	duplicateChapterPolicy, err := normalizeNumberingPolicy(cfg.DuplicateChapterPolicy, true)
+	if err != nil {
+		return nil, fmt.Errorf("重复章节检查策略无效: %w", err)
+	}
+	chapterOrderPolicy, err := normalizeNumberingPolicy(cfg.ChapterOrderPolicy, true)
+	if err != nil {
+		return nil, fmt.Errorf("章节顺序检查策略无效: %w", err)
+	}
+	similarChapterPolicy, err := normalizeSimilarPolicy(cfg.SimilarChapterPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("相似章节策略无效: %w", err)
+	}
+	similarChapterThreshold := cfg.SimilarChapterThreshold
+	if similarChapterThreshold == 0 {
+		similarChapterThreshold = defaultSimilarChapterThreshold
+	}
+	if similarChapterThreshold < 0 || similarChapterThreshold > 1 {
+		return nil, fmt.Errorf("相似章节阈值必须在 0 到 1 之间: %v", cfg.SimilarChapterThreshold)
+	}
+	similarChapterWindow := cfg.SimilarChapterWindow
+	if similarChapterWindow <= 0 {
+		similarChapterWindow = defaultSimilarChapterWindow
+	}
+	reflowMode, err := normalizeReflowMode(cfg.ReflowMode)
+	if err != nil {
+		return nil, err
+	}
+	paragraphMode, err := normalizeParagraphMode(cfg.ParagraphMode)
+	if err != nil {
+		return nil, err
+	}
+	frontMatter, err := normalizeFrontMatterMode(cfg.FrontMatter)
+	if err != nil {
+		return nil, err
+	}
+	markup, err := normalizeMarkup(cfg.Markup)
+	if err != nil {
+		return nil, err
+	}
+	footnoteMode, err := normalizeFootnoteMode(cfg.FootnoteMode)
+	if err != nil {
+		return nil, err
+	}
+	footnoteRefRegexps, err := compileCapturePatterns(cfg.FootnoteRefPatterns, 1, "注释引用正则")
+	if err != nil {
+		return nil, err
+	}
+	footnoteNoteRegexps, err := compileCapturePatterns(cfg.FootnoteNotePatterns, 2, "注释段落正则")
+	if err != nil {
+		return nil, err
+	}
+	footnoteInlineRegexps, err := compileCapturePatterns(cfg.FootnoteInlinePatterns, 1, "行内注释正则")
+	if err != nil {
+		return nil, err
+	}
+	imageRegexps, err := compileCapturePatterns(cfg.ImagePatterns, 1, "插图引用正则")
+	if err != nil {
+		return nil, err
+	}
 
 	ignoredLineRegexps := make([]*regexp.Regexp, 0, len(cfg.IgnoredLinePatterns))
 	for _, pattern := range cfg.IgnoredLinePatterns {
@@ -991,6 +1422,23 @@ func compileRuleConfig(cfg RuleConfig) (*ParseRules, error) {
 		ignoredLineRegexps = append(ignoredLineRegexps, compiled)
 	}
 
+	sceneBreakRegexps := make([]*regexp.Regexp, 0, len(cfg.SceneBreakPatterns))
+	for _, pattern := range cfg.SceneBreakPatterns {
+		if strings.TrimSpace(pattern) == "" {
+			continue
+		}
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("场景分隔正则无效 %q: %w", pattern, err)
+		}
+		sceneBreakRegexps = append(sceneBreakRegexps, compiled)
+	}
+
+	replacements, err := compileReplacementRules(cfg.Replacements)
+	if err != nil {
+		return nil, err
+	}
+
 	specialChapterSet := make(map[string]struct{}, len(cfg.SpecialChapterTitles))
 	for _, title := range cfg.SpecialChapterTitles {
 		title = strings.TrimSpace(title)
@@ -1019,6 +1467,7 @@ func compileRuleConfig(cfg RuleConfig) (*ParseRules, error) {
 		TitleRegex:          titleRegex,
 		TitleAuthorRegex:    titleAuthorRegex,
 		AuthorRegex:         authorRegex,
+		PartRegex:           partRegex,
 		VolumeRegex:         volumeRegex,
 		ChapterRegex:        chapterRegex,
 		ExtraRegex:          extraRegex,
@@ -1027,9 +1476,51 @@ func compileRuleConfig(cfg RuleConfig) (*ParseRules, error) {
 		SpecialChapterSet:   specialChapterSet,
 		IgnoredLineRegexps:  ignoredLineRegexps,
 		IgnoredLineContains: ignoredLineContains,
+		SceneBreakRegexps:   sceneBreakRegexps,
+		Replacements:        replacements,
+
+		ChapterNumberRegex:     chapterNumberRegex,
+		MissingChapterPolicy:   missingChapterPolicy,
+		DuplicateChapterPolicy: duplicateChapterPolicy,
+		ChapterOrderPolicy:     chapterOrderPolicy,
+
+		SimilarChapterPolicy:    similarChapterPolicy,
+		SimilarChapterThreshold: similarChapterThreshold,
+		SimilarChapterWindow:    similarChapterWindow,
+
+		ReflowMode:    reflowMode,
+		ParagraphMode: paragraphMode,
+		FrontMatter:   frontMatter,
+		Markup:        markup,
+
+		FootnoteMode:          footnoteMode,
+		FootnoteRefRegexps:    footnoteRefRegexps,
+		FootnoteNoteRegexps:   footnoteNoteRegexps,
+		FootnoteInlineRegexps: footnoteInlineRegexps,
+
+		ImageRegexps: imageRegexps,
 	}, nil
 }
 
+// compileCapturePatterns 编译带捕获组的正则列表，并检查捕获组数量是否满足要求。
+func compileCapturePatterns(patterns []string, groups int, name string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		if strings.TrimSpace(pattern) == "" {
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s无效 %q: %w", name, pattern, err)
+		}
+		if re.NumSubexp() < groups {
+			return nil, fmt.Errorf("%s至少需要 %d 个捕获组 %q", name, groups, pattern)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
 // ShouldIgnoreLine 判断一行文本是否应被当作作者说明、更新提示等噪音行跳过。
 func (r *ParseRules) ShouldIgnoreLine(line string) bool {
 	trimmed := strings.TrimSpace(line)
@@ -1051,6 +1542,20 @@ func (r *ParseRules) ShouldIgnoreLine(line string) bool {
 	return false
 }
 
+// IsSceneBreak 判断一行文本是否为场景分隔行。
+func (r *ParseRules) IsSceneBreak(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" {
+		return false
+	}
+	for _, regex := range r.SceneBreakRegexps {
+		if regex.MatchString(trimmed) {
+			return true
+		}
+	}
+	return false
+}
+
 // IsSpecialChapterTitle 判断一行文本是否属于特殊章节标题。
 func (r *ParseRules) IsSpecialChapterTitle(line string) bool {
 	trimmed := strings.TrimSpace(line)
@@ -1066,6 +1571,7 @@ func (r *ParseRules) IsStructuralLine(line string) bool {
 		return false
 	}
 	return r.ShouldIgnoreLine(line) ||
+		(r.PartRegex != nil && r.PartRegex.MatchString(line)) ||
 		(r.VolumeRegex != nil && r.VolumeRegex.MatchString(line)) ||
 		(r.ChapterRegex != nil && r.ChapterRegex.MatchString(line)) ||
 		(r.ExtraRegex != nil && r.ExtraRegex.MatchString(line)) ||
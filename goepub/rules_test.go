@@ -98,6 +98,47 @@ intro_prefixes = [
 	}
 }
 
+func TestSceneBreakPatternsCanBeExtendedByConfig(t *testing.T) {
+	isolateAutoRuleConfigDiscovery(t)
+
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "rules.toml")
+	configContent := `
+[channels.custom]
+extends_presets = ["serial"]
+scene_break_patterns = ['^<场景切换>$']
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	rules, err := buildParseRules(&Book{RuleConfigPath: configPath, RuleChannel: "custom"})
+	if err != nil {
+		t.Fatalf("build parse rules: %v", err)
+	}
+	if !rules.IsSceneBreak("<场景切换>") {
+		t.Fatal("expected custom scene break pattern to match")
+	}
+	if rules.IsSceneBreak("＊＊＊") {
+		t.Fatal("channel patterns should replace the built-in defaults")
+	}
+
+	defaults, err := buildParseRules(&Book{})
+	if err != nil {
+		t.Fatalf("build default rules: %v", err)
+	}
+	for _, line := range []string{"***", "* * *", "☆☆☆", "——————", "◆◆◆", "◆"} {
+		if !defaults.IsSceneBreak(line) {
+			t.Fatalf("expected %q to be a scene break", line)
+		}
+	}
+	for _, line := range []string{"——", "……", "**加粗**", "○"} {
+		if defaults.IsSceneBreak(line) {
+			t.Fatalf("expected %q not to be a scene break", line)
+		}
+	}
+}
+
 func TestBuildParseRulesCanApplyNamedPreset(t *testing.T) {
 	isolateAutoRuleConfigDiscovery(t)
 
@@ -0,0 +1,234 @@
+package goepub
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// 相似章节处理策略。
+const (
+	similarPolicyOff         = "off"
+	similarPolicyReport      = "report"
+	similarPolicyKeepLongest = "keep_longest"
+	similarPolicyKeepLatest  = "keep_latest"
+)
+
+const (
+	defaultSimilarChapterThreshold = 0.8
+	defaultSimilarChapterWindow    = 5
+	// similarShingleSize 是正文切片的字符长度，中文按 5 字一组比较能避开常见的短语巧合。
+	similarShingleSize = 5
+	// similarSketchSize 是每章保留的最小哈希个数，用于估算 Jaccard 相似度。
+	similarSketchSize = 128
+	// similarMinimumRunes 以下的短章节不参与比较，避免“求月票”式短章互相误判。
+	similarMinimumRunes = 50
+)
+
+// SimilarChapter 描述一对正文高度相似的章节。
+type SimilarChapter struct {
+	// Title 和 Line 是后出现的章节。
+	Title string `json:"title"`
+	Line  int    `json:"line"`
+	// OtherTitle 和 OtherLine 是与之相似的较早章节。
+	OtherTitle string `json:"other_title"`
+	OtherLine  int    `json:"other_line"`
+	// Similarity 是估算出的 Jaccard 相似度，范围 0 到 1。
+	Similarity float64 `json:"similarity"`
+	// DroppedTitle 和 DroppedLine 是按策略删除的那一章；report 模式下为空。
+	DroppedTitle string `json:"dropped_title,omitempty"`
+	DroppedLine  int    `json:"dropped_line,omitempty"`
+}
+
+// String 返回适合日志和命令行报告的描述。
+func (s SimilarChapter) String() string {
+	text := fmt.Sprintf("%s 与 %s 相似度 %.2f", s.Title, s.OtherTitle, s.Similarity)
+	if s.DroppedTitle != "" {
+		text += fmt.Sprintf("，已删除 %s", s.DroppedTitle)
+	}
+	return text
+}
+
+func normalizeSimilarPolicy(value string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", similarPolicyReport:
+		return similarPolicyReport, nil
+	case similarPolicyOff:
+		return similarPolicyOff, nil
+	case similarPolicyKeepLongest:
+		return similarPolicyKeepLongest, nil
+	case similarPolicyKeepLatest:
+		return similarPolicyKeepLatest, nil
+	}
+	return "", fmt.Errorf("不支持的策略: %s", value)
+}
+
+// chapterSketch 是单个章节的 MinHash 摘要。
+type chapterSketch struct {
+	ref    chapterRef
+	runes  int
+	hashes []uint64
+}
+
+// detectSimilarChapters 在相邻的若干章节之间比较正文相似度。
+// 正文先切成定长字符片段，再用 bottom-k MinHash 估算 Jaccard 相似度，
+// 这样每章只需保留固定大小的摘要，不必两两比较全文。
+func detectSimilarChapters(parsed *ParsedBook, rules *ParseRules) {
+	if rules.SimilarChapterPolicy == similarPolicyOff {
+		return
+	}
+
+	dropped := make(map[chapterRef]struct{})
+	window := make([]chapterSketch, 0, rules.SimilarChapterWindow)
+	var found []SimilarChapter
+
+	for vi := range parsed.Volumes {
+		for ci := range parsed.Volumes[vi].Chapters {
+			current, ok := sketchChapter(&parsed.Volumes[vi].Chapters[ci], chapterRef{volume: vi, chapter: ci})
+			if !ok {
+				continue
+			}
+
+			keepCurrent := true
+			for wi := len(window) - 1; wi >= 0; wi-- {
+				other := window[wi]
+				similarity := estimateJaccard(current.hashes, other.hashes)
+				if similarity < rules.SimilarChapterThreshold {
+					continue
+				}
+
+				currentChapter := &parsed.Volumes[vi].Chapters[ci]
+				otherChapter := &parsed.Volumes[other.ref.volume].Chapters[other.ref.chapter]
+				item := SimilarChapter{
+					Title:      currentChapter.Title,
+					Line:       currentChapter.Line,
+					OtherTitle: otherChapter.Title,
+					OtherLine:  otherChapter.Line,
+					Similarity: similarity,
+				}
+
+				dropOther := false
+				switch rules.SimilarChapterPolicy {
+				case similarPolicyKeepLatest:
+					dropOther = true
+				case similarPolicyKeepLongest:
+					dropOther = current.runes > other.runes
+					keepCurrent = dropOther
+				}
+				if rules.SimilarChapterPolicy != similarPolicyReport {
+					if dropOther {
+						dropped[other.ref] = struct{}{}
+						window = slices.Delete(window, wi, wi+1)
+						item.DroppedTitle, item.DroppedLine = otherChapter.Title, otherChapter.Line
+					} else {
+						dropped[current.ref] = struct{}{}
+						item.DroppedTitle, item.DroppedLine = currentChapter.Title, currentChapter.Line
+					}
+				}
+				found = append(found, item)
+				if !keepCurrent {
+					break
+				}
+			}
+
+			if !keepCurrent {
+				continue
+			}
+			window = append(window, current)
+			if len(window) > rules.SimilarChapterWindow {
+				window = window[1:]
+			}
+		}
+	}
+
+	for _, item := range found {
+		log.Printf("检测到相似章节: %s", item)
+	}
+	parsed.Report.SimilarChapters = found
+
+	if len(dropped) == 0 {
+		return
+	}
+	for vi := range parsed.Volumes {
+		volume := &parsed.Volumes[vi]
+		kept := make([]Chapter, 0, len(volume.Chapters))
+		for ci := range volume.Chapters {
+			if _, ok := dropped[chapterRef{volume: vi, chapter: ci}]; ok {
+				continue
+			}
+			kept = append(kept, volume.Chapters[ci])
+		}
+		volume.Chapters = kept
+	}
+}
+
+// sketchChapter 计算章节正文的 bottom-k MinHash 摘要。
+// 比较前会去掉标签、空白和标点，避免排版差异影响结果。
+func sketchChapter(chapter *Chapter, ref chapterRef) (chapterSketch, bool) {
+	text := html.UnescapeString(removeHTMLTags(chapter.Content.String()))
+	runes := make([]rune, 0, len(text)/3)
+	for _, r := range text {
+		if unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			continue
+		}
+		runes = append(runes, r)
+	}
+	if len(runes) < similarMinimumRunes {
+		return chapterSketch{}, false
+	}
+
+	hashes := make([]uint64, 0, len(runes)-similarShingleSize+1)
+	for i := 0; i+similarShingleSize <= len(runes); i++ {
+		hashes = append(hashes, hashShingle(runes[i:i+similarShingleSize]))
+	}
+	slices.Sort(hashes)
+	hashes = slices.Compact(hashes)
+	// 只复制摘要部分，避免窗口里长期引用整章的哈希数组。
+	sketch := slices.Clone(hashes[:min(len(hashes), similarSketchSize)])
+	return chapterSketch{ref: ref, runes: len(runes), hashes: sketch}, true
+}
+
+// hashShingle 对一组字符做 FNV-1a 哈希，直接按 rune 计算以避免逐片段分配字符串。
+func hashShingle(runes []rune) uint64 {
+	const (
+		offset64 = 14695981039346656037
+		prime64  = 1099511628211
+	)
+	hash := uint64(offset64)
+	for _, r := range runes {
+		for shift := 0; shift < 32; shift += 8 {
+			hash ^= uint64(byte(r >> shift))
+			hash *= prime64
+		}
+	}
+	return hash
+}
+
+// estimateJaccard 用两个有序 bottom-k 摘要估算原集合的 Jaccard 相似度：
+// 取并集中最小的 k 个哈希，统计其中同时出现在两边的比例。
+// k 取两个摘要长度的较小值，保证参与统计的哈希都落在两边摘要的覆盖范围内。
+func estimateJaccard(a, b []uint64) float64 {
+	k := min(len(a), len(b))
+	union, shared := 0, 0
+	i, j := 0, 0
+	for union < k && (i < len(a) || j < len(b)) {
+		switch {
+		case j >= len(b) || (i < len(a) && a[i] < b[j]):
+			i++
+		case i >= len(a) || b[j] < a[i]:
+			j++
+		default:
+			shared++
+			i++
+			j++
+		}
+		union++
+	}
+	if union == 0 {
+		return 0
+	}
+	return float64(shared) / float64(union)
+}
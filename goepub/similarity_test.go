@@ -0,0 +1,126 @@
+package goepub
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// similarTestBody 生成足够长且每章不同的正文，variant 用于制造少量差异。
+func similarTestBody(seed string, variant string) string {
+	lines := make([]string, 0, 12)
+	for i := 0; i < 12; i++ {
+		lines = append(lines, fmt.Sprintf("%s的第%d段，风从山谷吹来，他抬头看了看天色。", seed, i))
+	}
+	lines = append(lines, variant)
+	return strings.Join(lines, "\n")
+}
+
+func compileSimilarTestRules(t *testing.T, policy string) *ParseRules {
+	t.Helper()
+	cfg := defaultRuleConfig()
+	cfg.SimilarChapterPolicy = policy
+	rules, err := compileRuleConfig(cfg)
+	if err != nil {
+		t.Fatalf("compile rules: %v", err)
+	}
+	return rules
+}
+
+func TestDetectSimilarChaptersReportsRevisedCopy(t *testing.T) {
+	parsed := newChapterTestBook(
+		[2]string{"第11章 山谷", similarTestBody("张三", "")},
+		[2]string{"第12章 夜行", similarTestBody("李四", "")},
+		[2]string{"第12章 夜行（修）", similarTestBody("李四", "作者修订了结尾。")},
+		[2]string{"第13章 天明", similarTestBody("王五", "")},
+	)
+	detectSimilarChapters(parsed, compileSimilarTestRules(t, "report"))
+
+	if len(parsed.Volumes[0].Chapters) != 4 {
+		t.Fatalf("report mode should keep all chapters, got %d", len(parsed.Volumes[0].Chapters))
+	}
+	similar := parsed.Report.SimilarChapters
+	if len(similar) != 1 {
+		t.Fatalf("expected one similar pair, got %+v", similar)
+	}
+	if similar[0].Title != "第12章 夜行（修）" || similar[0].OtherTitle != "第12章 夜行" || similar[0].DroppedTitle != "" {
+		t.Fatalf("unexpected similar pair: %+v", similar[0])
+	}
+}
+
+func TestDetectSimilarChaptersKeepPolicies(t *testing.T) {
+	items := [][2]string{
+		{"第12章 夜行", similarTestBody("李四", "")},
+		{"第13章 天明", similarTestBody("王五", "")},
+		{"第12章 夜行", similarTestBody("李四", "多出一句话。")},
+	}
+
+	parsed := newChapterTestBook(items...)
+	detectSimilarChapters(parsed, compileSimilarTestRules(t, "keep_longest"))
+	chapters := parsed.Volumes[0].Chapters
+	if len(chapters) != 2 || chapters[0].Title != "第13章 天明" || chapters[1].Line != 30 {
+		t.Fatalf("keep_longest should keep the longer copy: %+v", chapterTitles(parsed.Volumes[0]))
+	}
+
+	parsed = newChapterTestBook(items[2], items[1], items[0])
+	detectSimilarChapters(parsed, compileSimilarTestRules(t, "keep_longest"))
+	chapters = parsed.Volumes[0].Chapters
+	if len(chapters) != 2 || chapters[0].Line != 10 {
+		t.Fatalf("keep_longest should drop the shorter later copy: %+v", parsed.Report.SimilarChapters)
+	}
+
+	parsed = newChapterTestBook(items[2], items[1], items[0])
+	detectSimilarChapters(parsed, compileSimilarTestRules(t, "keep_latest"))
+	chapters = parsed.Volumes[0].Chapters
+	if len(chapters) != 2 || chapters[1].Line != 30 || parsed.Report.SimilarChapters[0].DroppedLine != 10 {
+		t.Fatalf("keep_latest should keep the later copy: %+v", parsed.Report.SimilarChapters)
+	}
+}
+
+func TestSimilarChapterPolicyCanBeSetPerChannel(t *testing.T) {
+	isolateAutoRuleConfigDiscovery(t)
+
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "rules.toml")
+	configContent := `
+[channels.pirate]
+similar_chapter_policy = "keep_latest"
+similar_chapter_threshold = 0.7
+similar_chapter_window = 2
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	txtPath := filepath.Join(tmpDir, "pirate.txt")
+	content := strings.Join([]string{
+		"相似测试",
+		"第一章 夜行",
+		similarTestBody("李四", ""),
+		"第一章 夜行",
+		similarTestBody("李四", "修订版"),
+	}, "\n")
+	if err := os.WriteFile(txtPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write txt: %v", err)
+	}
+
+	book := &Book{Filename: txtPath, RuleConfigPath: configPath, RuleChannel: "pirate"}
+	parsed, err := ParseBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("parse book: %v", err)
+	}
+	if book.parseRules.SimilarChapterWindow != 2 || book.parseRules.SimilarChapterThreshold != 0.7 {
+		t.Fatalf("unexpected similarity rules: %+v", book.parseRules)
+	}
+	chapters := parsed.Volumes[0].Chapters
+	if len(chapters) != 1 || chapters[0].Line == 2 {
+		t.Fatalf("expected the latest copy to be kept, got %+v", parsed.Report.SimilarChapters)
+	}
+	// 相似章节已删除，序号检查不应再报告重复。
+	if len(parsed.Report.NumberingIssues) != 0 {
+		t.Fatalf("unexpected numbering issues: %+v", parsed.Report.NumberingIssues)
+	}
+}
@@ -0,0 +1,150 @@
+package goepub
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// defaultChapterSplitSize 是单个章节 XHTML 正文的默认字节数上限。
+// 旧款 Kindle、Kobo 等墨水屏阅读器打开超过约 300 KB 的 XHTML 时会卡顿甚至崩溃，这里留出一些余量。
+const defaultChapterSplitSize = 256 * 1024
+
+// chapterLayout 控制章节写成 XHTML 文件的方式。
+type chapterLayout struct {
+	// splitSize 是单个文件正文的字节数上限，超过时拆分；0 表示不拆分。
+	splitSize int
+	// packSize 大于 0 时把同一卷中相邻的章节合并写入一个文件，合并后不超过该字节数。
+	packSize int
+	// groupSize 大于 0 时，无标题的卷章节数超过它时在目录中每 groupSize 章归为一组。
+	groupSize int
+	// lang 决定分组标题的写法。
+	lang string
+}
+
+// bookChapterLayout 根据 Book 的配置确定章节文件的拆分和合并方式。
+// 合并的上限与拆分相同；关闭拆分时仍按默认上限合并。
+func bookChapterLayout(book *Book) chapterLayout {
+	layout := chapterLayout{groupSize: book.TOCGroupSize, lang: book.Lang}
+	switch {
+	case book.ChapterSplitSize > 0:
+		layout.splitSize = book.ChapterSplitSize
+	case book.ChapterSplitSize == 0:
+		layout.splitSize = defaultChapterSplitSize
+	}
+	if book.PackChapters {
+		layout.packSize = layout.splitSize
+		if layout.packSize == 0 {
+			layout.packSize = defaultChapterSplitSize
+		}
+	}
+	return layout
+}
+
+// packedChapterCount 返回从 chapters 开头起可以合并进一个文件的章节数，至少为 1。
+func packedChapterCount(chapters []Chapter, limit int) int {
+	total := 0
+	for k := range chapters {
+		// 标题转义和带锚点的标题标签按固定的开销估算。
+		size := len(chapters[k].Title) + chapters[k].Content.Len() + 96
+		if k > 0 && total+size > limit {
+			return k
+		}
+		total += size
+	}
+	return len(chapters)
+}
+
+// chapterAnchor 返回合并模式下章节标题的锚点 id。
+func chapterAnchor(volume, chapter int) string {
+	return fmt.Sprintf("volume%d_chapter%d", volume, chapter)
+}
+
+// continuationFilename 返回章节第 part 个续接文件的文件名。
+func continuationFilename(volume, chapter, part int) string {
+	return fmt.Sprintf("volume%d_chapter%d_%d.xhtml", volume, chapter, part)
+}
+
+var (
+	elementIDPattern    = regexp.MustCompile(` id="([^"]+)"`)
+	fragmentHrefPattern = regexp.MustCompile(` href="#([^"]+)"`)
+)
+
+// linkSplitParts 修正拆分后跨文件的页内链接：目标 id 落在另一个文件中时，链接改为指向那个文件。
+// 脚注引用和章末注释在长章节中常被拆进不同的文件。
+func linkSplitParts(parts []string, filenames []string) []string {
+	owners := make(map[string]int)
+	for k, part := range parts {
+		for _, m := range elementIDPattern.FindAllStringSubmatch(part, -1) {
+			owners[m[1]] = k
+		}
+	}
+	linked := make([]string, len(parts))
+	for k, part := range parts {
+		linked[k] = fragmentHrefPattern.ReplaceAllStringFunc(part, func(match string) string {
+			id := fragmentHrefPattern.FindStringSubmatch(match)[1]
+			owner, ok := owners[id]
+			if !ok || owner == k {
+				return match
+			}
+			return fmt.Sprintf(` href="%s#%s"`, filenames[owner], id)
+		})
+	}
+	return linked
+}
+
+// splitChapterBody 在顶层块元素之间的换行处把章节正文拆成不超过 limit 字节的若干段。
+// 段落、场景分隔等格式化结果都以换行结尾，因此这些换行就是可以安全断开的段落边界；
+// 单个段落超过上限时独占一段，不会从段落中间断开。limit 不大于 0 时不拆分。
+func splitChapterBody(body string, limit int) []string {
+	if limit <= 0 || len(body) <= limit {
+		return []string{body}
+	}
+	var parts []string
+	start, boundary, depth := 0, 0, 0
+	for i := 0; i < len(body); i++ {
+		switch body[i] {
+		case '<':
+			end := strings.IndexByte(body[i:], '>')
+			if end < 0 {
+				i = len(body)
+				continue
+			}
+			depth += tagDepthChange(body[i+1 : i+end])
+			if depth < 0 {
+				depth = 0
+			}
+			i += end
+		case '\n':
+			if depth > 0 {
+				continue
+			}
+			if i+1-start > limit && boundary > start {
+				parts = append(parts, body[start:boundary])
+				start = boundary
+			}
+			boundary = i + 1
+		}
+	}
+	if len(body)-start > limit && boundary > start && boundary < len(body) {
+		parts = append(parts, body[start:boundary])
+		start = boundary
+	}
+	return append(parts, body[start:])
+}
+
+// tagDepthChange 返回一个标签对元素嵌套深度的影响：开始标签加一，结束标签减一，自闭合和空元素不变。
+func tagDepthChange(tag string) int {
+	switch {
+	case strings.HasPrefix(tag, "/"):
+		return -1
+	case strings.HasPrefix(tag, "!"), strings.HasPrefix(tag, "?"), strings.HasSuffix(tag, "/"):
+		return 0
+	}
+	name, _, _ := strings.Cut(tag, " ")
+	switch strings.ToLower(name) {
+	case "br", "hr", "img", "wbr":
+		return 0
+	}
+	return 1
+}
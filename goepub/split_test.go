@@ -0,0 +1,54 @@
+package goepub
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitChapterBody(t *testing.T) {
+	paragraph := formatParagraph(strings.Repeat("字", 10))
+	quote := "<blockquote>\n<p>引文</p>\n</blockquote>\n"
+	tests := []struct {
+		name  string
+		body  string
+		limit int
+		want  []string
+	}{
+		{"under limit", paragraph + paragraph, 1024, []string{paragraph + paragraph}},
+		{"disabled", paragraph + paragraph, 0, []string{paragraph + paragraph}},
+		{"paragraph boundaries", paragraph + paragraph + paragraph, len(paragraph) * 2, []string{paragraph + paragraph, paragraph}},
+		{"oversized paragraph", paragraph + paragraph, len(paragraph) / 2, []string{paragraph, paragraph}},
+		{"nested block", paragraph + quote + paragraph, len(paragraph) + 10, []string{paragraph, quote, paragraph}},
+		{"self closing tags", "<p>a<br/>\nb</p>\n" + SceneBreak + paragraph, len(paragraph), []string{"<p>a<br/>\nb</p>\n" + SceneBreak, paragraph}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitChapterBody(tt.body, tt.limit)
+			if strings.Join(got, "") != tt.body {
+				t.Fatalf("parts do not add up to the body: %q", got)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("splitChapterBody() = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("part %d = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLinkSplitParts(t *testing.T) {
+	parts := []string{
+		`<p>甲<a href="#note1" id="noteref1">1</a><a href="#top">顶</a></p>` + "\n",
+		`<p id="top">乙</p>` + "\n" + `<aside id="note1"><p><a href="#noteref1">1</a></p></aside>` + "\n",
+	}
+	got := linkSplitParts(parts, []string{"a.xhtml", "a_1.xhtml"})
+	if want := `<p>甲<a href="a_1.xhtml#note1" id="noteref1">1</a><a href="a_1.xhtml#top">顶</a></p>` + "\n"; got[0] != want {
+		t.Fatalf("part 0 = %q, want %q", got[0], want)
+	}
+	if !strings.Contains(got[1], `<a href="a.xhtml#noteref1">`) || !strings.Contains(got[1], `<p id="top">`) {
+		t.Fatalf("unexpected part 1: %q", got[1])
+	}
+}
@@ -1,84 +1,234 @@
 package goepub
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 	"unicode/utf8"
 
-	"golang.org/x/text/encoding/simplifiedchinese"
 	"golang.org/x/text/transform"
 )
 
 const (
-	encodingAuto    = "auto"
-	encodingUTF8    = "utf-8"
-	encodingUTF8Alt = "utf8"
-	encodingGBK     = "gbk"
-	encodingGB18030 = "gb18030"
+	encodingAuto     = "auto"
+	encodingUTF8     = "utf-8"
+	encodingUTF16LE  = "utf-16le"
+	encodingUTF16BE  = "utf-16be"
+	encodingGBK      = "gbk"
+	encodingGB18030  = "gb18030"
+	encodingBig5     = "big5"
+	encodingShiftJIS = "shift_jis"
 )
 
+// utf8ValidateChunkSize 是流式校验 UTF-8 时单次读取的字节数。
+const utf8ValidateChunkSize = 64 * 1024
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// encodingAliases 把常见的编码别名归一到 supportedEncodings 中的名称。
+var encodingAliases = map[string]string{
+	"utf8":        encodingUTF8,
+	"utf-16":      encodingUTF16LE,
+	"utf16":       encodingUTF16LE,
+	"utf16le":     encodingUTF16LE,
+	"utf16be":     encodingUTF16BE,
+	"gb2312":      encodingGBK,
+	"cp936":       encodingGBK,
+	"big-5":       encodingBig5,
+	"cp950":       encodingBig5,
+	"shift-jis":   encodingShiftJIS,
+	"sjis":        encodingShiftJIS,
+	"cp932":       encodingShiftJIS,
+	"windows-31j": encodingShiftJIS,
+}
+
 // normalizeEncodingName 归一化编码名称，方便统一比较。
 func normalizeEncodingName(encoding string) string {
 	normalized := strings.ToLower(strings.TrimSpace(encoding))
-	switch normalized {
-	case "", encodingAuto:
+	if normalized == "" {
 		return encodingAuto
-	case encodingUTF8Alt:
-		return encodingUTF8
-	default:
-		return normalized
 	}
+	if alias, ok := encodingAliases[normalized]; ok {
+		return alias
+	}
+	return normalized
+}
+
+// unsupportedEncodingError 返回列出可选值的编码错误。
+func unsupportedEncodingError(encoding string) error {
+	return fmt.Errorf("不支持的文本编码: %s，可选值: %s", encoding, strings.Join(supportedEncodings, "、"))
+}
+
+// openTextReader 以流式方式打开 TXT 文件，返回已经解码为 UTF-8 的读取器。
+// 它不会把整份文件读入内存：auto/utf-8 模式只额外做一次分块的 UTF-8 校验，auto 模式再读取一段样本评分，随后回到文件开头按流解码。
+func openTextReader(path string, encoding string) (io.ReadCloser, encodingDetection, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, encodingDetection{}, fmt.Errorf("读取文件失败: %s - %w", path, err)
+	}
+
+	reader, detection, err := newDecodedReader(f, encoding)
+	if err != nil {
+		_ = f.Close()
+		return nil, encodingDetection{}, err
+	}
+	return &decodedFile{Reader: reader, file: f}, detection, nil
+}
+
+// decodedFile 把解码后的读取器和底层文件句柄绑定在一起，便于统一关闭。
+type decodedFile struct {
+	io.Reader
+	file *os.File
 }
 
-// decodeTextContent 将原始字节解码为 UTF-8 字符串。
-// 当编码为 auto 时，会优先判断是否为 UTF-8，否则回退到 GB18030。
-func decodeTextContent(raw []byte, encoding string) (string, string, error) {
-	switch normalizeEncodingName(encoding) {
-	case encodingAuto:
-		if hasUTF8BOM(raw) {
-			return string(bytes.TrimPrefix(raw, []byte{0xEF, 0xBB, 0xBF})), encodingUTF8, nil
+// Close 关闭底层文件。
+func (d *decodedFile) Close() error {
+	return d.file.Close()
+}
+
+// newDecodedReader 根据编码设置为文件构造流式解码器。
+func newDecodedReader(f *os.File, encoding string) (io.Reader, encodingDetection, error) {
+	name := normalizeEncodingName(encoding)
+	switch {
+	case name == encodingAuto:
+		return detectDecodedReader(f)
+	case name == encodingUTF8:
+		if _, err := skipUTF8BOM(f); err != nil {
+			return nil, encodingDetection{}, err
+		}
+		offset, err := f.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, encodingDetection{}, fmt.Errorf("读取文件位置失败: %w", err)
 		}
-		if utf8.Valid(raw) {
-			return string(raw), encodingUTF8, nil
+		valid, err := validateUTF8Stream(f)
+		if err != nil {
+			return nil, encodingDetection{}, err
 		}
-		decoded, err := decodeGB18030(raw)
-		return decoded, encodingGB18030, err
-	case encodingUTF8:
-		content := raw
-		if hasUTF8BOM(content) {
-			content = bytes.TrimPrefix(content, []byte{0xEF, 0xBB, 0xBF})
+		if !valid {
+			return nil, encodingDetection{}, fmt.Errorf("文件内容不是有效的 UTF-8")
 		}
-		if !utf8.Valid(content) {
-			return "", "", fmt.Errorf("文件内容不是有效的 UTF-8")
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			return nil, encodingDetection{}, fmt.Errorf("重置文件读取位置失败: %w", err)
 		}
-		return string(content), encodingUTF8, nil
-	case encodingGBK:
-		decoded, err := decodeBytes(raw, simplifiedchinese.GBK.NewDecoder())
-		return decoded, encodingGBK, err
-	case encodingGB18030:
-		decoded, err := decodeGB18030(raw)
-		return decoded, encodingGB18030, err
+		return f, encodingDetection{encoding: encodingUTF8, confidence: 1}, nil
+	case isSupportedEncoding(name):
+		return transform.NewReader(f, textEncoding(name).NewDecoder()), encodingDetection{encoding: name, confidence: 1}, nil
 	default:
-		return "", "", fmt.Errorf("不支持的文本编码: %s", encoding)
+		return nil, encodingDetection{}, unsupportedEncodingError(encoding)
 	}
 }
 
-// hasUTF8BOM 判断文本是否带有 UTF-8 BOM。
-func hasUTF8BOM(raw []byte) bool {
-	return len(raw) >= 3 && raw[0] == 0xEF && raw[1] == 0xBB && raw[2] == 0xBF
+// detectDecodedReader 自动识别文件编码：BOM 优先，其次是整份文件都有效的 UTF-8，
+// 都不满足时读取文件开头的样本给候选编码评分。
+// 除 UTF-16 外，选出的编码只作为主编码，实际按行解码以修复混合编码和乱码。
+func detectDecodedReader(f *os.File) (io.Reader, encodingDetection, error) {
+	sample := make([]byte, encodingSampleSize)
+	n, err := io.ReadFull(f, sample)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, encodingDetection{}, fmt.Errorf("读取文件失败: %w", err)
+	}
+	sample = sample[:n]
+	truncated := n == encodingSampleSize
+
+	bom, size := detectBOM(sample)
+	detection := encodingDetection{encoding: bom, confidence: 1}
+	valid := false
+	if bom == "" {
+		// UTF-8 文本不会包含 NUL 字节，而 UTF-16 的 ASCII 字符都带一个 NUL，
+		// 先排除这种情况，避免把纯英文的 UTF-16 文件误判为有效 UTF-8。
+		if bytes.IndexByte(sample, 0) < 0 {
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return nil, encodingDetection{}, fmt.Errorf("重置文件读取位置失败: %w", err)
+			}
+			if valid, err = validateUTF8Stream(f); err != nil {
+				return nil, encodingDetection{}, err
+			}
+		}
+		if valid {
+			detection.encoding = encodingUTF8
+		} else {
+			detection = detectSampleEncoding(sample, truncated)
+		}
+	}
+
+	// UTF-16 的解码器会自行识别并去掉 BOM，只有 UTF-8 需要跳过。
+	offset := int64(0)
+	if bom == encodingUTF8 {
+		offset = int64(size)
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, encodingDetection{}, fmt.Errorf("重置文件读取位置失败: %w", err)
+	}
+	if detection.encoding == encodingUTF16LE || detection.encoding == encodingUTF16BE {
+		return transform.NewReader(f, textEncoding(detection.encoding).NewDecoder()), detection, nil
+	}
+	detection.segments = newSegmentDecoder(f, detection.encoding)
+	detection.segments.validUTF8 = valid
+	return detection.segments, detection, nil
 }
 
-// decodeGB18030 使用 GB18030 解码，这个编码向下兼容常见的 GBK 文本。
-func decodeGB18030(raw []byte) (string, error) {
-	return decodeBytes(raw, simplifiedchinese.GB18030.NewDecoder())
+// skipUTF8BOM 检查文件开头是否带有 UTF-8 BOM。
+// 带 BOM 时读取位置停在 BOM 之后，否则回到文件开头。
+func skipUTF8BOM(f *os.File) (bool, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return false, fmt.Errorf("重置文件读取位置失败: %w", err)
+	}
+	head := make([]byte, len(utf8BOM))
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return false, fmt.Errorf("读取文件失败: %w", err)
+	}
+	if n == len(utf8BOM) && bytes.Equal(head, utf8BOM) {
+		return true, nil
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return false, fmt.Errorf("重置文件读取位置失败: %w", err)
+	}
+	return false, nil
 }
 
-// decodeBytes 使用指定解码器把文本转换成 UTF-8 字符串。
-func decodeBytes(raw []byte, transformer transform.Transformer) (string, error) {
-	decoded, _, err := transform.String(transformer, string(raw))
-	if err != nil {
-		return "", fmt.Errorf("解码文本失败: %w", err)
+// validateUTF8Stream 分块校验读取器中的内容是否为有效 UTF-8。
+// 块边界上被截断的多字节字符会留到下一块一起校验，因此内存占用与文件大小无关。
+func validateUTF8Stream(r io.Reader) (bool, error) {
+	reader := bufio.NewReaderSize(r, utf8ValidateChunkSize)
+	chunk := make([]byte, utf8ValidateChunkSize)
+	var carry []byte
+
+	for {
+		n, err := reader.Read(chunk)
+		if n > 0 {
+			data := append(carry, chunk[:n]...)
+			cut := incompleteRuneTail(data)
+			if !utf8.Valid(data[:cut]) {
+				return false, nil
+			}
+			carry = append(carry[:0:0], data[cut:]...)
+		}
+		if errors.Is(err, io.EOF) {
+			return len(carry) == 0, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("读取文件失败: %w", err)
+		}
+	}
+}
+
+// incompleteRuneTail 返回 data 末尾不完整多字节字符的起始位置；
+// 如果末尾字符完整，则返回 len(data)。
+func incompleteRuneTail(data []byte) int {
+	n := len(data)
+	for i := n - 1; i >= 0 && i >= n-utf8.UTFMax; i-- {
+		if !utf8.RuneStart(data[i]) {
+			continue
+		}
+		if !utf8.FullRune(data[i:]) {
+			return i
+		}
+		break
 	}
-	return decoded, nil
+	return n
 }
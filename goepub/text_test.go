@@ -2,29 +2,24 @@ package goepub
 
 import (
 	"context"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"golang.org/x/text/encoding/simplifiedchinese"
 	"golang.org/x/text/transform"
 )
 
-func TestDecodeTextContentAutoGB18030(t *testing.T) {
+func TestOpenTextReaderAutoGB18030(t *testing.T) {
 	isolateAutoRuleConfigDiscovery(t)
 
-	encoded, _, err := transform.String(simplifiedchinese.GB18030.NewEncoder(), "我不是戏神 作者：三九音域")
-	if err != nil {
-		t.Fatalf("encode gb18030: %v", err)
-	}
-
-	decoded, detectedEncoding, err := decodeTextContent([]byte(encoded), "")
-	if err != nil {
-		t.Fatalf("decode content: %v", err)
-	}
-	if detectedEncoding != encodingGB18030 {
-		t.Fatalf("expected encoding %s, got %s", encodingGB18030, detectedEncoding)
+	txtPath := writeEncodedText(t, simplifiedchinese.GB18030, "我不是戏神 作者：三九音域")
+	decoded, detection := readDetectedText(t, txtPath, "")
+	if detection.encoding != encodingGB18030 {
+		t.Fatalf("expected encoding %s, got %s", encodingGB18030, detection.encoding)
 	}
 	if decoded != "我不是戏神 作者：三九音域" {
 		t.Fatalf("unexpected decoded content: %s", decoded)
@@ -96,3 +91,76 @@ func TestVolumePatternDoesNotTreatAuthorNotesAsRealVolumes(t *testing.T) {
 		t.Fatalf("expected line to be treated as a real volume title: %s", realVolume)
 	}
 }
+
+func TestValidateUTF8StreamHandlesSplitRunes(t *testing.T) {
+	valid, err := validateUTF8Stream(iotest.OneByteReader(strings.NewReader("第一章 开始\n正文内容")))
+	if err != nil {
+		t.Fatalf("validate utf-8 stream: %v", err)
+	}
+	if !valid {
+		t.Fatal("expected multi-byte runes split across reads to be valid utf-8")
+	}
+
+	encoded, _, err := transform.String(simplifiedchinese.GB18030.NewEncoder(), "第一章 开始")
+	if err != nil {
+		t.Fatalf("encode gb18030: %v", err)
+	}
+	valid, err = validateUTF8Stream(strings.NewReader(encoded))
+	if err != nil {
+		t.Fatalf("validate gb18030 stream: %v", err)
+	}
+	if valid {
+		t.Fatal("expected gb18030 bytes not to be treated as utf-8")
+	}
+
+	valid, err = validateUTF8Stream(strings.NewReader("正文\xe7\xab"))
+	if err != nil {
+		t.Fatalf("validate truncated stream: %v", err)
+	}
+	if valid {
+		t.Fatal("expected truncated trailing rune to be invalid")
+	}
+}
+
+func TestOpenTextReaderStreamsGB18030(t *testing.T) {
+	tmpDir := t.TempDir()
+	txtPath := filepath.Join(tmpDir, "gb18030.txt")
+
+	source := strings.Repeat("第一章 开始\n这是一段正文。\n", 2000)
+	encoded, _, err := transform.String(simplifiedchinese.GB18030.NewEncoder(), source)
+	if err != nil {
+		t.Fatalf("encode gb18030: %v", err)
+	}
+	if err := os.WriteFile(txtPath, []byte(encoded), 0o644); err != nil {
+		t.Fatalf("write txt: %v", err)
+	}
+
+	reader, detection, err := openTextReader(txtPath, "")
+	if err != nil {
+		t.Fatalf("open text reader: %v", err)
+	}
+	defer reader.Close()
+
+	if detection.encoding != encodingGB18030 {
+		t.Fatalf("expected encoding %s, got %+v", encodingGB18030, detection)
+	}
+	decoded, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("read decoded text: %v", err)
+	}
+	if string(decoded) != source {
+		t.Fatal("unexpected decoded content from streaming reader")
+	}
+}
+
+func TestOpenTextReaderRejectsInvalidUTF8(t *testing.T) {
+	tmpDir := t.TempDir()
+	txtPath := filepath.Join(tmpDir, "invalid.txt")
+	if err := os.WriteFile(txtPath, []byte("第一章\n\xff\xfe"), 0o644); err != nil {
+		t.Fatalf("write txt: %v", err)
+	}
+
+	if _, _, err := openTextReader(txtPath, encodingUTF8); err == nil {
+		t.Fatal("expected invalid utf-8 content to be rejected")
+	}
+}
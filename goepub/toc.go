@@ -0,0 +1,243 @@
+package goepub
+
+import (
+	"archive/zip"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// go-epub 没有导出的目录结构常量，这里与它写出的文件布局保持一致。
+const (
+	epubContentFolder = "EPUB"
+	epubXHTMLFolder   = "xhtml"
+	epubNavFile       = "nav.xhtml"
+	epubNCXFile       = "toc.ncx"
+)
+
+// tocEntry 是 EPUB 目录中的一项。
+// go-epub 会把每个 section 都写进目录，续接文件这类不该出现在目录里的文件只能在写出后按 tocEntry 重写目录。
+type tocEntry struct {
+	title string
+	// href 是相对 EPUB 内容目录的路径，例如 xhtml/volume0_chapter0.xhtml；为空时是没有标题页的分组。
+	href     string
+	children []*tocEntry
+}
+
+// epubTOC 记录写入 EPUB 的目录结构；rewrite 为 true 时它与 go-epub 生成的目录不同，需要重写。
+type epubTOC struct {
+	entries []*tocEntry
+	rewrite bool
+}
+
+// add 在 parent 下追加一项，parent 为 nil 时追加到顶层。
+func (t *epubTOC) add(parent *tocEntry, title, filename string) *tocEntry {
+	entry := &tocEntry{title: title, href: path.Join(epubXHTMLFolder, filename)}
+	if parent == nil {
+		t.entries = append(t.entries, entry)
+	} else {
+		parent.children = append(parent.children, entry)
+	}
+	return entry
+}
+
+// addGroup 在 parent 下追加一个没有标题页的分组，只用来在目录中收纳子项。
+func (t *epubTOC) addGroup(parent *tocEntry, title string) *tocEntry {
+	entry := &tocEntry{title: title}
+	if parent == nil {
+		t.entries = append(t.entries, entry)
+	} else {
+		parent.children = append(parent.children, entry)
+	}
+	t.rewrite = true
+	return entry
+}
+
+// firstHref 返回目录项自身或第一个子项的链接。
+func (e *tocEntry) firstHref() string {
+	if e.href != "" || len(e.children) == 0 {
+		return e.href
+	}
+	return e.children[0].firstHref()
+}
+
+// tocGroupFormats 是各语言预设下章节分组标题的格式，默认使用中文。
+var tocGroupFormats = map[string]string{
+	englishRulePreset:  "Chapters %d–%d",
+	japaneseRulePreset: "第%d–%d話",
+}
+
+// tocGroupTitle 返回一组章节在目录中的分组标题，例如“第1–100章”。
+// 章节带有序号时使用首尾章节的序号，否则使用它们在卷中的位置，start 是第一章的下标。
+func tocGroupTitle(lang string, chapters []Chapter, start int) string {
+	format, ok := tocGroupFormats[languageRulePreset(lang)]
+	if !ok {
+		format = "第%d–%d章"
+	}
+	first, last := 0, 0
+	for _, chapter := range chapters {
+		if chapter.Number <= 0 {
+			continue
+		}
+		if first == 0 {
+			first = chapter.Number
+		}
+		last = chapter.Number
+	}
+	if first == 0 || last < first {
+		first, last = start+1, start+len(chapters)
+	}
+	return fmt.Sprintf(format, first, last)
+}
+
+// rewriteTOC 用 toc 替换已写出 EPUB 中的 nav.xhtml 和 toc.ncx 目录列表，其余文件原样复制。
+func rewriteTOC(output string, toc *epubTOC) error {
+	nav := path.Join(epubContentFolder, epubNavFile)
+	ncx := path.Join(epubContentFolder, epubNCXFile)
+	return rewriteEPUBFiles(output, map[string]func([]byte) ([]byte, error){
+		nav: func(data []byte) ([]byte, error) {
+			var list strings.Builder
+			list.WriteString("<ol>\n")
+			writeNavEntries(&list, toc.entries, 4)
+			list.WriteString("      </ol>")
+			return replaceBetween(data, `<nav epub:type="toc">`, "<ol>", "</ol>", list.String())
+		},
+		ncx: func(data []byte) ([]byte, error) {
+			var navMap strings.Builder
+			navMap.WriteString("<navMap>\n")
+			index := 0
+			writeNCXEntries(&navMap, toc.entries, 2, &index)
+			navMap.WriteString("  </navMap>")
+			return replaceBetween(data, "<ncx", "<navMap>", "</navMap>", navMap.String())
+		},
+	})
+}
+
+// writeNavEntries 输出 EPUB3 目录的列表项，depth 是当前层级的缩进级别。
+func writeNavEntries(b *strings.Builder, entries []*tocEntry, depth int) {
+	indent := strings.Repeat("  ", depth)
+	for _, entry := range entries {
+		if entry.href == "" {
+			// EPUB3 目录允许用 span 表示不可跳转的分组标题。
+			fmt.Fprintf(b, "%s<li>\n%s  <span>%s</span>\n", indent, indent, html.EscapeString(entry.title))
+		} else {
+			fmt.Fprintf(b, "%s<li>\n%s  <a href=\"%s\">%s</a>\n", indent, indent, html.EscapeString(entry.href), html.EscapeString(entry.title))
+		}
+		if len(entry.children) > 0 {
+			fmt.Fprintf(b, "%s  <ol>\n", indent)
+			writeNavEntries(b, entry.children, depth+2)
+			fmt.Fprintf(b, "%s  </ol>\n", indent)
+		}
+		fmt.Fprintf(b, "%s</li>\n", indent)
+	}
+}
+
+// writeNCXEntries 输出 EPUB2 目录的 navPoint，编号按出现顺序从 1 开始，与 go-epub 的写法一致。
+// NCX 的每一项都必须指向内容，分组指向它的第一个子项。
+func writeNCXEntries(b *strings.Builder, entries []*tocEntry, depth int, index *int) {
+	indent := strings.Repeat("  ", depth)
+	for _, entry := range entries {
+		*index++
+		fmt.Fprintf(b, "%s<navPoint id=\"navPoint-%d\">\n", indent, *index)
+		fmt.Fprintf(b, "%s  <navLabel>\n%s    <text>%s</text>\n%s  </navLabel>\n", indent, indent, html.EscapeString(entry.title), indent)
+		fmt.Fprintf(b, "%s  <content src=\"%s\"></content>\n", indent, html.EscapeString(entry.firstHref()))
+		writeNCXEntries(b, entry.children, depth+1, index)
+		fmt.Fprintf(b, "%s</navPoint>\n", indent)
+	}
+}
+
+// replaceBetween 在 anchor 之后找到第一个 start 和最后一个 end，用 replacement 替换包括二者在内的内容。
+func replaceBetween(data []byte, anchor, start, end, replacement string) ([]byte, error) {
+	text := string(data)
+	from := strings.Index(text, anchor)
+	if from < 0 {
+		return nil, fmt.Errorf("目录文件中缺少 %s", anchor)
+	}
+	open := strings.Index(text[from:], start)
+	closing := strings.LastIndex(text, end)
+	if open < 0 || closing < from+open {
+		return nil, fmt.Errorf("目录文件中缺少 %s", start)
+	}
+	open += from
+	return []byte(text[:open] + replacement + text[closing+len(end):]), nil
+}
+
+// rewriteEPUBFiles 按 updates 改写 EPUB 中的指定文件。
+// 其余文件按原始的压缩数据复制，mimetype 仍是第一个不压缩的文件；新文件写完后再替换原文件。
+func rewriteEPUBFiles(output string, updates map[string]func([]byte) ([]byte, error)) error {
+	reader, err := zip.OpenReader(output)
+	if err != nil {
+		return fmt.Errorf("打开 EPUB 失败: %w", err)
+	}
+	defer reader.Close()
+
+	temp, err := os.CreateTemp(filepath.Dir(output), ".gotexttoepub-*.epub")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	tempName := temp.Name()
+	defer func() {
+		_ = temp.Close()
+		_ = os.Remove(tempName)
+	}()
+	// 临时文件默认只有属主可读，替换后保持原文件的权限。
+	if info, err := os.Stat(output); err == nil {
+		if err := temp.Chmod(info.Mode().Perm()); err != nil {
+			return fmt.Errorf("设置 EPUB 文件权限失败: %w", err)
+		}
+	}
+
+	writer := zip.NewWriter(temp)
+	for _, file := range reader.File {
+		update, ok := updates[file.Name]
+		if !ok {
+			if err := writer.Copy(file); err != nil {
+				return fmt.Errorf("复制 EPUB 文件失败 %s: %w", file.Name, err)
+			}
+			continue
+		}
+		data, err := readZipFile(file)
+		if err != nil {
+			return err
+		}
+		if data, err = update(data); err != nil {
+			return fmt.Errorf("改写 EPUB 文件失败 %s: %w", file.Name, err)
+		}
+		w, err := writer.CreateHeader(&zip.FileHeader{Name: file.Name, Method: zip.Deflate, Modified: file.Modified})
+		if err != nil {
+			return fmt.Errorf("写入 EPUB 文件失败 %s: %w", file.Name, err)
+		}
+		if _, err := w.Write(data); err != nil {
+			return fmt.Errorf("写入 EPUB 文件失败 %s: %w", file.Name, err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("写入 EPUB 失败: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("写入 EPUB 失败: %w", err)
+	}
+	// Windows 上替换仍被打开的文件会失败，先关闭原文件。
+	_ = reader.Close()
+	if err := os.Rename(tempName, output); err != nil {
+		return fmt.Errorf("替换 EPUB 文件失败: %w", err)
+	}
+	return nil
+}
+
+func readZipFile(file *zip.File) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("读取 EPUB 文件失败 %s: %w", file.Name, err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("读取 EPUB 文件失败 %s: %w", file.Name, err)
+	}
+	return data, nil
+}
@@ -21,6 +21,7 @@ import (
 
 const (
 	inputFile  = "input.txt"
+	bundleFile = "input.zip"
 	coverFile  = "cover"
 	outputFile = "output.epub"
 	metaFile   = "job.json"
@@ -69,6 +70,24 @@ type Job struct {
 	OutputName    string     `json:"outputName,omitempty"`
 	OutputSize    int64      `json:"outputSize,omitempty"`
 	ErrorCode     string     `json:"errorCode,omitempty"`
+	Options       Options    `json:"options"`
+}
+
+// Options carries the conversion settings chosen at submission time.
+// They are persisted with the job so recovered jobs convert the same way.
+type Options struct {
+	Reflow            string `json:"reflow,omitempty"`
+	ChineseConversion string `json:"chineseConversion,omitempty"`
+	// Bundle marks the input as a ZIP archive holding the TXT and the images it references.
+	Bundle bool `json:"bundle,omitempty"`
+}
+
+// inputName returns the file name the job input is stored under.
+func (o Options) inputName() string {
+	if o.Bundle {
+		return bundleFile
+	}
+	return inputFile
 }
 
 // SubmitInput transfers ownership of InputPath and CoverPath to Manager.
@@ -80,6 +99,7 @@ type SubmitInput struct {
 	OwnerHash    string
 	OriginalName string
 	InputSize    int64
+	Options      Options
 }
 
 type ConvertFunc func(
@@ -271,7 +291,7 @@ func (m *Manager) Submit(_ context.Context, in SubmitInput) (Job, error) {
 	if err := os.Mkdir(dir, 0o700); err != nil {
 		return Job{}, fmt.Errorf("create job directory: %w", err)
 	}
-	if err := moveOwnedFile(in.InputPath, filepath.Join(dir, inputFile)); err != nil {
+	if err := moveOwnedFile(in.InputPath, filepath.Join(dir, in.Options.inputName())); err != nil {
 		_ = os.RemoveAll(dir)
 		return Job{}, fmt.Errorf("store input file: %w", err)
 	}
@@ -295,6 +315,7 @@ func (m *Manager) Submit(_ context.Context, in SubmitInput) (Job, error) {
 		Status:        StatusQueued,
 		CreatedAt:     now,
 		QueuePosition: len(m.queueOrder) + 1,
+		Options:       in.Options,
 	}
 	if err := m.persistLocked(job); err != nil {
 		_ = os.RemoveAll(dir)
@@ -467,7 +488,7 @@ func (m *Manager) runJob(id string) {
 	job.QueuePosition = 0
 	_ = m.persistLocked(job)
 	snapshot := *job
-	inputPath := filepath.Join(m.jobDir(id), inputFile)
+	inputPath := filepath.Join(m.jobDir(id), job.Options.inputName())
 	coverPath := filepath.Join(m.jobDir(id), coverFile)
 	if _, err := os.Stat(coverPath); errors.Is(err, fs.ErrNotExist) {
 		coverPath = ""
@@ -523,7 +544,7 @@ func (m *Manager) runJob(id string) {
 		_ = os.Remove(filepath.Join(m.jobDir(id), outputFile))
 	}
 	m.finishIPLocked(job)
-	_ = os.Remove(filepath.Join(m.jobDir(id), inputFile))
+	_ = os.Remove(filepath.Join(m.jobDir(id), job.Options.inputName()))
 	_ = os.Remove(filepath.Join(m.jobDir(id), coverFile))
 	_ = os.RemoveAll(filepath.Join(m.jobDir(id), workDir))
 	_ = m.persistLocked(job)
@@ -724,7 +745,7 @@ func (m *Manager) recoverLocked() ([]string, error) {
 		_ = os.RemoveAll(filepath.Join(dir, workDir))
 		switch job.Status {
 		case StatusQueued, StatusConverting:
-			if info, statErr := os.Stat(filepath.Join(dir, inputFile)); statErr == nil &&
+			if info, statErr := os.Stat(filepath.Join(dir, job.Options.inputName())); statErr == nil &&
 				info.Mode().IsRegular() {
 				job.Status = StatusQueued
 				job.QueuePosition = 0
@@ -235,6 +235,78 @@ func TestRecoveryRequeuesInterruptedJobs(t *testing.T) {
 	}
 }
 
+func TestSubmitPassesOptionsToConverter(t *testing.T) {
+	received := make(chan Options, 1)
+	root := filepath.Join(t.TempDir(), "jobs")
+	manager := startTestManager(t, Config{
+		DataDir:   root,
+		Workers:   1,
+		QueueSize: 1,
+		Convert: func(ctx context.Context, job *Job, inputPath, coverPath string) (string, int64, error) {
+			received <- job.Options
+			return fixedOutput(8)(ctx, job, inputPath, coverPath)
+		},
+	})
+
+	job, err := manager.Submit(context.Background(), SubmitInput{
+		InputPath:    createInput(t, "novel"),
+		ClientIP:     "192.0.2.9",
+		OwnerHash:    "owner",
+		OriginalName: "novel.txt",
+		Options:      Options{Reflow: "force"},
+	})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if got := <-received; got.Reflow != "force" {
+		t.Fatalf("converter received options %+v", got)
+	}
+	waitForStatus(t, manager, job.ID, "owner", StatusSucceeded)
+
+	raw, err := os.ReadFile(filepath.Join(root, job.ID, metaFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var persisted Job
+	if err := json.Unmarshal(raw, &persisted); err != nil {
+		t.Fatal(err)
+	}
+	if persisted.Options.Reflow != "force" {
+		t.Fatalf("persisted options = %+v", persisted.Options)
+	}
+}
+
+func TestSubmitStoresBundleInput(t *testing.T) {
+	received := make(chan string, 1)
+	root := filepath.Join(t.TempDir(), "jobs")
+	manager := startTestManager(t, Config{
+		DataDir:   root,
+		Workers:   1,
+		QueueSize: 1,
+		Convert: func(ctx context.Context, job *Job, inputPath, coverPath string) (string, int64, error) {
+			received <- inputPath
+			return fixedOutput(8)(ctx, job, inputPath, coverPath)
+		},
+	})
+
+	job, err := manager.Submit(context.Background(), SubmitInput{
+		InputPath: createInput(t, "zip"),
+		ClientIP:  "192.0.2.10",
+		OwnerHash: "owner",
+		Options:   Options{Bundle: true},
+	})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if got := <-received; got != filepath.Join(root, job.ID, bundleFile) {
+		t.Fatalf("converter received input %q", got)
+	}
+	waitForStatus(t, manager, job.ID, "owner", StatusSucceeded)
+	if _, err := os.Stat(filepath.Join(root, job.ID, bundleFile)); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("bundle input was not removed after conversion: %v", err)
+	}
+}
+
 func TestCleanupPurgesTerminalJobAfterRetention(t *testing.T) {
 	clock := &fakeClock{now: time.Date(2026, 7, 26, 12, 0, 0, 0, time.UTC)}
 	manager := startTestManager(t, Config{
@@ -0,0 +1,186 @@
+package webapp
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// 打包上传的 ZIP 中只解出 TXT 和它引用的插图，其余文件忽略。
+const (
+	// maxBundleEntries 限制 ZIP 中的条目数，避免大量小文件拖慢解压。
+	maxBundleEntries = 2000
+	// maxBundleExpansion 是解压后总大小与上传大小上限的最大倍数。
+	// 图片本身已经压缩过，正常的插图包解压后不会比 ZIP 大多少。
+	maxBundleExpansion = 4
+)
+
+// bundleImageExtensions 是会被解出的插图扩展名，与 goepub 查找插图时尝试的扩展名一致。
+var bundleImageExtensions = map[string]struct{}{".jpg": {}, ".jpeg": {}, ".png": {}, ".gif": {}, ".webp": {}}
+
+// bundleEntry 是 ZIP 中需要解出的一个文件，name 是清理后的相对路径。
+type bundleEntry struct {
+	file *zip.File
+	name string
+}
+
+// readBundle 检查上传的 ZIP，返回其中唯一的 TXT 和所有插图。
+// maxTextBytes 限制 TXT 解压后的大小，maxTotalBytes 限制所有解出文件的总大小；
+// 这里使用 ZIP 目录中声明的大小，解压时 archive/zip 会拒绝实际内容超过声明大小的条目。
+func readBundle(reader *zip.Reader, maxTextBytes, maxTotalBytes int64) (text bundleEntry, images []bundleEntry, err error) {
+	if len(reader.File) > maxBundleEntries {
+		return bundleEntry{}, nil, fmt.Errorf("%w: ZIP 中的文件过多", errInvalidUpload)
+	}
+	var total uint64
+	for _, file := range reader.File {
+		if !file.Mode().IsRegular() {
+			continue
+		}
+		name, ok := bundleEntryName(file)
+		if !ok {
+			continue
+		}
+		ext := strings.ToLower(path.Ext(name))
+		_, isImage := bundleImageExtensions[ext]
+		if ext != ".txt" && !isImage {
+			continue
+		}
+		total += file.UncompressedSize64
+		if total > uint64(maxTotalBytes) {
+			return bundleEntry{}, nil, errUploadTooLarge
+		}
+		if isImage {
+			images = append(images, bundleEntry{file: file, name: name})
+			continue
+		}
+		if text.file != nil {
+			return bundleEntry{}, nil, fmt.Errorf("%w: ZIP 中只能包含一个 TXT 文件", errInvalidUpload)
+		}
+		if file.UncompressedSize64 > uint64(maxTextBytes) {
+			return bundleEntry{}, nil, errUploadTooLarge
+		}
+		text = bundleEntry{file: file, name: name}
+	}
+	if text.file == nil {
+		return bundleEntry{}, nil, fmt.Errorf("%w: ZIP 中没有 TXT 文件", errInvalidUpload)
+	}
+	return text, images, nil
+}
+
+// bundleEntryName 返回条目清理后的相对路径。
+// 绝对路径、跳出解压目录的路径以及 macOS 压缩时附带的资源文件都会被跳过。
+func bundleEntryName(file *zip.File) (string, bool) {
+	name := file.Name
+	if file.NonUTF8 && !utf8.ValidString(name) {
+		// Windows 上压缩的中文文件名通常是 GBK 编码。
+		if decoded, err := simplifiedchinese.GB18030.NewDecoder().String(name); err == nil {
+			name = decoded
+		}
+	}
+	name = strings.ReplaceAll(name, "\\", "/")
+	if strings.HasPrefix(name, "/") || strings.Contains(name, ":") {
+		return "", false
+	}
+	name = path.Clean(name)
+	if name == "." || name == ".." || strings.HasPrefix(name, "../") {
+		return "", false
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if segment == "__MACOSX" || strings.HasPrefix(segment, ".") {
+			return "", false
+		}
+	}
+	return name, true
+}
+
+// validateUploadedBundle 在接收上传时检查 ZIP 的结构和大小，并确认其中的 TXT 不是二进制文件。
+func validateUploadedBundle(file *os.File, size, maxUploadBytes int64) error {
+	reader, err := zip.NewReader(file, size)
+	if err != nil {
+		return fmt.Errorf("%w: 文件不是有效的 ZIP", errInvalidUpload)
+	}
+	text, _, err := readBundle(reader, maxUploadBytes, maxUploadBytes*maxBundleExpansion)
+	if err != nil {
+		return err
+	}
+	rc, err := text.file.Open()
+	if err != nil {
+		return fmt.Errorf("%w: 无法读取 ZIP 中的 TXT 文件", errInvalidUpload)
+	}
+	defer rc.Close()
+	sample := make([]byte, 8192)
+	n, err := io.ReadFull(rc, sample)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: 无法读取 ZIP 中的 TXT 文件", errInvalidUpload)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: TXT 文件不能为空", errInvalidUpload)
+	}
+	if bytes.IndexByte(sample[:n], 0) >= 0 {
+		return fmt.Errorf("%w: 文件包含二进制 NUL 字节", errInvalidUpload)
+	}
+	return nil
+}
+
+// extractBundle 把 ZIP 中的 TXT 和插图按原目录结构解压到 dir，返回 TXT 的路径。
+// ZIP 在上传时已经检查过，这里沿用声明的大小作为上限。
+func extractBundle(bundlePath, dir string) (string, error) {
+	reader, err := zip.OpenReader(bundlePath)
+	if err != nil {
+		return "", fmt.Errorf("打开 ZIP 失败: %w", err)
+	}
+	defer reader.Close()
+
+	text, images, err := readBundle(&reader.Reader, math.MaxInt64, math.MaxInt64)
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range append(images, text) {
+		target := filepath.Join(dir, filepath.FromSlash(entry.name))
+		if !pathWithin(dir, target) {
+			return "", fmt.Errorf("ZIP 中的文件路径无效: %s", entry.name)
+		}
+		if err := extractBundleFile(entry.file, target); err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(dir, filepath.FromSlash(text.name)), nil
+}
+
+func extractBundleFile(file *zip.File, target string) (retErr error) {
+	if err := os.MkdirAll(filepath.Dir(target), 0o700); err != nil {
+		return fmt.Errorf("创建解压目录失败: %w", err)
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("读取 ZIP 文件失败 %s: %w", file.Name, err)
+	}
+	defer rc.Close()
+	// 同名条目只保留第一个。
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o600)
+	if errors.Is(err, os.ErrExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("创建解压文件失败: %w", err)
+	}
+	defer func() {
+		if closeErr := out.Close(); retErr == nil && closeErr != nil {
+			retErr = fmt.Errorf("关闭解压文件失败: %w", closeErr)
+		}
+	}()
+	if _, err := io.Copy(out, io.LimitReader(rc, int64(file.UncompressedSize64))); err != nil {
+		return fmt.Errorf("解压 ZIP 文件失败 %s: %w", file.Name, err)
+	}
+	return nil
+}
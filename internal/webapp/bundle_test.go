@@ -0,0 +1,161 @@
+package webapp
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lifei6671/gotexttoepub/internal/jobs"
+)
+
+func TestParseUploadBundle(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string][]byte
+		wantErr error
+	}{
+		{name: "TXT和插图", files: map[string][]byte{"书/书.txt": []byte("第一章\n[img]001.png[/img]"), "书/插图/001.png": testPNG(t)}},
+		{name: "缺少TXT", files: map[string][]byte{"插图/001.png": testPNG(t)}, wantErr: errInvalidUpload},
+		{name: "多个TXT", files: map[string][]byte{"a.txt": []byte("甲"), "b.txt": []byte("乙")}, wantErr: errInvalidUpload},
+		{name: "忽略跳出目录的TXT", files: map[string][]byte{"../a.txt": []byte("甲")}, wantErr: errInvalidUpload},
+		{name: "拒绝二进制TXT", files: map[string][]byte{"a.txt": {'a', 0}}, wantErr: errInvalidUpload},
+		{name: "拒绝解压后过大", files: map[string][]byte{"a.txt": bytes.Repeat([]byte("字"), 2048)}, wantErr: errUploadTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, contentType := multipartTXT(t, "插图版.zip", string(testBundle(t, tt.files)))
+			req := httptest.NewRequest("POST", "/api/conversions", body)
+			req.Header.Set("Content-Type", contentType)
+			incoming := filepath.Join(t.TempDir(), "incoming")
+			got, err := parseUpload(httptest.NewRecorder(), req, incoming, 4096, 1024)
+			if tt.wantErr != nil {
+				if err == nil || !errors.Is(err, tt.wantErr) {
+					t.Fatalf("parseUpload() error = %v, want %v", err, tt.wantErr)
+				}
+				if entries, readErr := os.ReadDir(incoming); readErr == nil && len(entries) != 0 {
+					t.Fatalf("failed upload left files: %v", entries)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseUpload() error = %v", err)
+			}
+			t.Cleanup(func() { _ = os.Remove(got.InputPath) })
+			if !got.Bundle || filepath.Ext(got.InputPath) != ".zip" || got.OriginalName != "插图版.zip" {
+				t.Fatalf("unexpected bundle upload: %+v", got)
+			}
+		})
+	}
+}
+
+func TestConvertEPUBExtractsBundle(t *testing.T) {
+	jobDir := t.TempDir()
+	inputPath := filepath.Join(jobDir, "input.zip")
+	bundle := testBundle(t, map[string][]byte{
+		"长夜/长夜.txt":            []byte("长夜\n第一章 开始\n正文\n[img]001.png[/img]\n<插图02>"),
+		"长夜/插图/001.png":        testPNG(t),
+		"长夜/插图/02.png":         testPNG(t),
+		"__MACOSX/长夜/._长夜.txt": []byte("resource fork"),
+	})
+	if err := os.WriteFile(inputPath, bundle, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	job := &jobs.Job{Options: jobs.Options{Bundle: true}}
+	name, _, err := ConvertEPUB(context.Background(), job, inputPath, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "长夜.epub" {
+		t.Fatalf("output display name = %q", name)
+	}
+	reader, err := zip.OpenReader(filepath.Join(jobDir, "output.epub"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	images := 0
+	for _, file := range reader.File {
+		if strings.HasPrefix(filepath.Base(file.Name), "inline") {
+			images++
+		}
+	}
+	if images != 2 {
+		t.Fatalf("expected both illustrations to be packed, got %d", images)
+	}
+	if _, err := os.Stat(filepath.Join(jobDir, "work")); !os.IsNotExist(err) {
+		t.Fatalf("extracted files should be removed with the work directory: %v", err)
+	}
+}
+
+func TestConvertEPUBSkipsImagesForPlainTXT(t *testing.T) {
+	jobDir := t.TempDir()
+	inputPath := filepath.Join(jobDir, "input.txt")
+	content := "长夜\n第一章 开始\n正文\n[img]job.json[/img]\n[img]cover[/img]\n[img]leak.png[/img]"
+	if err := os.WriteFile(inputPath, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"job.json", "cover", "leak.png"} {
+		if err := os.WriteFile(filepath.Join(jobDir, name), testPNG(t), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, err := ConvertEPUB(context.Background(), &jobs.Job{}, inputPath, ""); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := zip.OpenReader(filepath.Join(jobDir, "output.epub"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	for _, file := range reader.File {
+		if strings.HasPrefix(filepath.Base(file.Name), "inline") {
+			t.Fatalf("files from the job directory should not be packed: %s", file.Name)
+		}
+	}
+}
+
+func TestBundleEntryNameDecodesGBK(t *testing.T) {
+	// “插图/1.png”的 GBK 编码。
+	file := &zip.File{FileHeader: zip.FileHeader{Name: "\xb2\xe5\xcd\xbc/1.png", NonUTF8: true}}
+	if name, ok := bundleEntryName(file); !ok || name != "插图/1.png" {
+		t.Fatalf("bundleEntryName() = %q, %v", name, ok)
+	}
+}
+
+// testBundle 按 files 生成一个 ZIP。
+func testBundle(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for name, data := range files {
+		w, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// testPNG 返回一张 1x1 的 PNG 图片。
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
@@ -13,7 +13,7 @@ import (
 )
 
 // ConvertEPUB 将任务目录中的受信任输入交给现有转换器，并原子发布最终文件。
-func ConvertEPUB(ctx context.Context, _ *jobs.Job, inputPath, coverPath string) (string, int64, error) {
+func ConvertEPUB(ctx context.Context, job *jobs.Job, inputPath, coverPath string) (string, int64, error) {
 	jobDir := filepath.Dir(inputPath)
 	workDir := filepath.Join(jobDir, "work")
 	if err := os.RemoveAll(workDir); err != nil {
@@ -26,10 +26,26 @@ func ConvertEPUB(ctx context.Context, _ *jobs.Job, inputPath, coverPath string)
 		_ = os.RemoveAll(workDir)
 	}()
 
+	filename := inputPath
+	if job != nil && job.Options.Bundle {
+		// 插图按 TXT 所在目录查找，ZIP 按原目录结构解压到工作目录中。
+		sourceDir := filepath.Join(workDir, "source")
+		var err error
+		if filename, err = extractBundle(inputPath, sourceDir); err != nil {
+			return "", 0, err
+		}
+	}
+
 	book := &goepub.Book{
-		Filename: inputPath,
+		Filename: filename,
 		Cover:    coverPath,
 		Output:   workDir,
+		// 单独上传的 TXT 没有附带插图，任务目录中只有任务自身的文件，不在其中查找插图。
+		DisableImageLookup: job == nil || !job.Options.Bundle,
+	}
+	if job != nil {
+		book.ReflowMode = job.Options.Reflow
+		book.ChineseConversion = job.Options.ChineseConversion
 	}
 	if err := goepub.NewEPUBConverter().Convert(ctx, book); err != nil {
 		return "", 0, fmt.Errorf("转换 EPUB 失败: %w", err)
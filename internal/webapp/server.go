@@ -234,6 +234,11 @@ func (s *Server) handleConversions(w http.ResponseWriter, r *http.Request) {
 		OwnerHash:    ownerHash,
 		OriginalName: upload.OriginalName,
 		InputSize:    upload.InputSize,
+		Options: jobs.Options{
+			Reflow:            upload.Reflow,
+			ChineseConversion: upload.ZhConvert,
+			Bundle:            upload.Bundle,
+		},
 	})
 	if err != nil {
 		s.writeManagerError(w, err)
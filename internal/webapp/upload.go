@@ -26,12 +26,25 @@ var (
 	maxCoverImagePixel = uint64(25_000_000)
 )
 
+// maxUploadParts 是 file、cover_file、cover_url 加上转换选项字段的总数。
+const maxUploadParts = 5
+
+// reflowModes 是 reflow 字段允许的取值，与 goepub 的折行重排模式一致。
+var reflowModes = map[string]struct{}{"auto": {}, "off": {}, "force": {}}
+
+// chineseConversions 是 zh_convert 字段允许的取值，与 goepub.ChineseConversions 一致。
+var chineseConversions = map[string]struct{}{"s2t": {}, "t2s": {}, "s2tw": {}, "s2hk": {}}
+
 type uploadedRequest struct {
 	InputPath    string
 	OriginalName string
 	InputSize    int64
 	CoverPath    string
 	CoverURL     string
+	Reflow       string
+	ZhConvert    string
+	// Bundle 表示上传的是包含 TXT 和插图的 ZIP。
+	Bundle bool
 }
 
 func parseUpload(w http.ResponseWriter, r *http.Request, incomingDir string, maxUploadBytes, maxCoverBytes int64) (_ *uploadedRequest, retErr error) {
@@ -60,7 +73,7 @@ func parseUpload(w http.ResponseWriter, r *http.Request, incomingDir string, max
 		}
 	}()
 
-	seenFile, seenCoverFile, seenCoverURL := false, false, false
+	seenFile, seenCoverFile, seenCoverURL, seenReflow, seenZhConvert := false, false, false, false, false
 	partCount := 0
 	for {
 		part, err := reader.NextPart()
@@ -75,9 +88,9 @@ func parseUpload(w http.ResponseWriter, r *http.Request, incomingDir string, max
 			return nil, fmt.Errorf("%w: 读取上传内容失败", errInvalidUpload)
 		}
 		partCount++
-		if partCount > 3 {
+		if partCount > maxUploadParts {
 			_ = part.Close()
-			return nil, fmt.Errorf("%w: 只允许 file、cover_file 和 cover_url 三个字段", errInvalidUpload)
+			return nil, fmt.Errorf("%w: 只允许 file、cover_file、cover_url、reflow 和 zh_convert 字段", errInvalidUpload)
 		}
 
 		switch part.FormName() {
@@ -87,11 +100,13 @@ func parseUpload(w http.ResponseWriter, r *http.Request, incomingDir string, max
 				return nil, fmt.Errorf("%w: file 字段必须且只能上传一个文件", errInvalidUpload)
 			}
 			seenFile = true
-			if !strings.EqualFold(filepath.Ext(part.FileName()), ".txt") {
+			extension := strings.ToLower(filepath.Ext(part.FileName()))
+			if extension != ".txt" && extension != ".zip" {
 				_ = part.Close()
-				return nil, fmt.Errorf("%w: 只支持 .txt 文件", errInvalidUpload)
+				return nil, fmt.Errorf("%w: 只支持 .txt 文件或包含 TXT 和插图的 .zip 文件", errInvalidUpload)
 			}
-			path, size, err := writeUploadedTXT(part, incomingDir, maxUploadBytes)
+			bundle := extension == ".zip"
+			path, size, err := writeUploadedTXT(part, incomingDir, maxUploadBytes, bundle)
 			_ = part.Close()
 			if err != nil {
 				return nil, err
@@ -99,6 +114,7 @@ func parseUpload(w http.ResponseWriter, r *http.Request, incomingDir string, max
 			result.InputPath = path
 			result.InputSize = size
 			result.OriginalName = cleanDisplayFilename(part.FileName())
+			result.Bundle = bundle
 		case "cover_file":
 			if seenCoverFile || part.FileName() == "" {
 				_ = part.Close()
@@ -126,6 +142,30 @@ func parseUpload(w http.ResponseWriter, r *http.Request, incomingDir string, max
 				return nil, fmt.Errorf("%w: 封面链接过长", errInvalidUpload)
 			}
 			result.CoverURL = strings.TrimSpace(string(value))
+		case "reflow":
+			if seenReflow || part.FileName() != "" {
+				_ = part.Close()
+				return nil, fmt.Errorf("%w: reflow 字段无效", errInvalidUpload)
+			}
+			seenReflow = true
+			value, err := readOptionField(part, reflowModes)
+			_ = part.Close()
+			if err != nil {
+				return nil, fmt.Errorf("%w: reflow 字段无效", errInvalidUpload)
+			}
+			result.Reflow = value
+		case "zh_convert":
+			if seenZhConvert || part.FileName() != "" {
+				_ = part.Close()
+				return nil, fmt.Errorf("%w: zh_convert 字段无效", errInvalidUpload)
+			}
+			seenZhConvert = true
+			value, err := readOptionField(part, chineseConversions)
+			_ = part.Close()
+			if err != nil {
+				return nil, fmt.Errorf("%w: zh_convert 字段无效", errInvalidUpload)
+			}
+			result.ZhConvert = value
 		default:
 			_ = part.Close()
 			return nil, fmt.Errorf("%w: 不支持字段 %q", errInvalidUpload, part.FormName())
@@ -141,8 +181,29 @@ func parseUpload(w http.ResponseWriter, r *http.Request, incomingDir string, max
 	return &result, nil
 }
 
-func writeUploadedTXT(part *multipart.Part, incomingDir string, maxUploadBytes int64) (path string, size int64, retErr error) {
-	file, err := os.CreateTemp(incomingDir, "upload-*.txt")
+// readOptionField 读取一个短文本选项字段，空值表示使用默认设置。
+func readOptionField(part *multipart.Part, allowed map[string]struct{}) (string, error) {
+	value, err := io.ReadAll(io.LimitReader(part, 33))
+	if err != nil || len(value) > 32 {
+		return "", errInvalidUpload
+	}
+	option := strings.ToLower(strings.TrimSpace(string(value)))
+	if option == "" {
+		return "", nil
+	}
+	if _, ok := allowed[option]; !ok {
+		return "", errInvalidUpload
+	}
+	return option, nil
+}
+
+// writeUploadedTXT 保存上传的 TXT；bundle 为 true 时上传的是包含 TXT 和插图的 ZIP。
+func writeUploadedTXT(part *multipart.Part, incomingDir string, maxUploadBytes int64, bundle bool) (path string, size int64, retErr error) {
+	pattern := "upload-*.txt"
+	if bundle {
+		pattern = "upload-*.zip"
+	}
+	file, err := os.CreateTemp(incomingDir, pattern)
 	if err != nil {
 		return "", 0, fmt.Errorf("创建上传临时文件失败: %w", err)
 	}
@@ -173,6 +234,12 @@ func writeUploadedTXT(part *multipart.Part, incomingDir string, maxUploadBytes i
 	if err := file.Sync(); err != nil {
 		return "", 0, fmt.Errorf("同步上传文件失败: %w", err)
 	}
+	if bundle {
+		if err := validateUploadedBundle(file, written, maxUploadBytes); err != nil {
+			return "", 0, err
+		}
+		return path, written, nil
+	}
 	if err := validateTextSample(file); err != nil {
 		return "", 0, err
 	}
@@ -8,6 +8,7 @@ import (
 	"image/color"
 	"image/jpeg"
 	"mime/multipart"
+	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
@@ -27,7 +28,8 @@ func TestParseUpload(t *testing.T) {
 	}{
 		{name: "有效TXT", filename: "小说.txt", content: []byte("第一章 开始\n正文"), coverURL: "https://example.com/a.jpg", maxBytes: 1024, wantDisplay: "小说.txt"},
 		{name: "清理展示文件名", filename: "../bad\r\nname.txt", content: []byte("第一章"), maxBytes: 1024, wantDisplay: "badname.txt"},
-		{name: "拒绝扩展名", filename: "novel.zip", content: []byte("text"), maxBytes: 1024, wantErr: errInvalidUpload},
+		{name: "拒绝扩展名", filename: "novel.docx", content: []byte("text"), maxBytes: 1024, wantErr: errInvalidUpload},
+		{name: "拒绝无效ZIP", filename: "novel.zip", content: []byte("text"), maxBytes: 1024, wantErr: errInvalidUpload},
 		{name: "拒绝二进制", filename: "novel.txt", content: []byte{'a', 0, 'b'}, maxBytes: 1024, wantErr: errInvalidUpload},
 		{name: "拒绝过大", filename: "novel.txt", content: []byte("12345"), maxBytes: 4, wantErr: errUploadTooLarge},
 	}
@@ -101,6 +103,94 @@ func TestParseUploadRejectsUnknownField(t *testing.T) {
 	}
 }
 
+// newOptionUploadRequest 构造一个带 TXT 文件和单个选项字段的上传请求。
+func newOptionUploadRequest(t *testing.T, field, value string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	filePart, err := writer.CreateFormFile("file", "novel.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := filePart.Write([]byte("第一章 开始\n正文")); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.WriteField(field, value); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("POST", "/api/conversions", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestParseUploadReflowOption(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{name: "强制重排", value: "force", want: "force"},
+		{name: "忽略大小写", value: " OFF ", want: "off"},
+		{name: "空值使用默认", value: "", want: ""},
+		{name: "拒绝未知值", value: "sometimes", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newOptionUploadRequest(t, "reflow", tt.value)
+			got, err := parseUpload(httptest.NewRecorder(), req, t.TempDir(), 1024, 1024)
+			if tt.wantErr {
+				if err == nil || !errors.Is(err, errInvalidUpload) {
+					t.Fatalf("parseUpload() error = %v, want invalid upload", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseUpload() error = %v", err)
+			}
+			t.Cleanup(func() { _ = os.Remove(got.InputPath) })
+			if got.Reflow != tt.want {
+				t.Fatalf("Reflow = %q, want %q", got.Reflow, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseUploadZhConvertOption(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{name: "台湾正体", value: "S2TW", want: "s2tw"},
+		{name: "空值不转换", value: "", want: ""},
+		{name: "拒绝未知值", value: "s2jp", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newOptionUploadRequest(t, "zh_convert", tt.value)
+			got, err := parseUpload(httptest.NewRecorder(), req, t.TempDir(), 1024, 1024)
+			if tt.wantErr {
+				if err == nil || !errors.Is(err, errInvalidUpload) {
+					t.Fatalf("parseUpload() error = %v, want invalid upload", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseUpload() error = %v", err)
+			}
+			t.Cleanup(func() { _ = os.Remove(got.InputPath) })
+			if got.ZhConvert != tt.want {
+				t.Fatalf("ZhConvert = %q, want %q", got.ZhConvert, tt.want)
+			}
+		})
+	}
+}
+
 func TestParseUploadCoverFile(t *testing.T) {
 	validPNG, err := base64.StdEncoding.DecodeString("iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVQIHWP4z8DwHwAFgAI/ScL1XQAAAABJRU5ErkJggg==")
 	if err != nil {
@@ -0,0 +1,180 @@
+// Package zhconv 提供离线的简繁中文转换。
+//
+// 转换方式与 OpenCC 一致：先用词组词典按最长匹配切分并转换，未命中词组的部分按单字转换，
+// 需要地区用字时再叠加一层异体字词典。词典数据取自 OpenCC（Apache License 2.0），
+// 随程序一起嵌入，运行时不依赖外部文件。
+package zhconv
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// 支持的转换方式。
+const (
+	// S2T 把简体转换为繁体（OpenCC 标准字形）。
+	S2T = "s2t"
+	// T2S 把繁体转换为简体。
+	T2S = "t2s"
+	// S2TW 把简体转换为台湾正体用字。
+	S2TW = "s2tw"
+	// S2HK 把简体转换为香港繁体用字。
+	S2HK = "s2hk"
+)
+
+//go:embed dictionary/*.txt
+var dictionaryFS embed.FS
+
+// variants 描述每种转换方式依次执行的词典组，同一组内靠前的词典优先。
+var variants = map[string][][]string{
+	S2T:  {{"STPhrases", "STCharacters"}},
+	T2S:  {{"TSPhrases", "TSCharacters"}},
+	S2TW: {{"STPhrases", "STCharacters"}, {"TWVariants"}},
+	S2HK: {{"STPhrases", "STCharacters"}, {"HKVariants"}},
+}
+
+var (
+	cacheMu    sync.Mutex
+	converters = make(map[string]*Converter)
+)
+
+// Variants 返回支持的转换方式。
+func Variants() []string {
+	return []string{S2T, T2S, S2TW, S2HK}
+}
+
+// Normalize 规范化转换方式名称，空字符串表示不转换。
+func Normalize(variant string) (string, error) {
+	value := strings.ToLower(strings.TrimSpace(variant))
+	if value == "" {
+		return "", nil
+	}
+	if _, ok := variants[value]; !ok {
+		return "", fmt.Errorf("不支持的简繁转换方式: %s", variant)
+	}
+	return value, nil
+}
+
+// Converter 是某一种转换方式的转换器，创建后只读，可以在多个 goroutine 中共用。
+type Converter struct {
+	stages []*dictionary
+}
+
+// New 返回指定转换方式的转换器。词典在首次使用时加载，之后复用同一个实例。
+func New(variant string) (*Converter, error) {
+	value, err := Normalize(variant)
+	if err != nil {
+		return nil, err
+	}
+	if value == "" {
+		return nil, fmt.Errorf("简繁转换方式不能为空")
+	}
+
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if converter, ok := converters[value]; ok {
+		return converter, nil
+	}
+	converter := &Converter{}
+	for _, group := range variants[value] {
+		dict, err := loadDictionary(group...)
+		if err != nil {
+			return nil, err
+		}
+		converter.stages = append(converter.stages, dict)
+	}
+	converters[value] = converter
+	return converter, nil
+}
+
+// Convert 转换一段文本。非中文字符和 HTML 标签原样保留。
+func (c *Converter) Convert(text string) string {
+	for _, stage := range c.stages {
+		text = stage.convert(text)
+	}
+	return text
+}
+
+// dictionary 是合并后的转换词典。
+type dictionary struct {
+	entries map[string]string
+	// maxRunes 记录以某个字开头的词条最多有几个字，用于限制最长匹配的尝试次数。
+	maxRunes map[rune]int
+}
+
+// loadDictionary 按顺序加载并合并词典文件，同一个词条以先出现的为准。
+// 每行格式为“原词<Tab>候选1 候选2…”，只取第一个候选。
+func loadDictionary(names ...string) (*dictionary, error) {
+	dict := &dictionary{
+		entries:  make(map[string]string),
+		maxRunes: make(map[rune]int),
+	}
+	for _, name := range names {
+		file, err := dictionaryFS.Open("dictionary/" + name + ".txt")
+		if err != nil {
+			return nil, fmt.Errorf("打开简繁词典 %s 失败: %w", name, err)
+		}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+				continue
+			}
+			key := fields[0]
+			if _, exists := dict.entries[key]; exists {
+				continue
+			}
+			dict.entries[key] = fields[1]
+			first, _ := utf8.DecodeRuneInString(key)
+			dict.maxRunes[first] = max(dict.maxRunes[first], utf8.RuneCountInString(key))
+		}
+		_ = file.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("读取简繁词典 %s 失败: %w", name, err)
+		}
+	}
+	return dict, nil
+}
+
+// convert 按正向最长匹配转换文本。
+func (d *dictionary) convert(text string) string {
+	var b strings.Builder
+	b.Grow(len(text))
+	for i := 0; i < len(text); {
+		first, size := utf8.DecodeRuneInString(text[i:])
+		limit := d.maxRunes[first]
+		if limit == 0 {
+			b.WriteString(text[i : i+size])
+			i += size
+			continue
+		}
+
+		// 先收集候选长度对应的字节边界，再从最长的候选开始查词典。
+		var ends [64]int
+		count, end := 0, i
+		for count < limit && count < len(ends) && end < len(text) {
+			_, width := utf8.DecodeRuneInString(text[end:])
+			end += width
+			ends[count] = end
+			count++
+		}
+		matched := false
+		for n := count - 1; n >= 0; n-- {
+			if value, ok := d.entries[text[i:ends[n]]]; ok {
+				b.WriteString(value)
+				i = ends[n]
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			b.WriteString(text[i : i+size])
+			i += size
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,43 @@
+package zhconv
+
+import "testing"
+
+func TestConvertUsesPhrasesAndRegionalVariants(t *testing.T) {
+	tests := []struct {
+		variant string
+		input   string
+		want    string
+	}{
+		// 词组优先于单字：“头发”和“发展”中的“发”分别转换为“髮”和“發”。
+		{variant: S2T, input: "头发和发展，里面的后台。", want: "頭髮和發展，裏面的後臺。"},
+		{variant: S2TW, input: "他说里面着手开发。", want: "他說裡面著手開發。"},
+		{variant: S2HK, input: "他说里面的后台。", want: "他説裏面的後台。"},
+		{variant: T2S, input: "頭髮和發展，裏面的後臺。", want: "头发和发展，里面的后台。"},
+		// 标签、实体和非中文字符原样保留。
+		{variant: S2T, input: `<p class="lines">鼠标 &amp; Mouse</p>`, want: `<p class="lines">鼠標 &amp; Mouse</p>`},
+	}
+	for _, tt := range tests {
+		converter, err := New(tt.variant)
+		if err != nil {
+			t.Fatalf("New(%q) error = %v", tt.variant, err)
+		}
+		if got := converter.Convert(tt.input); got != tt.want {
+			t.Fatalf("%s Convert(%q) = %q, want %q", tt.variant, tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	if got, err := Normalize(" S2TW "); err != nil || got != S2TW {
+		t.Fatalf("Normalize() = %q, %v", got, err)
+	}
+	if got, err := Normalize(""); err != nil || got != "" {
+		t.Fatalf("empty variant should mean no conversion: %q, %v", got, err)
+	}
+	if _, err := Normalize("s2jp"); err == nil {
+		t.Fatal("expected unsupported variant to fail")
+	}
+	if _, err := New(""); err == nil {
+		t.Fatal("expected empty variant to be rejected by New")
+	}
+}
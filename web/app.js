@@ -43,6 +43,8 @@ const elements = {
   coverPreviewMark: document.getElementById("coverPreviewMark"),
   coverSourceTitle: document.getElementById("coverSourceTitle"),
   coverSourceDescription: document.getElementById("coverSourceDescription"),
+  reflowMode: document.getElementById("reflowMode"),
+  zhConvert: document.getElementById("zhConvert"),
   formError: document.getElementById("formError"),
   submitButton: document.getElementById("submitButton"),
   capacity: document.getElementById("capacity"),
@@ -313,6 +315,10 @@ async function handleSubmit(event) {
   } else if (coverUrl) {
     formData.append("cover_url", coverUrl);
   }
+  formData.append("reflow", elements.reflowMode.value);
+  if (elements.zhConvert.value) {
+    formData.append("zh_convert", elements.zhConvert.value);
+  }
 
   const xhr = new XMLHttpRequest();
   xhr.open("POST", "/api/conversions");
@@ -384,8 +390,8 @@ function validateForm(file, coverFile, coverUrl) {
   if (!file) {
     return "请先选择一个 TXT 文档。";
   }
-  if (!file.name.toLowerCase().endsWith(".txt")) {
-    return "请选择扩展名为 .txt 的纯文本文档。";
+  if (!/\.(txt|zip)$/.test(file.name.toLowerCase())) {
+    return "请选择扩展名为 .txt 的纯文本文档，或包含 TXT 和插图的 .zip 文件。";
   }
   if (coverFile && !isSupportedCoverFile(coverFile)) {
     return "封面仅支持 JPEG 或 PNG 图片。";
@@ -409,7 +415,7 @@ function isSupportedCoverFile(file) {
 }
 
 function titleFromFileName(fileName) {
-  const rawName = stringValue(fileName).replace(/\.txt$/i, "").trim();
+  const rawName = stringValue(fileName).replace(/\.(txt|zip)$/i, "").trim();
   const normalized = rawName.replace(/[_.-]+/g, " ").replace(/\s+/g, " ").trim();
   const title = normalized || "未命名文稿";
   const characters = Array.from(title);